@@ -0,0 +1,84 @@
+// Package backplane connects the chat servers of a cluster with
+// publish/subscribe messaging.
+package backplane
+
+import (
+	"errors"
+	"sync"
+)
+
+// Backplane delivers the messages published to a topic to its subscribers
+// on every server, including the publishing one. Delivery is best effort,
+// messages may be lost while a server is disconnected or falling behind.
+type Backplane interface {
+	Publish(topic string, data []byte) error
+	// Subscribe calls handler for the messages published to topic, one
+	// at a time and in the order each server published them.
+	Subscribe(topic string, handler func(data []byte)) error
+	Close() error
+}
+
+var ErrClosed = errors.New("backplane closed")
+
+// Messages queued for a subscriber of a Local backplane before messages
+// are dropped.
+const localQueueSize = 1024
+
+// Local is an in-process backplane, for servers sharing a process.
+type Local struct {
+	mu     sync.RWMutex
+	subs   map[string][]chan []byte
+	closed bool
+}
+
+func NewLocal() *Local {
+	return &Local{subs: make(map[string][]chan []byte)}
+}
+
+func (l *Local) Publish(topic string, data []byte) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return ErrClosed
+	}
+	for _, sub := range l.subs[topic] {
+		select {
+		case sub <- data:
+		default:
+			// Subscriber falling behind
+		}
+	}
+	return nil
+}
+
+func (l *Local) Subscribe(topic string, handler func(data []byte)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return ErrClosed
+	}
+	sub := make(chan []byte, localQueueSize)
+	l.subs[topic] = append(l.subs[topic], sub)
+	go func() {
+		for data := range sub {
+			handler(data)
+		}
+	}()
+	return nil
+}
+
+// Close stops delivery to all subscribers.
+func (l *Local) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	for _, subs := range l.subs {
+		for _, sub := range subs {
+			close(sub)
+		}
+	}
+	return nil
+}
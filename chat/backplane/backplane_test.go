@@ -0,0 +1,143 @@
+package backplane_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/tormoder/chat/backplane"
+	"github.com/tormoder/chat/logging"
+)
+
+// subscribe subscribes to topic on bp and returns the channel the messages
+// are passed on.
+func subscribe(t *testing.T, bp backplane.Backplane, topic string) <-chan []byte {
+	msgs := make(chan []byte, 16)
+	if err := bp.Subscribe(topic, func(data []byte) { msgs <- data }); err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+// expect receives want from msgs, skipping any messages equal to skip.
+func expect(t *testing.T, msgs <-chan []byte, want, skip []byte) {
+	for {
+		select {
+		case got := <-msgs:
+			if skip != nil && bytes.Equal(got, skip) {
+				continue
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("got message %q, want %q", got, want)
+			}
+			return
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for message %q", want)
+		}
+	}
+}
+
+func testPubSub(t *testing.T, a, b backplane.Backplane) {
+	aMsgs := subscribe(t, a, "t1")
+	bMsgs := subscribe(t, b, "t1")
+	other := subscribe(t, b, "t2")
+
+	// Binary data with RESP delimiters
+	data := []byte("hello\r\n\x00*3\r\n")
+	// The subscriptions may need a moment to reach a server, so publish
+	// until both got the message. Copies still on the way are skipped below.
+	var gotA, gotB bool
+	for i := 0; i < 50 && !(gotA && gotB); i++ {
+		if err := a.Publish("t1", data); err != nil {
+			t.Fatal(err)
+		}
+		timeout := time.After(100 * time.Millisecond)
+	wait:
+		for !(gotA && gotB) {
+			select {
+			case <-aMsgs:
+				gotA = true
+			case <-bMsgs:
+				gotB = true
+			case <-timeout:
+				break wait
+			}
+		}
+	}
+	if !gotA || !gotB {
+		t.Fatal("subscriptions not in effect")
+	}
+
+	if err := b.Publish("t1", []byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish("t1", []byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, aMsgs, []byte("one"), data)
+	expect(t, aMsgs, []byte("two"), data)
+	expect(t, bMsgs, []byte("one"), data)
+	expect(t, bMsgs, []byte("two"), data)
+	if len(other) != 0 {
+		t.Errorf("got %d messages on unrelated topic", len(other))
+	}
+}
+
+func TestLocal(t *testing.T) {
+	bp := backplane.NewLocal()
+	testPubSub(t, bp, bp)
+	bp.Close()
+	if err := bp.Publish("t1", nil); err != backplane.ErrClosed {
+		t.Errorf("publishing after close: got %v, want ErrClosed", err)
+	}
+}
+
+func TestBroker(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := backplane.NewBroker(ln, logging.Discard())
+	go broker.Serve()
+	defer broker.Close()
+
+	a, err := backplane.DialRedis(ln.Addr().String(), logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := backplane.DialRedis(ln.Addr().String(), logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	testPubSub(t, a, b)
+}
+
+func TestBrokerRejectsLongValues(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := backplane.NewBroker(ln, logging.Discard())
+	go broker.Serve()
+	defer broker.Close()
+
+	for _, value := range []string{"*100000\r\n", "*1\r\n$100000000\r\n"} {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = conn.Write([]byte(value)); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err = conn.Read(make([]byte, 64)); err != io.EOF {
+			t.Errorf("%q: got %v, want connection closed", value, err)
+		}
+		conn.Close()
+	}
+}
@@ -0,0 +1,217 @@
+package backplane
+
+import (
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/tormoder/chat/logging"
+)
+
+// Values queued for a broker client before it is disconnected as too slow.
+const brokerQueueSize = 4096
+
+// Broker is a minimal pub/sub server speaking the subset of the Redis
+// protocol used by Redis: PUBLISH, SUBSCRIBE, UNSUBSCRIBE and PING. It lets
+// a small cluster run without a Redis server.
+type Broker struct {
+	ln  net.Listener
+	log *logging.Logger
+
+	mu      sync.Mutex // Protects the fields below and the clients' topics
+	clients map[*brokerClient]bool
+	subs    map[string]map[*brokerClient]bool
+}
+
+type brokerClient struct {
+	rc     *respConn
+	out    chan interface{}
+	done   chan struct{}
+	once   sync.Once
+	topics map[string]bool
+}
+
+func NewBroker(ln net.Listener, logger *logging.Logger) *Broker {
+	return &Broker{
+		ln:      ln,
+		log:     logger,
+		clients: make(map[*brokerClient]bool),
+		subs:    make(map[string]map[*brokerClient]bool),
+	}
+}
+
+// Serve accepts connections until the listener is closed.
+func (b *Broker) Serve() error {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return err
+		}
+		c := &brokerClient{
+			rc:     newRespConn(conn),
+			out:    make(chan interface{}, brokerQueueSize),
+			done:   make(chan struct{}),
+			topics: make(map[string]bool),
+		}
+		b.mu.Lock()
+		b.clients[c] = true
+		b.mu.Unlock()
+		go b.write(c)
+		go b.serve(c)
+	}
+}
+
+// Close stops accepting connections and disconnects all clients.
+func (b *Broker) Close() error {
+	err := b.ln.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		c.close()
+	}
+	return err
+}
+
+func (b *Broker) serve(c *brokerClient) {
+	defer b.drop(c)
+	for {
+		value, err := c.rc.readValue()
+		if err != nil {
+			return
+		}
+		args, _ := value.([]interface{})
+		var cmd []byte
+		if len(args) > 0 {
+			cmd, _ = args[0].([]byte)
+		}
+		switch strings.ToUpper(string(cmd)) {
+		case "":
+			c.send(respError("ERR expected a command array"))
+		case "PUBLISH":
+			if len(args) != 3 {
+				c.send(respError("ERR wrong number of arguments for 'publish'"))
+				continue
+			}
+			topic, _ := args[1].([]byte)
+			c.send(int64(b.publish(string(topic), args[2])))
+		case "SUBSCRIBE":
+			for _, arg := range args[1:] {
+				topic, _ := arg.([]byte)
+				n := b.subscribe(c, string(topic))
+				c.send([]interface{}{[]byte("subscribe"), topic, int64(n)})
+			}
+		case "UNSUBSCRIBE":
+			topics := args[1:]
+			if len(topics) == 0 {
+				for _, topic := range b.topics(c) {
+					topics = append(topics, []byte(topic))
+				}
+			}
+			for _, arg := range topics {
+				topic, _ := arg.([]byte)
+				n := b.unsubscribe(c, string(topic))
+				c.send([]interface{}{[]byte("unsubscribe"), topic, int64(n)})
+			}
+		case "PING":
+			c.send("PONG")
+		default:
+			c.send(respError("ERR unknown command '" + string(cmd) + "'"))
+		}
+	}
+}
+
+// write writes the values queued for c.
+func (b *Broker) write(c *brokerClient) {
+	defer b.drop(c)
+	for {
+		select {
+		case v := <-c.out:
+			if err := c.rc.writeValue(v); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (b *Broker) publish(topic string, data interface{}) int {
+	msg := []interface{}{[]byte("message"), []byte(topic), data}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.subs[topic] {
+		if !c.send(msg) {
+			b.log.Warn("backplane client too slow, disconnecting", "addr", c.rc.conn.RemoteAddr())
+			c.close()
+		}
+	}
+	return len(b.subs[topic])
+}
+
+// subscribe subscribes c to topic and returns its number of topics.
+func (b *Broker) subscribe(c *brokerClient, topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[*brokerClient]bool)
+	}
+	b.subs[topic][c] = true
+	c.topics[topic] = true
+	return len(c.topics)
+}
+
+// unsubscribe unsubscribes c from topic and returns its number of topics.
+func (b *Broker) unsubscribe(c *brokerClient, topic string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(c.topics, topic)
+	delete(b.subs[topic], c)
+	if len(b.subs[topic]) == 0 {
+		delete(b.subs, topic)
+	}
+	return len(c.topics)
+}
+
+func (b *Broker) topics(c *brokerClient) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var topics []string
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// drop forgets c and closes its connection.
+func (b *Broker) drop(c *brokerClient) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.clients[c] {
+		return
+	}
+	delete(b.clients, c)
+	for topic := range c.topics {
+		delete(b.subs[topic], c)
+		if len(b.subs[topic]) == 0 {
+			delete(b.subs, topic)
+		}
+	}
+	c.close()
+}
+
+// send queues v for writing, it returns false if the queue is full.
+func (c *brokerClient) send(v interface{}) bool {
+	select {
+	case c.out <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *brokerClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.rc.Close()
+	})
+}
@@ -0,0 +1,219 @@
+package backplane
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tormoder/chat/logging"
+)
+
+const (
+	dialTimeout       = 5 * time.Second
+	minRedialInterval = 250 * time.Millisecond
+	maxRedialInterval = 10 * time.Second
+)
+
+// Redis is a backplane using the PUBLISH and SUBSCRIBE commands of a Redis
+// server or a Broker. Subscriptions are kept on a connection of their own,
+// which is redialed if lost.
+type Redis struct {
+	addr string
+	log  *logging.Logger
+
+	pubMu sync.Mutex // Serializes publishing
+	pub   *respConn  // nil until redialed after an error
+
+	mu       sync.Mutex // Protects the fields below
+	sub      *respConn
+	handlers map[string][]func([]byte)
+	closed   bool
+}
+
+// DialRedis connects to the server at addr.
+func DialRedis(addr string, logger *logging.Logger) (*Redis, error) {
+	pub, err := dialResp(addr)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := dialResp(addr)
+	if err != nil {
+		pub.Close()
+		return nil, err
+	}
+	r := &Redis{
+		addr:     addr,
+		log:      logger,
+		pub:      pub,
+		sub:      sub,
+		handlers: make(map[string][]func([]byte)),
+	}
+	go r.receive(sub)
+	return r, nil
+}
+
+func dialResp(addr string) (*respConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return newRespConn(conn), nil
+}
+
+func (r *Redis) Publish(topic string, data []byte) error {
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+	if r.isClosed() {
+		return ErrClosed
+	}
+	if r.pub == nil {
+		pub, err := dialResp(r.addr)
+		if err != nil {
+			return err
+		}
+		r.pub = pub
+	}
+	err := r.pub.writeArray([]byte("PUBLISH"), []byte(topic), data)
+	var reply interface{}
+	if err == nil {
+		reply, err = r.pub.readValue()
+	}
+	if err != nil {
+		r.pub.Close()
+		r.pub = nil
+		return err
+	}
+	if rerr, ok := reply.(respError); ok {
+		return rerr
+	}
+	return nil
+}
+
+func (r *Redis) Subscribe(topic string, handler func(data []byte)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ErrClosed
+	}
+	first := len(r.handlers[topic]) == 0
+	r.handlers[topic] = append(r.handlers[topic], handler)
+	if !first || r.sub == nil {
+		// Subscribed when redialed
+		return nil
+	}
+	return r.sub.writeArray([]byte("SUBSCRIBE"), []byte(topic))
+}
+
+// Close closes the connections, messages are no longer delivered.
+func (r *Redis) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	if r.sub != nil {
+		r.sub.Close()
+	}
+	r.mu.Unlock()
+
+	r.pubMu.Lock()
+	defer r.pubMu.Unlock()
+	if r.pub != nil {
+		r.pub.Close()
+	}
+	return nil
+}
+
+func (r *Redis) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// receive calls the handlers for the messages arriving on sub, redialing
+// and subscribing again if the connection is lost.
+func (r *Redis) receive(sub *respConn) {
+	for {
+		err := r.readMessages(sub)
+		sub.Close()
+		if r.isClosed() {
+			return
+		}
+		r.log.Warn("backplane connection lost", "addr", r.addr, "err", err)
+		if sub = r.redial(); sub == nil {
+			return
+		}
+		r.log.Info("backplane reconnected", "addr", r.addr)
+	}
+}
+
+func (r *Redis) readMessages(sub *respConn) error {
+	for {
+		value, err := sub.readValue()
+		if err != nil {
+			return err
+		}
+		if rerr, ok := value.(respError); ok {
+			return rerr
+		}
+		// Pushes are ["message", topic, data], subscription
+		// confirmations are skipped
+		push, ok := value.([]interface{})
+		if !ok || len(push) != 3 || !isBulk(push[0], "message") {
+			continue
+		}
+		topic, ok1 := push[1].([]byte)
+		data, ok2 := push[2].([]byte)
+		if !ok1 || !ok2 {
+			return fmt.Errorf("unexpected message %q", push)
+		}
+		r.mu.Lock()
+		handlers := r.handlers[string(topic)]
+		r.mu.Unlock()
+		for _, handler := range handlers {
+			handler(data)
+		}
+	}
+}
+
+// redial connects until it succeeds or the backplane is closed, in which
+// case it returns nil, and subscribes to all topics again.
+func (r *Redis) redial() *respConn {
+	delay := minRedialInterval
+	for {
+		time.Sleep(delay)
+		if delay *= 2; delay > maxRedialInterval {
+			delay = maxRedialInterval
+		}
+		sub, err := dialResp(r.addr)
+		if err != nil {
+			continue
+		}
+
+		r.mu.Lock()
+		if r.closed {
+			r.mu.Unlock()
+			sub.Close()
+			return nil
+		}
+		args := [][]byte{[]byte("SUBSCRIBE")}
+		for topic := range r.handlers {
+			args = append(args, []byte(topic))
+		}
+		if len(args) > 1 {
+			err = sub.writeArray(args...)
+		}
+		if err == nil {
+			r.sub = sub
+		}
+		r.mu.Unlock()
+		if err != nil {
+			sub.Close()
+			continue
+		}
+		return sub
+	}
+}
+
+func isBulk(v interface{}, s string) bool {
+	b, ok := v.([]byte)
+	return ok && string(b) == s
+}
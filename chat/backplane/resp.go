@@ -0,0 +1,164 @@
+package backplane
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+const (
+	// Largest bulk string accepted, room for the largest message gRPC
+	// accepts by default.
+	maxBulkLen = 8 << 20
+	// Most elements of an array accepted, commands and replies are short.
+	maxArrayLen = 1024
+)
+
+var errBadResp = errors.New("malformed RESP value")
+
+// respError is an error reply.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+// respConn reads and writes values of RESP, the Redis serialization
+// protocol. Simple strings are read as string, bulk strings as []byte,
+// integers as int64, arrays as []interface{} and errors as respError.
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+func newRespConn(conn net.Conn) *respConn {
+	return &respConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}
+}
+
+func (rc *respConn) Close() error {
+	return rc.conn.Close()
+}
+
+// writeArray writes an array of bulk strings and flushes it.
+func (rc *respConn) writeArray(args ...[]byte) error {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	return rc.writeValue(values)
+}
+
+// writeValue writes a value of one of the types read by readValue and
+// flushes it.
+func (rc *respConn) writeValue(v interface{}) error {
+	rc.encode(v)
+	return rc.w.Flush()
+}
+
+func (rc *respConn) encode(v interface{}) {
+	switch v := v.(type) {
+	case string:
+		rc.w.WriteString("+" + v + "\r\n")
+	case respError:
+		rc.w.WriteString("-" + string(v) + "\r\n")
+	case int64:
+		fmt.Fprintf(rc.w, ":%d\r\n", v)
+	case []byte:
+		fmt.Fprintf(rc.w, "$%d\r\n", len(v))
+		rc.w.Write(v)
+		rc.w.WriteString("\r\n")
+	case []interface{}:
+		fmt.Fprintf(rc.w, "*%d\r\n", len(v))
+		for _, elem := range v {
+			rc.encode(elem)
+		}
+	default:
+		rc.w.WriteString("$-1\r\n")
+	}
+}
+
+func (rc *respConn) readValue() (interface{}, error) {
+	line, err := rc.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errBadResp
+	}
+	switch line[0] {
+	case '+':
+		return string(line[1:]), nil
+	case '-':
+		return respError(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(string(line[1:]), 10, 64)
+	case '$':
+		n, err := parseLen(line[1:], maxBulkLen)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		// Grown as the data arrives, so that claiming a length does not
+		// make us allocate it
+		var buf bytes.Buffer
+		if _, err = io.CopyN(&buf, rc.r, int64(n)+2); err != nil {
+			return nil, err
+		}
+		return buf.Bytes()[:n], nil
+	case '*':
+		n, err := parseLen(line[1:], maxArrayLen)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]interface{}, 0, minInt(n, 16))
+		for i := 0; i < n; i++ {
+			value, err := rc.readValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	return nil, errBadResp
+}
+
+// readLine reads a line without the CRLF.
+func (rc *respConn) readLine() ([]byte, error) {
+	line, err := rc.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return nil, errBadResp
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return nil, errBadResp
+	}
+	return line[:len(line)-2], nil
+}
+
+// parseLen parses the length of a bulk string or array, -1 for null, of at
+// most max.
+func parseLen(b []byte, max int) (int, error) {
+	n, err := strconv.Atoi(string(b))
+	if err != nil || n < -1 || n > max {
+		return 0, errBadResp
+	}
+	return n, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -5,188 +5,322 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tormoder/chat/backplane"
 	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/logging"
 	pb "github.com/tormoder/chat/proto"
 	"github.com/tormoder/chat/storage"
 
 	"golang.org/x/net/context"
 )
 
+const maxHistoryLimit = 500
+
 type Service struct {
-	ustorage storage.UserStorage
+	ustorage  storage.UserStorage
+	mstorage  storage.MessageStorage
+	mailboxes storage.MailboxStorage
+	log       *logging.Logger
+
+	sessions   map[string]map[string]*session // Nick to session id to session
+	dropCounts map[string]uint64              // Messages dropped per nick due to a full queue
+	sentCounts map[string]uint64              // Messages sent by users per type
+	logouts    uint64                         // Login sessions ended
+	mu         sync.Mutex                     // Protects sessions and the counts
+
+	receipts   map[string]map[uint64]*receiptRequest // Recipient to mailbox id
+	receiptsMu sync.Mutex                            // Protects receipts
+
+	rooms   map[string]map[string]bool // Room name to set of member nicks
+	roomsMu sync.RWMutex               // Protects rooms
+
+	presence   map[string]*presenceState
+	presenceMu sync.Mutex // Protects presence
+
+	sessionTimeout time.Duration // See reapIdleSessions
 
-	connectedClients map[string]chan *pb.ChatServerMsg
-	mu               sync.Mutex // Protects connectedClients
+	backplane backplane.Backplane // nil unless clustered
+	node      string              // Name of this server in the cluster
+	remote    map[string]*remoteNode
+	remoteMu  sync.Mutex // Protects remote
+
+	shutdown    chan struct{}     // Closed by Shutdown
+	shutdownMsg *pb.ChatServerMsg // Set under mu before shutdown is closed
 }
 
-func NewService(userStorage storage.UserStorage) *Service {
-	return &Service{
-		ustorage:         userStorage,
-		connectedClients: make(map[string]chan *pb.ChatServerMsg),
+// NewService returns a chat service that logs out sessions without a
+// listening stream for sessionTimeout, which must be positive. If bp is not
+// nil, the service exchanges messages with the other servers on bp.
+func NewService(userStorage storage.UserStorage, msgStorage storage.MessageStorage, mailboxStorage storage.MailboxStorage, bp backplane.Backplane, sessionTimeout time.Duration, logger *logging.Logger) *Service {
+	s := &Service{
+		ustorage:       userStorage,
+		mstorage:       msgStorage,
+		mailboxes:      mailboxStorage,
+		log:            logger,
+		sessionTimeout: sessionTimeout,
+		sessions:       make(map[string]map[string]*session),
+		dropCounts:     make(map[string]uint64),
+		sentCounts:     make(map[string]uint64),
+		receipts:       make(map[string]map[uint64]*receiptRequest),
+		rooms:          make(map[string]map[string]bool),
+		presence:       make(map[string]*presenceState),
+		shutdown:       make(chan struct{}),
+		backplane:      bp,
+		node:           nodeID(),
+		remote:         make(map[string]*remoteNode),
+	}
+	if bp != nil {
+		s.joinCluster()
 	}
+	go s.reapIdleSessions()
+	return s
+}
+
+// BroadcastAllConnectedClients queues msg for every session and returns how
+// many sessions it was queued for and dropped for. In a cluster msg is also
+// passed on to the other servers, whose sessions are not counted.
+func (s *Service) BroadcastAllConnectedClients(msg *pb.ChatServerMsg) (delivered, dropped int) {
+	delivered, dropped = s.broadcastLocal(msg)
+	s.publish(topicBroadcast, &pb.ClusterMsg{Msg: msg})
+	return delivered, dropped
 }
 
-func (s *Service) BroadcastAllConnectedClients(msg *pb.ChatServerMsg) {
-	go func() {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		for _, clientChan := range s.connectedClients {
-			select {
-			case clientChan <- msg:
-				// Send OK
-			default:
-				// Client queue full, drop message
+func (s *Service) broadcastLocal(msg *pb.ChatServerMsg) (delivered, dropped int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for nick, sessions := range s.sessions {
+		for _, sess := range sessions {
+			if s.enqueue(nick, sess, msg) {
+				delivered++
+			} else {
+				dropped++
 			}
 		}
-	}()
+	}
+	return delivered, dropped
 }
 
 func (s *Service) SendPrivate(ctx context.Context, privMsgReq *pb.PrivateMsgRequest) (*pb.SendMsgResponse, error) {
-	c.Debugln("send public message request from", privMsgReq.GetCreds().Nick)
+	s.log.WithContext(ctx).Debug("send private message request")
 	user, err := s.ustorage.CheckCredentials(privMsgReq.GetCreds())
 	if err != nil {
 		return nil, err
 	}
-
-	clientCh, found := s.connectedClients[privMsgReq.To]
-	if found {
-		select {
-		case clientCh <- &pb.ChatServerMsg{
-			Msg: &pb.ChatServerMsg_PrivateMsg{
-				PrivateMsg: &pb.PrivateMsg{
-					To:       privMsgReq.To,
-					From:     &user.User,
-					Msg:      privMsgReq.Msg,
-					TimeSent: time.Now().Unix(),
-				},
-			},
-		}:
-			// Send OK
-		default:
-			// Client queue full, drop message
+	if user.IsMuted() {
+		return nil, errMuted
+	}
+	s.touch(user.Nick)
+	if enc := privMsgReq.Encrypted; enc != nil {
+		if err = checkEncrypted(enc, privMsgReq.Msg, user.PublicKey); err != nil {
+			return nil, err
 		}
-		return &pb.SendMsgResponse{}, nil
 	}
 
-	u, found := s.ustorage.GetUser(privMsgReq.To)
+	privMsg := &pb.PrivateMsg{
+		To:        privMsgReq.To,
+		From:      &user.User,
+		Msg:       privMsgReq.Msg,
+		Encrypted: privMsgReq.Encrypted,
+		TimeSent:  time.Now().Unix(),
+	}
+
+	recipient, found := s.ustorage.GetUser(privMsgReq.To)
+	if !recipient.Online && s.onlineElsewhere(privMsgReq.To) {
+		resp, err := s.relayPrivate(privMsg, privMsgReq.WantReceipts)
+		if err != nil {
+			return nil, err
+		}
+		s.countSent("private")
+		return resp, nil
+	}
 	if !found {
 		return nil, errors.New("requested user not found")
 	}
-	select {
-	case u.MsgChannel <- &pb.ChatServerMsg{
+	if !recipient.Online && !recipient.Registered() {
+		// The next guest with the nick is someone else
+		return nil, errors.New("requested user not logged-in, messages are only kept for registered users")
+	}
+
+	resp, err := s.deliverPrivate(privMsg, privMsgReq.WantReceipts)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != pb.SendMsgResponse_DROPPED {
+		s.countSent("private")
+	}
+	return resp, nil
+}
+
+// deliverPrivate stores privMsg in the recipient's mailbox and the history
+// and queues it for the recipient's sessions.
+func (s *Service) deliverPrivate(privMsg *pb.PrivateMsg, wantReceipts bool) (*pb.SendMsgResponse, error) {
+	// Hold mu while delivering so messages reach the recipient's queue in
+	// mailbox id order.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mailboxMsg, err := s.mailboxes.Deliver(privMsg)
+	if err == storage.ErrMailboxFull {
+		s.dropCounts[privMsg.To]++
+		return &pb.SendMsgResponse{
+			Status:  pb.SendMsgResponse_DROPPED,
+			Reason:  err.Error(),
+			Dropped: 1,
+		}, nil
+	}
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	err = s.mstorage.AddPrivateMsg(privMsg)
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	if wantReceipts {
+		s.addReceiptRequest(mailboxMsg, privMsg.From.GetNick())
+	}
+
+	resp := &pb.SendMsgResponse{
+		Id: mailboxMsg.Id,
+	}
+	sessions := s.sessions[privMsg.To]
+	if len(sessions) == 0 {
+		// Delivered from the mailbox when the recipient connects
+		resp.Status = pb.SendMsgResponse_QUEUED
+		resp.Reason = "recipient offline"
+		return resp, nil
+	}
+	msg := &pb.ChatServerMsg{
 		Msg: &pb.ChatServerMsg_PrivateMsg{
-			PrivateMsg: &pb.PrivateMsg{
-				To:       privMsgReq.To,
-				From:     &user.User,
-				Msg:      privMsgReq.Msg,
-				TimeSent: time.Now().Unix(),
-			},
+			PrivateMsg: mailboxMsg,
 		},
-	}:
-		// Send OK
-	default:
-		// Client queue full, drop message
 	}
+	for _, sess := range sessions {
+		if s.enqueue(privMsg.To, sess, msg) {
+			resp.Delivered++
+		} else {
+			resp.Dropped++
+		}
+	}
+	if resp.Delivered == 0 {
+		// Stays in the mailbox until the recipient reconnects
+		resp.Status = pb.SendMsgResponse_QUEUED
+		resp.Reason = "recipient queue full"
+		return resp, nil
+	}
+	resp.Status = pb.SendMsgResponse_DELIVERED
 
-	return &pb.SendMsgResponse{}, nil
+	return resp, nil
 }
 
 func (s *Service) SendPublic(ctx context.Context, pubMsgReq *pb.PublicMsgRequest) (*pb.SendMsgResponse, error) {
-	c.Debugln("send public message request from", pubMsgReq.GetCreds().Nick)
+	s.log.WithContext(ctx).Debug("send public message request")
 	user, err := s.ustorage.CheckCredentials(pubMsgReq.GetCreds())
 	if err != nil {
 		return nil, err
 	}
+	if user.IsMuted() {
+		return nil, errMuted
+	}
+	s.touch(user.Nick)
+
+	pubMsg := &pb.PublicMsg{
+		From:     &user.User,
+		Msg:      pubMsgReq.Msg,
+		TimeSent: time.Now().Unix(),
+	}
+	err = s.mstorage.AddPublicMsg(pubMsg)
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	s.countSent("public")
 
-	s.BroadcastAllConnectedClients(
+	delivered, dropped := s.BroadcastAllConnectedClients(
 		&pb.ChatServerMsg{
 			Msg: &pb.ChatServerMsg_PublicMsg{
-				PublicMsg: &pb.PublicMsg{
-					From:     &user.User,
-					Msg:      pubMsgReq.Msg,
-					TimeSent: time.Now().Unix(),
-				},
+				PublicMsg: pubMsg,
 			},
 		})
 
-	return &pb.SendMsgResponse{}, nil
+	return broadcastResponse(delivered, dropped), nil
 }
 
-func (s *Service) ListenForMessages(creds *pb.Credentials, stream pb.ChatService_ListenForMessagesServer) error {
-	c.Debugln("listen for messages request from", creds.Nick)
-	user, err := s.ustorage.CheckCredentials(creds)
+func (s *Service) GetHistory(ctx context.Context, histReq *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	s.log.WithContext(ctx).Debug("history request")
+	user, err := s.ustorage.CheckCredentials(histReq.GetCreds())
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	user.Online = true
-	msgChan := user.MsgChannel
-	err = s.ustorage.UpdateUser(user)
+	limit := int(histReq.Limit)
+	if limit <= 0 || limit > maxHistoryLimit {
+		limit = maxHistoryLimit
+	}
+	msgs, err := s.mstorage.GetHistory(user.Nick, histReq.AfterId, histReq.BeforeId, limit)
 	if err != nil {
-		return c.InternalServerError("storage error")
+		return nil, c.InternalServerError("storage error")
 	}
 
-	s.addClientChanToConnected(creds.Nick, msgChan)
-
-	hbTicker := time.NewTicker(time.Second)
-	hb := &pb.ChatServerMsg{
-		Msg: &pb.ChatServerMsg_Heartbeat{
-			Heartbeat: &pb.Heartbeat{},
-		},
+	hresp := &pb.HistoryResponse{
+		Msgs: make([]*pb.ChatServerMsg, len(msgs)),
+		Ids:  make([]uint64, len(msgs)),
 	}
-
-	c.Debugln("serving messages for", creds.Nick)
-
-	for {
-		select {
-		case msg := <-msgChan:
-			err = stream.Send(msg)
-			if err != nil {
-				goto cleanup
-			}
-		case <-hbTicker.C:
-			err = stream.Send(hb)
-			if err != nil {
-				goto cleanup
-			}
-		}
+	for i, msg := range msgs {
+		hresp.Msgs[i], hresp.Ids[i] = msg.Msg, msg.ID
 	}
+	return hresp, nil
+}
 
-cleanup:
-	s.rmClientChanFromConnected(creds.Nick)
-	user, found := s.ustorage.GetUser(creds.Nick)
-	if !found {
+func (s *Service) ListenForMessages(creds *pb.Credentials, stream pb.ChatService_ListenForMessagesServer) error {
+	s.log.WithContext(stream.Context()).Debug("listen for messages request")
+	user, err := s.ustorage.CheckCredentials(creds)
+	if err != nil {
 		return err
 	}
-	user.Online = false
-	user.TimeLastSeen = time.Now().Unix()
-	s.ustorage.UpdateUser(user)
 
-	s.BroadcastAllConnectedClients(
-		&pb.ChatServerMsg{
-			Msg: &pb.ChatServerMsg_UserEvent{
-				UserEvent: &pb.UserEvent{
-					Event: pb.UserEvent_LOGOUT,
-					User:  &user.User,
-					Time:  time.Now().Unix(),
-				},
-			},
-		},
-	)
+	sess, err := s.newSession(user.Nick, creds.Session)
+	if err != nil {
+		return err
+	}
+	s.touch(user.Nick)
+	return s.serveSession(user.Nick, sess, false, 0, stream, nil)
+}
 
-	c.Debugln("user", creds.Nick, "exited from message listing loop")
+func (s *Service) ResumeListening(resumeReq *pb.ResumeRequest, stream pb.ChatService_ResumeListeningServer) error {
+	s.log.WithContext(stream.Context()).Debug("resume listening request")
+	user, err := s.ustorage.CheckCredentials(resumeReq.GetCreds())
+	if err != nil {
+		return err
+	}
 
-	return err
+	sess, err := s.resumableSession(user.Nick, resumeReq.Creds.Session)
+	if err != nil {
+		return err
+	}
+	return s.serveSession(user.Nick, sess, true, resumeReq.Cursor, stream, nil)
 }
 
-func (s *Service) addClientChanToConnected(nick string, ch chan *pb.ChatServerMsg) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.connectedClients[nick] = ch
+func (s *Service) Ping(ctx context.Context, pingReq *pb.PingRequest) (*pb.PingResponse, error) {
+	s.log.WithContext(ctx).Debug("ping request")
+	_, err := s.ustorage.CheckCredentials(pingReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.PingResponse{
+		Seq: pingReq.Seq,
+	}, nil
 }
 
-func (s *Service) rmClientChanFromConnected(nick string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	delete(s.connectedClients, nick)
+func (s *Service) AckMessages(ctx context.Context, ackReq *pb.AckRequest) (*pb.AckResponse, error) {
+	s.log.WithContext(ctx).Debug("ack messages request")
+	user, err := s.ustorage.CheckCredentials(ackReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mailboxes.Ack(user.Nick, ackReq.UpTo)
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	s.sendReceipts(user.Nick, ackReq.UpTo, pb.Receipt_DELIVERED)
+
+	return &pb.AckResponse{}, nil
 }
@@ -0,0 +1,113 @@
+package chat_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+func receipt(typ pb.Receipt_Type) func(*pb.ChatServerMsg) bool {
+	return func(msg *pb.ChatServerMsg) bool {
+		return msg.GetReceipt().GetType() == typ
+	}
+}
+
+func TestSendStatus(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := ts.listen("alice")
+	_, carolStream := ts.listen("carol")
+	if _, err := ts.users.Register(ctx, &pb.RegisterRequest{Nick: "bob", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "carol", Msg: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != pb.SendMsgResponse_DELIVERED || resp.Delivered != 1 || resp.Id == 0 {
+		t.Errorf("to online user: got %v, want delivered to one session", resp)
+	}
+	ts.next(carolStream, privateMsg)
+
+	resp, err = ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "bob", Msg: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != pb.SendMsgResponse_QUEUED || resp.Id == 0 {
+		t.Errorf("to offline user: got %v, want queued", resp)
+	}
+
+	for i := 1; i < storage.MaxMailboxSize; i++ {
+		if _, err = ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "bob", Msg: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err = ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "bob", Msg: "one too many"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != pb.SendMsgResponse_DROPPED || resp.Dropped != 1 {
+		t.Errorf("to full mailbox: got %v, want dropped", resp)
+	}
+
+	bob, err := ts.users.Login(ctx, &pb.LoginRequest{Nick: "bob", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := ts.chat.GetStats(ctx, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.DroppedMsgs != 1 {
+		t.Errorf("got %d dropped messages for bob, want 1", stats.DroppedMsgs)
+	}
+	if stats, err = ts.chat.GetStats(ctx, alice); err != nil || stats.DroppedMsgs != 0 {
+		t.Errorf("got %v, %v for alice, want no dropped messages", stats, err)
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	alice, aliceStream := ts.listen("alice")
+	carol, carolStream := ts.listen("carol")
+
+	resp, err := ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "carol", Msg: "hi", WantReceipts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := ts.next(carolStream, privateMsg)
+	if id := msg.GetPrivateMsg().Id; id != resp.Id {
+		t.Fatalf("got message id %d, want %d", id, resp.Id)
+	}
+
+	if _, err = ts.chat.AckMessages(ctx, &pb.AckRequest{Creds: carol, UpTo: resp.Id}); err != nil {
+		t.Fatal(err)
+	}
+	delivered := ts.next(aliceStream, receipt(pb.Receipt_DELIVERED)).GetReceipt()
+	if delivered.Id != resp.Id || delivered.To != "carol" {
+		t.Errorf("got delivery receipt %v, want one for message %d to carol", delivered, resp.Id)
+	}
+	if _, err = ts.chat.MarkRead(ctx, &pb.AckRequest{Creds: carol, UpTo: resp.Id}); err != nil {
+		t.Fatal(err)
+	}
+	read := ts.next(aliceStream, receipt(pb.Receipt_READ)).GetReceipt()
+	if read.Id != resp.Id || read.To != "carol" {
+		t.Errorf("got read receipt %v, want one for message %d to carol", read, resp.Id)
+	}
+
+	// Each receipt is sent once
+	if _, err = ts.chat.MarkRead(ctx, &pb.AckRequest{Creds: carol, UpTo: resp.Id}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.chat.SendPublic(ctx, &pb.PublicMsgRequest{Creds: carol, Msg: "done"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := ts.next(aliceStream, func(msg *pb.ChatServerMsg) bool { return !heartbeat(msg) }); msg.GetPublicMsg() == nil {
+		t.Errorf("got %v, want no more receipts", msg)
+	}
+}
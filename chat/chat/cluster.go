@@ -0,0 +1,281 @@
+package chat
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Topics on the backplane.
+const (
+	topicBroadcast = "chat.broadcast" // Messages for every session
+	topicPrivate   = "chat.private"   // Private messages for the recipient's server
+	topicUser      = "chat.user"      // Messages for the sessions of a nick, like receipts
+	topicNodes     = "chat.nodes"     // Users online per server
+)
+
+const (
+	// Servers publish their online users this often.
+	announceInterval = 5 * time.Second
+	// A server that has not announced itself for nodeTimeout is gone.
+	nodeTimeout = 3 * announceInterval
+)
+
+var errBackplane = status.Error(codes.Unavailable, "other chat servers unreachable")
+
+// remoteNode is another server of the cluster.
+type remoteNode struct {
+	users map[string]*pb.User
+	seen  time.Time
+}
+
+// nodeID returns a name for this server, unique within the cluster.
+func nodeID() string {
+	host, _ := os.Hostname()
+	id, err := c.NewSessionID()
+	if err != nil {
+		id = strconv.Itoa(os.Getpid())
+	}
+	return host + "-" + id
+}
+
+// joinCluster subscribes to the backplane topics and starts announcing the
+// users online on this server.
+func (s *Service) joinCluster() {
+	s.subscribe(topicBroadcast, s.receiveBroadcast)
+	s.subscribe(topicPrivate, s.receivePrivate)
+	s.subscribe(topicUser, s.receiveUserMsg)
+	s.subscribe(topicNodes, s.receiveNodes)
+	go s.announce()
+	s.log.Info("joined cluster", "node", s.node)
+}
+
+// publish sends cmsg to the other servers of the cluster, if any. It must
+// not be called with mu held as publishing may block.
+func (s *Service) publish(topic string, cmsg *pb.ClusterMsg) error {
+	if s.backplane == nil {
+		return nil
+	}
+	cmsg.Node = s.node
+	data, err := proto.Marshal(cmsg)
+	if err != nil {
+		s.log.Error("encoding cluster message failed", "topic", topic, "err", err)
+		return err
+	}
+	if err := s.backplane.Publish(topic, data); err != nil {
+		s.log.Warn("publishing to backplane failed", "topic", topic, "err", err)
+		return errBackplane
+	}
+	return nil
+}
+
+// subscribe calls handler for the messages published to topic by the other
+// servers of the cluster.
+func (s *Service) subscribe(topic string, handler func(cmsg *pb.ClusterMsg)) {
+	err := s.backplane.Subscribe(topic, func(data []byte) {
+		cmsg := new(pb.ClusterMsg)
+		if err := proto.Unmarshal(data, cmsg); err != nil {
+			s.log.Warn("malformed cluster message", "topic", topic, "err", err)
+			return
+		}
+		if cmsg.Node == s.node {
+			return
+		}
+		handler(cmsg)
+	})
+	if err != nil {
+		s.log.Error("subscribing to backplane failed", "topic", topic, "err", err)
+	}
+}
+
+func (s *Service) receiveBroadcast(cmsg *pb.ClusterMsg) {
+	if cmsg.Msg == nil {
+		return
+	}
+	s.broadcastLocal(cmsg.Msg)
+	if event := cmsg.Msg.GetUserEvent(); event != nil {
+		s.trackRemoteUser(cmsg.Node, event)
+	}
+}
+
+// receivePrivate delivers a private message relayed by another server if
+// the recipient is logged in here.
+func (s *Service) receivePrivate(cmsg *pb.ClusterMsg) {
+	privMsg := cmsg.Msg.GetPrivateMsg()
+	if privMsg == nil {
+		return
+	}
+	if user, found := s.ustorage.GetUser(privMsg.To); !found || !user.Online {
+		return
+	}
+	if _, err := s.deliverPrivate(privMsg, cmsg.WantReceipts); err != nil {
+		s.log.Error("delivering relayed private message failed", "to", privMsg.To, "err", err)
+	}
+}
+
+func (s *Service) receiveUserMsg(cmsg *pb.ClusterMsg) {
+	if cmsg.Msg == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sess := range s.sessions[cmsg.Nick] {
+		s.enqueue(cmsg.Nick, sess, cmsg.Msg)
+	}
+}
+
+// receiveNodes records the users online on another server.
+func (s *Service) receiveNodes(cmsg *pb.ClusterMsg) {
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	now := time.Now()
+	for node, rn := range s.remote {
+		if now.Sub(rn.seen) > nodeTimeout {
+			delete(s.remote, node)
+			s.log.Warn("cluster node timed out", "node", node)
+		}
+	}
+	if cmsg.Leaving {
+		delete(s.remote, cmsg.Node)
+		s.log.Info("cluster node left", "node", cmsg.Node)
+		return
+	}
+	rn := s.remoteNodeLocked(cmsg.Node)
+	rn.users = make(map[string]*pb.User, len(cmsg.Users))
+	for _, user := range cmsg.Users {
+		rn.users[user.Nick] = user
+	}
+	rn.seen = now
+}
+
+// trackRemoteUser updates the users online on another server from a user
+// event, so that they are known before the server next announces them.
+func (s *Service) trackRemoteUser(node string, event *pb.UserEvent) {
+	if event.User == nil {
+		return
+	}
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	rn := s.remoteNodeLocked(node)
+	switch event.Event {
+	case pb.UserEvent_LOGIN:
+		user := proto.Clone(event.User).(*pb.User)
+		user.Presence = &pb.Presence{Status: pb.Presence_ONLINE}
+		rn.users[user.Nick] = user
+	case pb.UserEvent_LOGOUT, pb.UserEvent_KICK:
+		delete(rn.users, event.User.Nick)
+	}
+}
+
+func (s *Service) remoteNodeLocked(node string) *remoteNode {
+	rn, found := s.remote[node]
+	if !found {
+		rn = &remoteNode{users: make(map[string]*pb.User), seen: time.Now()}
+		s.remote[node] = rn
+		s.log.Info("cluster node joined", "node", node)
+	}
+	return rn
+}
+
+// announce publishes the users online on this server every announceInterval
+// until the service is shut down.
+func (s *Service) announce() {
+	ticker := time.NewTicker(announceInterval)
+	defer ticker.Stop()
+	for {
+		s.publish(topicNodes, &pb.ClusterMsg{
+			Users: s.VisibleUsers("", s.ustorage.GetAllOnlineUsersDTO()),
+		})
+		select {
+		case <-ticker.C:
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+// RemoteUser returns nick as announced by another server it is online on.
+func (s *Service) RemoteUser(nick string) (*pb.User, bool) {
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	for _, rn := range s.remote {
+		if user, found := rn.users[nick]; found && time.Since(rn.seen) <= nodeTimeout {
+			return user, true
+		}
+	}
+	return nil, false
+}
+
+// onlineElsewhere reports whether nick is online on another server.
+func (s *Service) onlineElsewhere(nick string) bool {
+	_, found := s.RemoteUser(nick)
+	return found
+}
+
+// WithRemoteUsers appends the users online only on other servers of the
+// cluster to users, the users visible on this server.
+func (s *Service) WithRemoteUsers(users []*pb.User) []*pb.User {
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		seen[user.Nick] = true
+	}
+	s.remoteMu.Lock()
+	defer s.remoteMu.Unlock()
+	for _, rn := range s.remote {
+		if time.Since(rn.seen) > nodeTimeout {
+			continue
+		}
+		for nick, user := range rn.users {
+			if seen[nick] {
+				continue
+			}
+			seen[nick] = true
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// relayPrivate publishes a private message for a recipient logged in on
+// another server, keeping it in the sender's history here.
+func (s *Service) relayPrivate(privMsg *pb.PrivateMsg, wantReceipts bool) (*pb.SendMsgResponse, error) {
+	err := s.publish(topicPrivate, &pb.ClusterMsg{
+		Nick: privMsg.To,
+		Msg: &pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_PrivateMsg{
+				PrivateMsg: privMsg,
+			},
+		},
+		WantReceipts: wantReceipts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mstorage.AddPrivateMsg(privMsg); err != nil {
+		s.log.Error("storing relayed private message failed", "to", privMsg.To, "err", err)
+	}
+	return &pb.SendMsgResponse{
+		Status: pb.SendMsgResponse_RELAYED,
+	}, nil
+}
+
+// sendToUser queues msg for the sessions of nick, or passes it on to the
+// other servers of the cluster if nick has none here.
+func (s *Service) sendToUser(nick string, msg *pb.ChatServerMsg) {
+	s.mu.Lock()
+	sessions := s.sessions[nick]
+	for _, sess := range sessions {
+		s.enqueue(nick, sess, msg)
+	}
+	s.mu.Unlock()
+	if len(sessions) == 0 {
+		s.publish(topicUser, &pb.ClusterMsg{Nick: nick, Msg: msg})
+	}
+}
@@ -0,0 +1,91 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tormoder/chat/backplane"
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+func newClusterServer(t *testing.T, bp backplane.Backplane) *testServer {
+	us := storage.NewInMemoryUserStorage()
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), bp, chat.DefaultSessionTimeout, logging.Discard())
+	return &testServer{
+		t:     t,
+		users: user.NewService(cs, us, true, logging.Discard()),
+		chat:  cs,
+	}
+}
+
+// waitForUser waits until nick is listed on ts.
+func (ts *testServer) waitForUser(creds *pb.Credentials, nick string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		resp, err := ts.users.ListUsers(context.Background(), creds)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		for _, user := range resp.Users {
+			if user.Nick == nick {
+				return
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	ts.t.Fatalf("%s not listed", nick)
+}
+
+func TestCluster(t *testing.T) {
+	bp := backplane.NewLocal()
+	defer bp.Close()
+	a, b := newClusterServer(t, bp), newClusterServer(t, bp)
+	ctx := context.Background()
+
+	alice, aliceStream := a.listen("alice")
+	bob, bobStream := b.listen("bob")
+	a.waitForUser(alice, "bob")
+	b.waitForUser(bob, "alice")
+
+	_, err := a.chat.SendPublic(ctx, &pb.PublicMsgRequest{Creds: alice, Msg: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := b.next(bobStream, func(msg *pb.ChatServerMsg) bool { return msg.GetPublicMsg() != nil })
+	if from := msg.GetPublicMsg().GetFrom().GetNick(); from != "alice" {
+		t.Errorf("public message from %q, want alice", from)
+	}
+
+	resp, err := a.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "bob", Msg: "psst", WantReceipts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != pb.SendMsgResponse_RELAYED {
+		t.Errorf("private message to other server: got status %v, want RELAYED", resp.Status)
+	}
+	pmsg := b.next(bobStream, privateMsg).GetPrivateMsg()
+	if pmsg.Msg != "psst" || pmsg.GetFrom().GetNick() != "alice" {
+		t.Errorf("got private message %v", pmsg)
+	}
+
+	// Receipts find their way back
+	_, err = b.chat.AckMessages(ctx, &pb.AckRequest{Creds: bob, UpTo: pmsg.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt := a.next(aliceStream, func(msg *pb.ChatServerMsg) bool { return msg.GetReceipt() != nil }).GetReceipt()
+	if receipt.Type != pb.Receipt_DELIVERED || receipt.To != "bob" || receipt.Id != pmsg.Id {
+		t.Errorf("got receipt %v", receipt)
+	}
+
+	// Users unknown to the whole cluster are still an error
+	if _, err = a.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "nobody", Msg: "hi"}); err == nil {
+		t.Error("private message to unknown user: got nil error")
+	}
+}
@@ -0,0 +1,231 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	pb "github.com/tormoder/chat/proto"
+
+	"golang.org/x/net/context"
+)
+
+type receiptRequest struct {
+	from      string
+	delivered bool
+}
+
+// enqueue queues msg for the session sess of nick without blocking and
+// counts the message as dropped if the queue is full. Must be called with
+// mu held.
+func (s *Service) enqueue(nick string, sess *session, msg *pb.ChatServerMsg) bool {
+	select {
+	case sess.msgChan <- msg:
+		return true
+	default:
+		s.dropCounts[nick]++
+		return false
+	}
+}
+
+func broadcastResponse(delivered, dropped int) *pb.SendMsgResponse {
+	resp := &pb.SendMsgResponse{
+		Status:    pb.SendMsgResponse_DELIVERED,
+		Delivered: uint32(delivered),
+		Dropped:   uint32(dropped),
+	}
+	switch {
+	case dropped > 0 && delivered == 0:
+		resp.Status = pb.SendMsgResponse_DROPPED
+		resp.Reason = "all recipient queues full"
+	case dropped > 0:
+		resp.Reason = fmt.Sprintf("%d recipient queues full", dropped)
+	}
+	return resp
+}
+
+// DropCounts returns the number of messages dropped per nick because the
+// client's queue was full.
+func (s *Service) DropCounts() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]uint64, len(s.dropCounts))
+	for nick, n := range s.dropCounts {
+		counts[nick] = n
+	}
+	return counts
+}
+
+// SentCounts returns the number of messages sent by users per type of
+// message: public, private or room.
+func (s *Service) SentCounts() map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := make(map[string]uint64, len(s.sentCounts))
+	for typ, n := range s.sentCounts {
+		counts[typ] = n
+	}
+	return counts
+}
+
+func (s *Service) countSent(typ string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sentCounts[typ]++
+}
+
+// AttachedCount returns the number of sessions of all users with a
+// listening stream attached.
+func (s *Service) AttachedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, sessions := range s.sessions {
+		for _, sess := range sessions {
+			if sess.attached() {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// LogoutCount returns the number of login sessions ended, by logging out,
+// being kicked, expiring or the service shutting down.
+func (s *Service) LogoutCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logouts
+}
+
+func (s *Service) countLogouts(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logouts += uint64(n)
+}
+
+// QueueLens returns the number of messages queued per connected user,
+// summed over its sessions.
+func (s *Service) QueueLens() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lens := make(map[string]int, len(s.sessions))
+	for nick, sessions := range s.sessions {
+		for _, sess := range sessions {
+			lens[nick] += len(sess.msgChan)
+		}
+	}
+	return lens
+}
+
+func (s *Service) GetStats(ctx context.Context, creds *pb.Credentials) (*pb.StatsResponse, error) {
+	s.log.WithContext(ctx).Debug("stats request")
+	user, err := s.ustorage.CheckCredentials(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	dropped := s.dropCounts[user.Nick]
+	var queued int
+	if sess := s.sessions[user.Nick][creds.Session]; sess != nil {
+		queued = len(sess.msgChan)
+	}
+	s.mu.Unlock()
+
+	return &pb.StatsResponse{
+		DroppedMsgs: dropped,
+		QueueLen:    uint32(queued),
+	}, nil
+}
+
+func (s *Service) MarkRead(ctx context.Context, readReq *pb.AckRequest) (*pb.AckResponse, error) {
+	s.log.WithContext(ctx).Debug("mark read request")
+	user, err := s.ustorage.CheckCredentials(readReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+
+	s.sendReceipts(user.Nick, readReq.UpTo, pb.Receipt_READ)
+
+	return &pb.AckResponse{}, nil
+}
+
+// PurgeNick drops the private messages to and from nick in the history,
+// those waiting in its mailbox and any receipts requested for them, so that
+// whoever uses the nick next cannot read them. The messages dropped for nick
+// are no longer counted.
+func (s *Service) PurgeNick(nick string) error {
+	s.receiptsMu.Lock()
+	delete(s.receipts, nick)
+	s.receiptsMu.Unlock()
+	s.mu.Lock()
+	delete(s.dropCounts, nick)
+	s.mu.Unlock()
+	if err := s.mailboxes.Purge(nick); err != nil {
+		return err
+	}
+	return s.mstorage.PurgeNick(nick)
+}
+
+func (s *Service) addReceiptRequest(msg *pb.PrivateMsg, from string) {
+	s.receiptsMu.Lock()
+	defer s.receiptsMu.Unlock()
+	reqs, found := s.receipts[msg.To]
+	if !found {
+		reqs = make(map[uint64]*receiptRequest)
+		s.receipts[msg.To] = reqs
+	}
+	reqs[msg.Id] = &receiptRequest{from: from}
+}
+
+// sendReceipts sends a receipt of type typ to the sender of every private
+// message to recipient with an id up to and including upTo, if the sender
+// asked for receipts. Receipts for senders that are not connected are lost.
+func (s *Service) sendReceipts(recipient string, upTo uint64, typ pb.Receipt_Type) {
+	s.receiptsMu.Lock()
+	var receipts []*pb.Receipt
+	senders := make(map[uint64]string)
+	reqs := s.receipts[recipient]
+	for id, req := range reqs {
+		if id > upTo {
+			continue
+		}
+		switch typ {
+		case pb.Receipt_DELIVERED:
+			if req.delivered {
+				continue
+			}
+			req.delivered = true
+		case pb.Receipt_READ:
+			delete(reqs, id)
+		}
+		senders[id] = req.from
+		receipts = append(receipts, &pb.Receipt{
+			Type: typ,
+			To:   recipient,
+			Id:   id,
+			Time: time.Now().Unix(),
+		})
+	}
+	if len(reqs) == 0 {
+		delete(s.receipts, recipient)
+	}
+	s.receiptsMu.Unlock()
+
+	sort.Sort(byReceiptID(receipts))
+
+	for _, receipt := range receipts {
+		s.sendToUser(senders[receipt.Id], &pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_Receipt{
+				Receipt: receipt,
+			},
+		})
+	}
+}
+
+type byReceiptID []*pb.Receipt
+
+func (s byReceiptID) Len() int           { return len(s) }
+func (s byReceiptID) Less(i, j int) bool { return s[i].Id < s[j].Id }
+func (s byReceiptID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"bytes"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Sizes of the parts of a NaCl box, see EncryptedMsg.
+const (
+	keySize     = 32
+	nonceSize   = 24
+	boxOverhead = 16
+)
+
+var (
+	errEncryptedAndPlain = status.Error(codes.InvalidArgument, "message both encrypted and in plaintext")
+	errMalformedBox      = status.Error(codes.InvalidArgument, "malformed encrypted message")
+	errNotSenderKey      = status.Error(codes.InvalidArgument, "message not encrypted with your published key")
+)
+
+// checkEncrypted checks that enc is well formed and sealed with senderKey,
+// the only part of the message the server can check.
+func checkEncrypted(enc *pb.EncryptedMsg, plain string, senderKey []byte) error {
+	if plain != "" {
+		return errEncryptedAndPlain
+	}
+	if len(enc.SenderKey) != keySize || len(enc.RecipientKey) != keySize ||
+		len(enc.Nonce) != nonceSize || len(enc.Box) < boxOverhead {
+		return errMalformedBox
+	}
+	if !bytes.Equal(enc.SenderKey, senderKey) {
+		return errNotSenderKey
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package chat_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+func TestEncryptedPrivateMsg(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	aliceKey, bobKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	alice, _ := ts.listenAs(&pb.LoginRequest{Nick: "alice", PublicKey: aliceKey})
+	bob, bobStream := ts.listenAs(&pb.LoginRequest{Nick: "bob", PublicKey: bobKey})
+
+	resp, err := ts.users.GetPublicKey(ctx, &pb.PublicKeyRequest{Creds: alice, Nick: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp.PublicKey, bobKey) {
+		t.Errorf("got key %x for bob, want %x", resp.PublicKey, bobKey)
+	}
+	ts.listen("carol")
+	if _, err = ts.users.GetPublicKey(ctx, &pb.PublicKeyRequest{Creds: alice, Nick: "carol"}); err == nil {
+		t.Error("key of user without one: got nil error")
+	}
+
+	enc := &pb.EncryptedMsg{
+		SenderKey:    aliceKey,
+		RecipientKey: bobKey,
+		Nonce:        make([]byte, 24),
+		Box:          []byte("sixteen bytes at least"),
+	}
+	_, err = ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: alice, To: "bob", Encrypted: enc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pmsg := ts.next(bobStream, privateMsg).GetPrivateMsg()
+	if pmsg.Msg != "" || !proto.Equal(pmsg.Encrypted, enc) {
+		t.Errorf("got private message %v, want it passed on as sent", pmsg)
+	}
+
+	hist, err := ts.chat.GetHistory(ctx, &pb.HistoryRequest{Creds: bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(hist.Msgs); n == 0 || hist.Msgs[n-1].GetPrivateMsg().Encrypted == nil {
+		t.Error("encrypted message not in history")
+	}
+
+	bad := []struct {
+		desc string
+		req  *pb.PrivateMsgRequest
+	}{
+		{"with plaintext", &pb.PrivateMsgRequest{Msg: "hi", Encrypted: enc}},
+		{"from bob's key", &pb.PrivateMsgRequest{Encrypted: &pb.EncryptedMsg{
+			SenderKey: bobKey, RecipientKey: bobKey, Nonce: enc.Nonce, Box: enc.Box,
+		}}},
+		{"with short nonce", &pb.PrivateMsgRequest{Encrypted: &pb.EncryptedMsg{
+			SenderKey: aliceKey, RecipientKey: bobKey, Nonce: enc.Nonce[1:], Box: enc.Box,
+		}}},
+	}
+	for _, b := range bad {
+		b.req.Creds, b.req.To = alice, "bob"
+		if _, err = ts.chat.SendPrivate(ctx, b.req); err == nil {
+			t.Errorf("encrypted message %s: got nil error", b.desc)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+package chat
+
+const ResumeBufferSize = resumeBufferSize
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+var errMuted = status.Error(codes.PermissionDenied, "you are muted")
+
+// Kick ends the listening streams of all sessions of nick with a
+// PermissionDenied error carrying reason, logs the user out and tells
+// everyone else. It reports whether nick was logged in.
+func (s *Service) Kick(nick, reason string) bool {
+	return s.KickSession(nick, "", reason)
+}
+
+// KickSession is Kick for the session id of nick only, everyone else is
+// told if it was the last session of nick.
+func (s *Service) KickSession(nick, id, reason string) bool {
+	ended := s.endSessions(nick, id, status.Error(codes.PermissionDenied, reason))
+	user, loggedOut, err := s.logout(nick, id)
+	if err != nil {
+		s.log.Error("logging out kicked user failed", "nick", nick, "err", err)
+	}
+	if !loggedOut {
+		return ended > 0
+	}
+
+	s.BroadcastAllConnectedClients(
+		&pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_UserEvent{
+				UserEvent: &pb.UserEvent{
+					Event: pb.UserEvent_KICK,
+					User:  &user.User,
+					Time:  time.Now().Unix(),
+				},
+			},
+		},
+	)
+	s.log.Info("user kicked", "nick", nick, "reason", reason)
+	return true
+}
+
+// SessionsFrom returns the ids of the sessions, by nick, logged in from the
+// IP address addr or with a listening stream last attached from it.
+func (s *Service) SessionsFrom(addr string) map[string][]string {
+	ids := make(map[string][]string)
+	for _, user := range s.ustorage.GetAllOnlineUsers() {
+		for _, sess := range user.Sessions {
+			if sess.Addr == addr {
+				ids[user.Nick] = append(ids[user.Nick], sess.ID)
+			}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for nick, sessions := range s.sessions {
+		for id, sess := range sessions {
+			if sess.peerAddr() == addr && !containsString(ids[nick], id) {
+				ids[nick] = append(ids[nick], id)
+			}
+		}
+	}
+	return ids
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package chat
+
+import (
+	"errors"
+	"time"
+
+	pb "github.com/tormoder/chat/proto"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Online users without activity for idleAfter are shown as idle.
+	idleAfter          = 5 * time.Minute
+	maxPresenceTextLen = 64
+)
+
+// presenceState is the presence of a logged-in user, users without one are
+// online.
+type presenceState struct {
+	status     pb.Presence_Status
+	text       string
+	idle       bool
+	lastActive time.Time
+	idleTimer  *time.Timer
+}
+
+func (ps *presenceState) presence() *pb.Presence {
+	return &pb.Presence{
+		Status: ps.status,
+		Text:   ps.text,
+		Idle:   ps.idle,
+	}
+}
+
+// visible returns the presence as seen by other users.
+func (ps *presenceState) visible() *pb.Presence {
+	if ps.status == pb.Presence_INVISIBLE {
+		return &pb.Presence{Status: pb.Presence_OFFLINE}
+	}
+	return ps.presence()
+}
+
+func (s *Service) SetPresence(ctx context.Context, presReq *pb.PresenceRequest) (*pb.Presence, error) {
+	s.log.WithContext(ctx).Debug("set presence request")
+	user, err := s.ustorage.CheckCredentials(presReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	switch presReq.Status {
+	case pb.Presence_ONLINE, pb.Presence_AWAY, pb.Presence_BUSY, pb.Presence_INVISIBLE:
+	default:
+		return nil, errors.New("invalid presence status")
+	}
+	if len(presReq.Text) > maxPresenceTextLen {
+		return nil, errors.New("presence text is too long")
+	}
+
+	s.presenceMu.Lock()
+	ps := s.presenceStateLocked(user.Nick)
+	ps.status = presReq.Status
+	ps.text = presReq.Text
+	ps.idle = false
+	s.activeLocked(user.Nick, ps)
+	presence, visible := ps.presence(), ps.visible()
+	s.presenceMu.Unlock()
+
+	s.broadcastPresence(user.Nick, presence, visible)
+
+	return presence, nil
+}
+
+func (s *Service) SendTyping(ctx context.Context, typingReq *pb.TypingRequest) (*pb.TypingResponse, error) {
+	s.log.WithContext(ctx).Debug("typing request")
+	user, err := s.ustorage.CheckCredentials(typingReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	s.touch(user.Nick)
+
+	var to func(nick string) bool
+	switch {
+	case typingReq.To != "":
+		if _, found := s.ustorage.GetUser(typingReq.To); !found {
+			return nil, errors.New("requested user not found")
+		}
+		to = func(nick string) bool { return nick == typingReq.To }
+	case typingReq.Room != "":
+		s.roomsMu.RLock()
+		members, found := s.rooms[typingReq.Room]
+		isMember := members[user.Nick]
+		recipients := make(map[string]bool, len(members))
+		for member := range members {
+			recipients[member] = member != user.Nick
+		}
+		s.roomsMu.RUnlock()
+		if !found {
+			return nil, errRoomNotFound
+		}
+		if !isMember {
+			return nil, errNotMember
+		}
+		to = func(nick string) bool { return recipients[nick] }
+	default:
+		to = func(nick string) bool { return nick != user.Nick }
+	}
+
+	if s.invisible(user.Nick) {
+		return &pb.TypingResponse{}, nil
+	}
+	s.sendEphemeral(
+		&pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_Typing{
+				Typing: &pb.Typing{
+					From: user.Nick,
+					To:   typingReq.To,
+					Time: time.Now().Unix(),
+				},
+			},
+			Room: typingReq.Room,
+		},
+		to,
+	)
+
+	return &pb.TypingResponse{}, nil
+}
+
+// VisibleUsers sets the presence of users as seen by viewer and leaves out
+// users that are invisible to viewer.
+func (s *Service) VisibleUsers(viewer string, users []*pb.User) []*pb.User {
+	s.presenceMu.Lock()
+	defer s.presenceMu.Unlock()
+	visible := users[:0]
+	for _, user := range users {
+		ps, found := s.presence[user.Nick]
+		switch {
+		case !found:
+			user.Presence = &pb.Presence{Status: pb.Presence_ONLINE}
+		case user.Nick == viewer:
+			user.Presence = ps.presence()
+		default:
+			user.Presence = ps.visible()
+		}
+		if user.Presence.Status == pb.Presence_OFFLINE {
+			continue
+		}
+		visible = append(visible, user)
+	}
+	return visible
+}
+
+// touch records activity by nick, ending idleness.
+func (s *Service) touch(nick string) {
+	s.presenceMu.Lock()
+	ps := s.presenceStateLocked(nick)
+	wasIdle := ps.idle
+	ps.idle = false
+	s.activeLocked(nick, ps)
+	presence, visible := ps.presence(), ps.visible()
+	s.presenceMu.Unlock()
+
+	if wasIdle {
+		s.broadcastPresence(nick, presence, visible)
+	}
+}
+
+func (s *Service) presenceStateLocked(nick string) *presenceState {
+	ps, found := s.presence[nick]
+	if !found {
+		ps = &presenceState{status: pb.Presence_ONLINE}
+		s.presence[nick] = ps
+	}
+	return ps
+}
+
+func (s *Service) activeLocked(nick string, ps *presenceState) {
+	ps.lastActive = time.Now()
+	if ps.idleTimer == nil {
+		ps.idleTimer = time.AfterFunc(idleAfter, func() {
+			s.markIdle(nick)
+		})
+		return
+	}
+	ps.idleTimer.Reset(idleAfter)
+}
+
+func (s *Service) markIdle(nick string) {
+	s.presenceMu.Lock()
+	ps, found := s.presence[nick]
+	if !found || ps.idle || ps.status != pb.Presence_ONLINE || time.Since(ps.lastActive) < idleAfter {
+		s.presenceMu.Unlock()
+		return
+	}
+	if user, found := s.ustorage.GetUser(nick); !found || !user.Online {
+		delete(s.presence, nick)
+		s.presenceMu.Unlock()
+		return
+	}
+	ps.idle = true
+	presence, visible := ps.presence(), ps.visible()
+	s.presenceMu.Unlock()
+
+	s.broadcastPresence(nick, presence, visible)
+}
+
+func (s *Service) forgetPresence(nick string) {
+	s.presenceMu.Lock()
+	defer s.presenceMu.Unlock()
+	if ps, found := s.presence[nick]; found && ps.idleTimer != nil {
+		ps.idleTimer.Stop()
+	}
+	delete(s.presence, nick)
+}
+
+func (s *Service) invisible(nick string) bool {
+	s.presenceMu.Lock()
+	defer s.presenceMu.Unlock()
+	ps, found := s.presence[nick]
+	return found && ps.status == pb.Presence_INVISIBLE
+}
+
+// broadcastPresence sends a presence event for nick, with presence to the
+// user itself and visible to everyone else.
+func (s *Service) broadcastPresence(nick string, presence, visible *pb.Presence) {
+	user, found := s.ustorage.GetUser(nick)
+	if !found {
+		return
+	}
+	event := func(p *pb.Presence) *pb.ChatServerMsg {
+		u := user.User
+		u.Presence = p
+		return &pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_UserEvent{
+				UserEvent: &pb.UserEvent{
+					Event: pb.UserEvent_PRESENCE,
+					User:  &u,
+					Time:  time.Now().Unix(),
+				},
+			},
+		}
+	}
+	self, others := event(presence), event(visible)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for n, sessions := range s.sessions {
+		msg := others
+		if n == nick {
+			msg = self
+		}
+		for _, sess := range sessions {
+			s.enqueue(n, sess, msg)
+		}
+	}
+}
+
+// sendEphemeral queues msg for the sessions of the users selected by to. It
+// is of no use later, so it is not counted as dropped if a queue is full.
+func (s *Service) sendEphemeral(msg *pb.ChatServerMsg, to func(nick string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for nick, sessions := range s.sessions {
+		if !to(nick) {
+			continue
+		}
+		for _, sess := range sessions {
+			select {
+			case sess.msgChan <- msg:
+			default:
+			}
+		}
+	}
+}
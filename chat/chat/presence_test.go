@@ -0,0 +1,179 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+type testServer struct {
+	t     *testing.T
+	users *user.Service
+	chat  *chat.Service
+}
+
+func newTestServer(t *testing.T) *testServer {
+	return newTestServerWith(t, storage.NewInMemoryUserStorage(), chat.DefaultSessionTimeout)
+}
+
+func newTestServerWith(t *testing.T, us storage.UserStorage, sessionTimeout time.Duration) *testServer {
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, sessionTimeout, logging.Discard())
+	return &testServer{
+		t:     t,
+		users: user.NewService(cs, us, true, logging.Discard()),
+		chat:  cs,
+	}
+}
+
+// listen logs in nick and returns its listening stream once established.
+func (ts *testServer) listen(nick string) (*pb.Credentials, *fakeStream) {
+	creds, err := ts.users.Login(context.Background(), &pb.LoginRequest{Nick: nick})
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	stream := &fakeStream{msgs: make(chan *pb.ChatServerMsg, 64)}
+	go ts.chat.ListenForMessages(creds, stream)
+	ts.next(stream, func(*pb.ChatServerMsg) bool { return true })
+	return creds, stream
+}
+
+// next returns the next message on stream accepted by match.
+func (ts *testServer) next(stream *fakeStream, match func(*pb.ChatServerMsg) bool) *pb.ChatServerMsg {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-stream.msgs:
+			if match(msg) {
+				return msg
+			}
+		case <-timeout:
+			ts.t.Fatal("timeout waiting for message")
+		}
+	}
+}
+
+func presenceEvent(msg *pb.ChatServerMsg) bool {
+	return msg.GetUserEvent().GetEvent() == pb.UserEvent_PRESENCE
+}
+
+func typingEvent(msg *pb.ChatServerMsg) bool {
+	return msg.GetTyping() != nil
+}
+
+func TestPresence(t *testing.T) {
+	ts := newTestServer(t)
+	alice, aliceStream := ts.listen("alice")
+	bob, bobStream := ts.listen("bob")
+
+	presence, err := ts.chat.SetPresence(context.Background(), &pb.PresenceRequest{
+		Creds:  alice,
+		Status: pb.Presence_AWAY,
+		Text:   "lunch",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presence.Status != pb.Presence_AWAY || presence.Text != "lunch" {
+		t.Errorf("SetPresence: got %v", presence)
+	}
+	got := ts.next(bobStream, presenceEvent).GetUserEvent().GetUser()
+	if got.Nick != "alice" || got.GetPresence().GetStatus() != pb.Presence_AWAY {
+		t.Errorf("presence event: got %v", got)
+	}
+	ts.next(aliceStream, presenceEvent)
+
+	_, err = ts.chat.SetPresence(context.Background(), &pb.PresenceRequest{
+		Creds:  alice,
+		Status: pb.Presence_INVISIBLE,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = ts.next(bobStream, presenceEvent).GetUserEvent().GetUser()
+	if got.GetPresence().GetStatus() != pb.Presence_OFFLINE {
+		t.Errorf("invisible user shown to others as %v", got.GetPresence())
+	}
+	got = ts.next(aliceStream, presenceEvent).GetUserEvent().GetUser()
+	if got.GetPresence().GetStatus() != pb.Presence_INVISIBLE {
+		t.Errorf("invisible user shown to itself as %v", got.GetPresence())
+	}
+
+	checkUsers := func(creds *pb.Credentials, want ...string) {
+		resp, err := ts.users.ListUsers(context.Background(), creds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var nicks []string
+		for _, u := range resp.Users {
+			nicks = append(nicks, u.Nick)
+		}
+		if len(nicks) != len(want) {
+			t.Errorf("users seen by %s: got %v, want %v", creds.Nick, nicks, want)
+			return
+		}
+		for i := range want {
+			if nicks[i] != want[i] {
+				t.Errorf("users seen by %s: got %v, want %v", creds.Nick, nicks, want)
+				return
+			}
+		}
+	}
+	checkUsers(alice, "alice", "bob")
+	checkUsers(bob, "bob")
+
+	_, err = ts.chat.SetPresence(context.Background(), &pb.PresenceRequest{
+		Creds:  alice,
+		Status: pb.Presence_OFFLINE,
+	})
+	if err == nil {
+		t.Error("SetPresence accepted offline status")
+	}
+}
+
+func TestTyping(t *testing.T) {
+	ts := newTestServer(t)
+	alice, _ := ts.listen("alice")
+	_, bobStream := ts.listen("bob")
+	carol, carolStream := ts.listen("carol")
+
+	_, err := ts.chat.SendTyping(context.Background(), &pb.TypingRequest{Creds: alice, To: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := ts.next(carolStream, typingEvent)
+	if msg.GetTyping().From != "alice" || msg.GetTyping().To != "carol" {
+		t.Errorf("typing event: got %v", msg)
+	}
+	if msg.Cursor != 0 {
+		t.Errorf("typing event recorded for resume at cursor %d", msg.Cursor)
+	}
+
+	_, err = ts.chat.SendTyping(context.Background(), &pb.TypingRequest{Creds: carol})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg = ts.next(bobStream, typingEvent)
+	if msg.GetTyping().From != "carol" {
+		t.Errorf("bob got typing event %v, want the public one from carol", msg)
+	}
+
+	_, err = ts.chat.SendTyping(context.Background(), &pb.TypingRequest{Creds: alice, To: "nobody"})
+	if err == nil {
+		t.Error("typing to unknown user accepted")
+	}
+
+	hist, err := ts.chat.GetHistory(context.Background(), &pb.HistoryRequest{Creds: carol})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hist.Msgs) != 0 {
+		t.Errorf("typing stored in history: %v", hist.Msgs)
+	}
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"time"
+
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+// DefaultSessionTimeout is how long a session is kept without a listening
+// stream, long enough for clients to resume after a brief disconnect.
+const DefaultSessionTimeout = 30 * time.Second
+
+// reapIdleSessions logs out sessions that have had no listening stream for
+// the session timeout until the service is shut down. That covers clients
+// that logged in without ever listening as well as broken streams that
+// were not resumed.
+func (s *Service) reapIdleSessions() {
+	ticker := time.NewTicker(s.sessionTimeout / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.reap(now)
+		case <-s.shutdown:
+			return
+		}
+	}
+}
+
+func (s *Service) reap(now time.Time) {
+	for _, user := range s.ustorage.GetAllOnlineUsers() {
+		for _, login := range user.Sessions {
+			if s.idleSince(user.Nick, login).Add(s.sessionTimeout).After(now) {
+				continue
+			}
+			s.expireSession(user.Nick, login.ID)
+		}
+	}
+}
+
+// idleSince returns when the login session last had a listening stream, or
+// the login time if it never had one. It is in the future while a stream
+// is attached.
+func (s *Service) idleSince(nick string, login storage.Session) time.Time {
+	s.mu.Lock()
+	sess := s.sessions[nick][login.ID]
+	s.mu.Unlock()
+	if sess == nil {
+		return time.Unix(login.Created, 0)
+	}
+	detached, ok := sess.detachedSince()
+	if !ok {
+		return time.Now().Add(s.sessionTimeout)
+	}
+	return detached
+}
+
+// expireSession logs out the session id of nick, notifying everyone if that
+// logged the user out.
+func (s *Service) expireSession(nick, id string) {
+	user, removed, err := s.ustorage.RemoveSession(nick, id)
+	if err != nil {
+		s.log.Error("logging out expired session failed", "nick", nick, "session", id, "err", err)
+		return
+	}
+	if removed == 0 {
+		// Logged out in the meantime
+		return
+	}
+	s.countLogouts(removed)
+	// A stream resumed or opened since is of no use without the session
+	s.endSessions(nick, id, errSessionExpired)
+	s.log.Info("session expired", "nick", nick, "session", id)
+	if user.Online {
+		return
+	}
+	s.loggedOut(user)
+
+	s.BroadcastAllConnectedClients(
+		&pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_UserEvent{
+				UserEvent: &pb.UserEvent{
+					Event: pb.UserEvent_LOGOUT,
+					User:  &user.User,
+					Time:  time.Now().Unix(),
+				},
+			},
+		},
+	)
+}
@@ -0,0 +1,237 @@
+package chat
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	pb "github.com/tormoder/chat/proto"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const maxRoomNameLen = 32
+
+var (
+	errRoomNotFound = status.Error(codes.NotFound, "requested room not found")
+	errRoomExists   = errors.New("room already exists")
+	errNotMember    = status.Error(codes.FailedPrecondition, "not a member of room")
+)
+
+func validRoomName(name string) error {
+	if name == "" {
+		return errors.New("room name is empty")
+	}
+	if len(name) > maxRoomNameLen {
+		return errors.New("room name is too long")
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return errors.New("room name contains whitespace")
+	}
+	return nil
+}
+
+func (s *Service) CreateRoom(ctx context.Context, roomReq *pb.RoomRequest) (*pb.RoomResponse, error) {
+	s.log.WithContext(ctx).Debug("create room request")
+	user, err := s.ustorage.CheckCredentials(roomReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	s.touch(user.Nick)
+	if err = validRoomName(roomReq.Room); err != nil {
+		return nil, err
+	}
+
+	s.roomsMu.Lock()
+	if _, found := s.rooms[roomReq.Room]; found {
+		s.roomsMu.Unlock()
+		return nil, errRoomExists
+	}
+	s.rooms[roomReq.Room] = map[string]bool{user.Nick: true}
+	s.roomsMu.Unlock()
+
+	s.log.WithContext(ctx).Info("room created", "room", roomReq.Room)
+
+	return &pb.RoomResponse{}, nil
+}
+
+func (s *Service) JoinRoom(ctx context.Context, roomReq *pb.RoomRequest) (*pb.RoomResponse, error) {
+	s.log.WithContext(ctx).Debug("join room request")
+	user, err := s.ustorage.CheckCredentials(roomReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	s.touch(user.Nick)
+
+	s.roomsMu.Lock()
+	members, found := s.rooms[roomReq.Room]
+	if !found {
+		s.roomsMu.Unlock()
+		return nil, errRoomNotFound
+	}
+	joined := !members[user.Nick]
+	members[user.Nick] = true
+	s.roomsMu.Unlock()
+
+	if joined {
+		s.BroadcastRoom(roomReq.Room, roomUserEvent(roomReq.Room, pb.UserEvent_JOIN, &user.User))
+	}
+
+	return &pb.RoomResponse{}, nil
+}
+
+func (s *Service) LeaveRoom(ctx context.Context, roomReq *pb.RoomRequest) (*pb.RoomResponse, error) {
+	s.log.WithContext(ctx).Debug("leave room request")
+	user, err := s.ustorage.CheckCredentials(roomReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	s.touch(user.Nick)
+
+	s.roomsMu.Lock()
+	members, found := s.rooms[roomReq.Room]
+	if !found {
+		s.roomsMu.Unlock()
+		return nil, errRoomNotFound
+	}
+	if !members[user.Nick] {
+		s.roomsMu.Unlock()
+		return nil, errNotMember
+	}
+	delete(members, user.Nick)
+	s.roomsMu.Unlock()
+
+	s.BroadcastRoom(roomReq.Room, roomUserEvent(roomReq.Room, pb.UserEvent_LEAVE, &user.User))
+
+	return &pb.RoomResponse{}, nil
+}
+
+func (s *Service) ListRooms(ctx context.Context, creds *pb.Credentials) (*pb.ListRoomsResponse, error) {
+	s.log.WithContext(ctx).Debug("list rooms request")
+	_, err := s.ustorage.CheckCredentials(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	s.roomsMu.RLock()
+	rooms := make([]*pb.Room, 0, len(s.rooms))
+	for name, members := range s.rooms {
+		room := &pb.Room{Name: name}
+		for nick := range members {
+			room.Members = append(room.Members, nick)
+		}
+		sort.Strings(room.Members)
+		rooms = append(rooms, room)
+	}
+	s.roomsMu.RUnlock()
+	sort.Sort(byRoomName(rooms))
+
+	return &pb.ListRoomsResponse{
+		Rooms: rooms,
+	}, nil
+}
+
+func (s *Service) SendToRoom(ctx context.Context, roomMsgReq *pb.RoomMsgRequest) (*pb.SendMsgResponse, error) {
+	s.log.WithContext(ctx).Debug("send room message request")
+	user, err := s.ustorage.CheckCredentials(roomMsgReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	if user.IsMuted() {
+		return nil, errMuted
+	}
+	s.touch(user.Nick)
+
+	s.roomsMu.RLock()
+	members, found := s.rooms[roomMsgReq.Room]
+	isMember := members[user.Nick]
+	s.roomsMu.RUnlock()
+	if !found {
+		return nil, errRoomNotFound
+	}
+	if !isMember {
+		return nil, errNotMember
+	}
+
+	s.countSent("room")
+	delivered, dropped := s.BroadcastRoom(
+		roomMsgReq.Room,
+		&pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_PublicMsg{
+				PublicMsg: &pb.PublicMsg{
+					From:     &user.User,
+					Msg:      roomMsgReq.Msg,
+					TimeSent: time.Now().Unix(),
+				},
+			},
+			Room: roomMsgReq.Room,
+		})
+
+	return broadcastResponse(delivered, dropped), nil
+}
+
+// BroadcastRoom queues msg for every session of the members of room and
+// returns how many sessions it was queued for and dropped for.
+func (s *Service) BroadcastRoom(room string, msg *pb.ChatServerMsg) (delivered, dropped int) {
+	s.roomsMu.RLock()
+	var nicks []string
+	for nick := range s.rooms[room] {
+		nicks = append(nicks, nick)
+	}
+	s.roomsMu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, nick := range nicks {
+		for _, sess := range s.sessions[nick] {
+			if s.enqueue(nick, sess, msg) {
+				delivered++
+			} else {
+				dropped++
+			}
+		}
+	}
+	return delivered, dropped
+}
+
+// LeaveAllRooms removes nick from every room it is a member of and notifies
+// the remaining members.
+func (s *Service) LeaveAllRooms(nick string) {
+	s.roomsMu.Lock()
+	var left []string
+	for name, members := range s.rooms {
+		if members[nick] {
+			delete(members, nick)
+			left = append(left, name)
+		}
+	}
+	s.roomsMu.Unlock()
+
+	user := &pb.User{Nick: nick, TimeLastSeen: time.Now().Unix()}
+	for _, room := range left {
+		s.BroadcastRoom(room, roomUserEvent(room, pb.UserEvent_LEAVE, user))
+	}
+}
+
+func roomUserEvent(room string, event pb.UserEvent_EventType, user *pb.User) *pb.ChatServerMsg {
+	return &pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_UserEvent{
+			UserEvent: &pb.UserEvent{
+				Event: event,
+				User:  user,
+				Time:  time.Now().Unix(),
+			},
+		},
+		Room: room,
+	}
+}
+
+type byRoomName []*pb.Room
+
+func (s byRoomName) Len() int           { return len(s) }
+func (s byRoomName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s byRoomName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
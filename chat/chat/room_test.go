@@ -0,0 +1,81 @@
+package chat_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+func roomEvent(room string, event pb.UserEvent_EventType, nick string) func(*pb.ChatServerMsg) bool {
+	return func(msg *pb.ChatServerMsg) bool {
+		e := msg.GetUserEvent()
+		return msg.Room == room && e.GetEvent() == event && e.GetUser().GetNick() == nick
+	}
+}
+
+func TestRooms(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	alice, aliceStream := ts.listen("alice")
+	bob, bobStream := ts.listen("bob")
+
+	if _, err := ts.chat.JoinRoom(ctx, &pb.RoomRequest{Creds: bob, Room: "dev"}); status.Code(err) != codes.NotFound {
+		t.Errorf("joining a missing room: got %v, want NotFound", err)
+	}
+	if _, err := ts.chat.SendToRoom(ctx, &pb.RoomMsgRequest{Creds: bob, Room: "dev", Msg: "hi"}); status.Code(err) != codes.NotFound {
+		t.Errorf("sending to a missing room: got %v, want NotFound", err)
+	}
+	if _, err := ts.chat.CreateRoom(ctx, &pb.RoomRequest{Creds: alice, Room: "dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.chat.SendToRoom(ctx, &pb.RoomMsgRequest{Creds: bob, Room: "dev", Msg: "hi"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("non-member sending to the room: got %v, want FailedPrecondition", err)
+	}
+	if _, err := ts.chat.LeaveRoom(ctx, &pb.RoomRequest{Creds: bob, Room: "dev"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("non-member leaving the room: got %v, want FailedPrecondition", err)
+	}
+
+	if _, err := ts.chat.JoinRoom(ctx, &pb.RoomRequest{Creds: bob, Room: "dev"}); err != nil {
+		t.Fatal(err)
+	}
+	ts.next(aliceStream, roomEvent("dev", pb.UserEvent_JOIN, "bob"))
+	resp, err := ts.chat.SendToRoom(ctx, &pb.RoomMsgRequest{Creds: alice, Room: "dev", Msg: "in the room"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Delivered != 2 {
+		t.Errorf("delivered to %d sessions, want 2", resp.Delivered)
+	}
+	msg := ts.next(bobStream, func(msg *pb.ChatServerMsg) bool { return msg.GetPublicMsg() != nil })
+	if msg.Room != "dev" || msg.GetPublicMsg().Msg != "in the room" {
+		t.Errorf("bob got %v, want the room message", msg)
+	}
+
+	// Rooms keep no history
+	hist, err := ts.chat.GetHistory(ctx, &pb.HistoryRequest{Creds: bob, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range hist.Msgs {
+		if msg.GetPublicMsg().GetMsg() == "in the room" {
+			t.Errorf("room message in history: %v", msg)
+		}
+	}
+
+	// Logging out leaves all rooms
+	if _, err = ts.users.Logout(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	ts.next(aliceStream, roomEvent("dev", pb.UserEvent_LEAVE, "bob"))
+	rooms, err := ts.chat.ListRooms(ctx, alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms.Rooms) != 1 || len(rooms.Rooms[0].Members) != 1 || rooms.Rooms[0].Members[0] != "alice" {
+		t.Errorf("got rooms %v, want dev with only alice", rooms.Rooms)
+	}
+}
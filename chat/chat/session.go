@@ -0,0 +1,397 @@
+package chat
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	c "github.com/tormoder/chat/common"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	// Number of sent messages kept for resuming a session.
+	resumeBufferSize = 256
+	// How long a new stream waits for the stream it replaces to exit.
+	takeoverTimeout = 5 * time.Second
+	// Number of messages queued for a session before messages are dropped.
+	queueSize = 2048
+)
+
+var (
+	errSessionExpired  = errors.New("listening session expired, log in again")
+	errSessionReplaced = errors.New("listening session taken over by another stream")
+)
+
+type msgStream interface {
+	Send(*pb.ChatServerMsg) error
+	Context() context.Context
+}
+
+// session is the message queue of a login session together with the state
+// needed to resume it on a new stream. It outlives its stream by the session
+// timeout, see reapIdleSessions. A user has a session for every client it is logged in from.
+type session struct {
+	id      string
+	msgChan chan *pb.ChatServerMsg
+
+	mu            sync.Mutex // Protects the fields below
+	cursor        uint64
+	sent          []*pb.ChatServerMsg // Recently sent messages, oldest first
+	lastMailboxID uint64
+	gen           uint64        // Incremented for every attached stream
+	stop          chan struct{} // Closed to stop the attached stream
+	done          chan struct{} // Closed when the attached stream exits
+	endErr        error         // Returned by the stream when ended, if set
+	addr          string        // IP address of the last attached stream
+	detached      time.Time     // When the last stream broke, zero while attached
+}
+
+// attach stops the currently attached stream, if any, and returns the
+// generation and channels for a new one.
+func (sess *session) attach() (gen uint64, stop, done chan struct{}) {
+	sess.mu.Lock()
+	prevDone := sess.stopLocked()
+	sess.gen++
+	sess.detached = time.Time{}
+	sess.stop = make(chan struct{})
+	sess.done = make(chan struct{})
+	gen, stop, done = sess.gen, sess.stop, sess.done
+	sess.mu.Unlock()
+	waitDone(prevDone)
+	return gen, stop, done
+}
+
+// end stops the attached stream, if any, and waits for it to exit.
+func (sess *session) end() {
+	sess.endWith(nil)
+}
+
+// endWith is end, with the stream ending with err if not nil.
+func (sess *session) endWith(err error) {
+	sess.mu.Lock()
+	sess.endErr = err
+	sess.gen++
+	prevDone := sess.stopLocked()
+	sess.mu.Unlock()
+	waitDone(prevDone)
+}
+
+func (sess *session) stopLocked() chan struct{} {
+	if sess.stop != nil {
+		close(sess.stop)
+		sess.stop = nil
+	}
+	return sess.done
+}
+
+// wait waits for the attached stream, if any, to exit.
+func (sess *session) wait() {
+	sess.mu.Lock()
+	done := sess.done
+	sess.mu.Unlock()
+	waitDone(done)
+}
+
+func waitDone(done chan struct{}) {
+	if done == nil {
+		return
+	}
+	select {
+	case <-done:
+	case <-time.After(takeoverTimeout):
+	}
+}
+
+func (sess *session) stopErr() error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.endErr
+}
+
+func (sess *session) setAddr(addr string) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.addr = addr
+}
+
+func (sess *session) peerAddr() string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.addr
+}
+
+// markDetached records that the stream of generation gen broke, it reports
+// false if another stream has been attached since.
+func (sess *session) markDetached(gen uint64) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.gen != gen {
+		return false
+	}
+	sess.detached = time.Now()
+	return true
+}
+
+// attached reports whether a stream is attached.
+func (sess *session) attached() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.stop != nil && sess.detached.IsZero()
+}
+
+// detachedSince returns when the last stream broke, or false while one is
+// attached.
+func (sess *session) detachedSince() (time.Time, bool) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.detached, !sess.detached.IsZero()
+}
+
+// record assigns the next cursor to a copy of msg and keeps it for resuming.
+func (sess *session) record(msg *pb.ChatServerMsg) *pb.ChatServerMsg {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.cursor++
+	m := &pb.ChatServerMsg{
+		Msg:    msg.Msg,
+		Room:   msg.Room,
+		Cursor: sess.cursor,
+	}
+	if len(sess.sent) == resumeBufferSize {
+		copy(sess.sent, sess.sent[1:])
+		sess.sent = sess.sent[:len(sess.sent)-1]
+	}
+	sess.sent = append(sess.sent, m)
+	return m
+}
+
+func (sess *session) sentAfter(cursor uint64) []*pb.ChatServerMsg {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	var msgs []*pb.ChatServerMsg
+	for _, m := range sess.sent {
+		if m.Cursor > cursor {
+			msgs = append(msgs, m)
+		}
+	}
+	return msgs
+}
+
+// outgoing returns msg as it is to be sent on the stream, or false if it
+// must be skipped.
+func (sess *session) outgoing(msg *pb.ChatServerMsg) (*pb.ChatServerMsg, bool) {
+	if pmsg := msg.GetPrivateMsg(); pmsg != nil && !sess.markMailboxSent(pmsg.Id) {
+		return nil, false
+	}
+	if msg.GetTyping() != nil {
+		// Ephemeral, not resent on resume
+		return msg, true
+	}
+	return sess.record(msg), true
+}
+
+// markMailboxSent reports whether the private message with the given
+// mailbox id has not been sent in this session before.
+func (sess *session) markMailboxSent(id uint64) bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if id <= sess.lastMailboxID {
+		return false
+	}
+	sess.lastMailboxID = id
+	return true
+}
+
+// newSession registers a fresh session id for nick, replacing any previous
+// one with the same id.
+func (s *Service) newSession(nick, id string) (*session, error) {
+	sess := &session{
+		id:      id,
+		msgChan: make(chan *pb.ChatServerMsg, queueSize),
+	}
+	s.mu.Lock()
+	if s.shutdownMsg != nil {
+		s.mu.Unlock()
+		return nil, errShuttingDown
+	}
+	sessions, found := s.sessions[nick]
+	if !found {
+		sessions = make(map[string]*session)
+		s.sessions[nick] = sessions
+	}
+	prev := sessions[id]
+	sessions[id] = sess
+	s.mu.Unlock()
+	if prev != nil {
+		prev.end()
+	}
+	return sess, nil
+}
+
+// resumableSession returns the session id of nick, unless it has expired.
+func (s *Service) resumableSession(nick, id string) (*session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shutdownMsg != nil {
+		return nil, errShuttingDown
+	}
+	sess, found := s.sessions[nick][id]
+	if !found {
+		return nil, errSessionExpired
+	}
+	return sess, nil
+}
+
+// EndSession stops message delivery to the session id of nick, or to all
+// sessions of nick if id is empty, and logs the session out. It reports
+// whether that logged the user out, in which case the user has left all
+// rooms. No one is notified.
+func (s *Service) EndSession(nick, id string) (storage.User, bool, error) {
+	s.endSessions(nick, id, nil)
+	return s.logout(nick, id)
+}
+
+// endSessions ends the streams of the session id of nick, or of all
+// sessions of nick if id is empty, with err. It returns the number of
+// sessions ended.
+func (s *Service) endSessions(nick, id string, err error) int {
+	var ended []*session
+	s.mu.Lock()
+	for sid, sess := range s.sessions[nick] {
+		if id == "" || sid == id {
+			ended = append(ended, sess)
+			s.removeSessionLocked(nick, sid)
+		}
+	}
+	s.mu.Unlock()
+	for _, sess := range ended {
+		sess.endWith(err)
+	}
+	return len(ended)
+}
+
+func (s *Service) removeSessionLocked(nick, id string) {
+	delete(s.sessions[nick], id)
+	if len(s.sessions[nick]) == 0 {
+		delete(s.sessions, nick)
+	}
+}
+
+// logout removes the login session id of nick, or all its sessions if id
+// is empty. A user left without sessions leaves all rooms, and the private
+// messages of a guest are purged as the nick is free for anyone. It reports
+// whether the user was logged out.
+func (s *Service) logout(nick, id string) (storage.User, bool, error) {
+	user, removed, err := s.ustorage.RemoveSession(nick, id)
+	if err != nil || removed == 0 {
+		return user, false, err
+	}
+	s.countLogouts(removed)
+	if user.Online {
+		return user, false, nil
+	}
+	s.loggedOut(user)
+	return user, true, nil
+}
+
+// loggedOut cleans up after user has logged out of the last session.
+func (s *Service) loggedOut(user storage.User) {
+	s.forgetPresence(user.Nick)
+	s.LeaveAllRooms(user.Nick)
+	if !user.Registered() {
+		if err := s.PurgeNick(user.Nick); err != nil {
+			s.log.Error("purging messages of guest failed", "nick", user.Nick, "err", err)
+		}
+	}
+}
+
+// serveSession sends the messages of sess on stream until the stream breaks
+// or is replaced. Replies, if not nil, are sent as they come.
+func (s *Service) serveSession(nick string, sess *session, resume bool, cursor uint64, stream msgStream, replies <-chan *pb.ChatServerMsg) error {
+	gen, stop, done := sess.attach()
+	defer close(done)
+	if addr, ok := c.PeerHost(stream.Context()); ok {
+		sess.setAddr(addr)
+	}
+
+	if resume {
+		for _, msg := range sess.sentAfter(cursor) {
+			if err := stream.Send(msg); err != nil {
+				return s.detach(nick, sess, gen, err)
+			}
+		}
+	}
+
+	// Private messages received while offline are sent before anything
+	// else. Messages delivered to the queue after the session was
+	// registered may also be pending, so skip any already sent by mailbox id.
+	pending, err := s.mailboxes.Pending(nick)
+	if err != nil {
+		return s.detach(nick, sess, gen, c.InternalServerError("storage error"))
+	}
+	for _, pmsg := range pending {
+		msg, ok := sess.outgoing(&pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_PrivateMsg{
+				PrivateMsg: pmsg,
+			},
+		})
+		if !ok {
+			continue
+		}
+		err = stream.Send(msg)
+		if err != nil {
+			return s.detach(nick, sess, gen, err)
+		}
+	}
+
+	hbTicker := time.NewTicker(time.Second)
+	defer hbTicker.Stop()
+	hb := &pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_Heartbeat{
+			Heartbeat: &pb.Heartbeat{},
+		},
+	}
+
+	log := s.log.WithContext(stream.Context())
+	log.Debug("serving messages", "resumed", resume)
+
+	for {
+		select {
+		case msg := <-sess.msgChan:
+			msg, ok := sess.outgoing(msg)
+			if !ok {
+				continue
+			}
+			err = stream.Send(msg)
+		case reply := <-replies:
+			err = stream.Send(reply)
+		case <-hbTicker.C:
+			err = stream.Send(hb)
+		case <-stop:
+			if err := sess.stopErr(); err != nil {
+				return err
+			}
+			log.Debug("stream replaced")
+			return errSessionReplaced
+		case <-s.shutdown:
+			// Not detached, Shutdown logs the user out
+			return s.sendShutdown(sess, stream)
+		}
+		if err != nil {
+			return s.detach(nick, sess, gen, err)
+		}
+	}
+}
+
+// detach keeps the session of a broken stream around for the session
+// timeout before it is logged out, see reapIdleSessions.
+func (s *Service) detach(nick string, sess *session, gen uint64, err error) error {
+	if sess.markDetached(gen) {
+		s.log.Info("user detached", "nick", nick, "session", sess.id, "err", err)
+	}
+	return err
+}
@@ -0,0 +1,253 @@
+package chat_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tormoder/chat/chat"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+// listenAs logs in with lreq and returns its listening stream once
+// established.
+func (ts *testServer) listenAs(lreq *pb.LoginRequest) (*pb.Credentials, *fakeStream) {
+	creds, err := ts.users.Login(context.Background(), lreq)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	stream := &fakeStream{msgs: make(chan *pb.ChatServerMsg, 64)}
+	go ts.chat.ListenForMessages(creds, stream)
+	ts.next(stream, func(*pb.ChatServerMsg) bool { return true })
+	return creds, stream
+}
+
+func privateMsg(msg *pb.ChatServerMsg) bool {
+	return msg.GetPrivateMsg() != nil
+}
+
+func TestMultipleSessions(t *testing.T) {
+	us := storage.NewInMemoryUserStorage()
+	ts := newTestServerWith(t, us, chat.DefaultSessionTimeout)
+	ctx := context.Background()
+
+	_, err := ts.users.Register(ctx, &pb.RegisterRequest{Nick: "alice", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lreq := &pb.LoginRequest{Nick: "alice", Password: "password1"}
+	laptop, laptopStream := ts.listenAs(lreq)
+	phone, phoneStream := ts.listenAs(lreq)
+	if laptop.Session == phone.Session {
+		t.Fatalf("got session %q twice", laptop.Session)
+	}
+	bob, _ := ts.listen("bob")
+	if _, err = ts.users.Login(ctx, &pb.LoginRequest{Nick: "bob"}); err == nil {
+		t.Error("second login to guest nick: got nil error")
+	}
+
+	resp, err := ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: bob, To: "alice", Msg: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Delivered != 2 {
+		t.Errorf("private message delivered to %d sessions, want 2", resp.Delivered)
+	}
+	for _, stream := range []*fakeStream{laptopStream, phoneStream} {
+		if msg := ts.next(stream, privateMsg); msg.GetPrivateMsg().Msg != "hi" {
+			t.Errorf("got private message %v", msg)
+		}
+	}
+
+	if _, err = ts.users.Logout(ctx, laptop); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := us.GetUser("alice"); !u.Online {
+		t.Error("user offline with a session left")
+	}
+	if _, err = ts.chat.GetStats(ctx, laptop); err == nil {
+		t.Error("logged out session: got nil error")
+	}
+	resp, err = ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: bob, To: "alice", Msg: "still there?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Delivered != 1 {
+		t.Errorf("private message delivered to %d sessions, want 1", resp.Delivered)
+	}
+	ts.next(phoneStream, privateMsg)
+
+	if _, err = ts.users.Logout(ctx, phone); err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := us.GetUser("alice"); u.Online {
+		t.Error("user online after logging out every session")
+	}
+}
+
+// brokenStream fails to send, like the stream of a vanished client.
+type brokenStream struct {
+	fakeStream
+}
+
+func (s *brokenStream) Send(*pb.ChatServerMsg) error {
+	return errors.New("connection reset")
+}
+
+func logoutEvent(nick string) func(*pb.ChatServerMsg) bool {
+	return func(msg *pb.ChatServerMsg) bool {
+		event := msg.GetUserEvent()
+		return event.GetEvent() == pb.UserEvent_LOGOUT && event.GetUser().GetNick() == nick
+	}
+}
+
+func TestSessionTimeout(t *testing.T) {
+	us := storage.NewInMemoryUserStorage()
+	ts := newTestServerWith(t, us, 200*time.Millisecond)
+	ctx := context.Background()
+	_, bobStream := ts.listen("bob")
+
+	// Logged in, but never listening
+	if _, err := ts.users.Login(ctx, &pb.LoginRequest{Nick: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	ts.next(bobStream, logoutEvent("alice"))
+	if u, _ := us.GetUser("alice"); u.Online {
+		t.Error("idle user still online")
+	}
+
+	carol, err := ts.users.Login(ctx, &pb.LoginRequest{Nick: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ts.chat.ListenForMessages(carol, &brokenStream{})
+	if err == nil {
+		t.Fatal("listening on broken stream: got nil error")
+	}
+	ts.next(bobStream, logoutEvent("carol"))
+	err = ts.chat.ResumeListening(&pb.ResumeRequest{Creds: carol}, &fakeStream{msgs: make(chan *pb.ChatServerMsg, 64)})
+	if err == nil {
+		t.Error("resuming expired session: got nil error")
+	}
+
+	if u, _ := us.GetUser("bob"); !u.Online {
+		t.Error("listening user logged out")
+	}
+}
+
+func publicMsg(msg *pb.ChatServerMsg) bool {
+	return msg.GetPublicMsg() != nil
+}
+
+// resume resumes the listening session of creds from cursor and returns
+// the new stream.
+func (ts *testServer) resume(creds *pb.Credentials, cursor uint64) *fakeStream {
+	stream := &fakeStream{msgs: make(chan *pb.ChatServerMsg, 512)}
+	go ts.chat.ResumeListening(&pb.ResumeRequest{Creds: creds, Cursor: cursor}, stream)
+	return stream
+}
+
+// replayed returns the next n messages on stream other than heartbeats.
+func (ts *testServer) replayed(stream *fakeStream, n int) []*pb.ChatServerMsg {
+	var msgs []*pb.ChatServerMsg
+	for len(msgs) < n {
+		msgs = append(msgs, ts.next(stream, func(msg *pb.ChatServerMsg) bool { return !heartbeat(msg) }))
+	}
+	return msgs
+}
+
+func heartbeat(msg *pb.ChatServerMsg) bool {
+	return msg.GetHeartbeat() != nil
+}
+
+func TestResume(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	alice, aliceStream := ts.listen("alice")
+	bob, err := ts.users.Login(ctx, &pb.LoginRequest{Nick: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	send := func(from, to int) {
+		for i := from; i <= to; i++ {
+			if _, err := ts.chat.SendPublic(ctx, &pb.PublicMsgRequest{Creds: bob, Msg: strconv.Itoa(i)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	send(1, 10)
+	cursors := make(map[string]uint64)
+	var last uint64
+	for i := 1; i <= 10; i++ {
+		msg := ts.next(aliceStream, publicMsg)
+		cursors[msg.GetPublicMsg().Msg] = msg.Cursor
+		last = msg.Cursor
+	}
+
+	// Exactly the messages after the cursor are sent again
+	stream := ts.resume(alice, cursors["5"])
+	for i, msg := range ts.replayed(stream, 5) {
+		want := strconv.Itoa(i + 6)
+		if msg.GetPublicMsg().GetMsg() != want || msg.Cursor != cursors[want] {
+			t.Errorf("replayed %v, want message %s with cursor %d", msg, want, cursors[want])
+		}
+	}
+	send(11, 11)
+	if msg := ts.next(stream, publicMsg); msg.GetPublicMsg().Msg != "11" || msg.Cursor != last+1 {
+		t.Errorf("got %v after the replay, want message 11 with cursor %d", msg, last+1)
+	}
+	last++
+
+	// Only the newest messages are kept for resuming, so with a cursor
+	// older than those the ones before are lost
+	const n = 300
+	send(12, 11+n)
+	for i := 0; i < n; i++ {
+		last = ts.next(stream, publicMsg).Cursor
+	}
+	stream = ts.resume(alice, 0)
+	msgs := ts.replayed(stream, chat.ResumeBufferSize)
+	if first := msgs[0].Cursor; first != last-chat.ResumeBufferSize+1 {
+		t.Errorf("replay from cursor %d, want %d", first, last-chat.ResumeBufferSize+1)
+	}
+	if msg := msgs[len(msgs)-1]; msg.Cursor != last || msg.GetPublicMsg().Msg != strconv.Itoa(11+n) {
+		t.Errorf("replay ends with %v, want message %d with cursor %d", msg, 11+n, last)
+	}
+	send(12+n, 12+n)
+	if msg := ts.next(stream, func(msg *pb.ChatServerMsg) bool { return !heartbeat(msg) }); msg.GetPublicMsg().GetMsg() != strconv.Itoa(12+n) {
+		t.Errorf("got %v after the replay, want only the kept messages replayed", msg)
+	}
+}
+
+func TestSessionCounts(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	alice, _ := ts.listen("alice")
+	carol, err := ts.users.Login(ctx, &pb.LoginRequest{Nick: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ts.chat.ListenForMessages(carol, &brokenStream{}); err == nil {
+		t.Fatal("listening on broken stream: got nil error")
+	}
+	// Detached sessions are kept, but not listening
+	if n := ts.chat.AttachedCount(); n != 1 {
+		t.Errorf("got %d attached sessions, want 1", n)
+	}
+
+	if _, err = ts.users.Logout(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	ts.chat.Kick("carol", "testing")
+	if n := ts.chat.AttachedCount(); n != 0 {
+		t.Errorf("got %d attached sessions after logging out, want 0", n)
+	}
+	if n := ts.chat.LogoutCount(); n != 2 {
+		t.Errorf("got %d logouts, want 2", n)
+	}
+}
@@ -0,0 +1,89 @@
+package chat
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Unavailable so that clients retry rather than log in again.
+var errShuttingDown = status.Error(codes.Unavailable, "server shutting down")
+
+// Shutdown sends a ServerShutdown message with the given reason and
+// reconnect hint (zero if unknown) as the last message on every listening
+// stream, ends the streams and marks all users offline. New listening
+// streams are refused afterwards.
+func (s *Service) Shutdown(reason string, reconnectAfter time.Duration) {
+	s.mu.Lock()
+	if s.shutdownMsg != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.shutdownMsg = &pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_ServerShutdown{
+			ServerShutdown: &pb.ServerShutdown{
+				Reason:         reason,
+				ReconnectAfter: int64(reconnectAfter / time.Second),
+			},
+		},
+	}
+	close(s.shutdown)
+	sessions := s.sessions
+	s.sessions = make(map[string]map[string]*session)
+	s.mu.Unlock()
+
+	var (
+		wg sync.WaitGroup
+		n  int
+	)
+	for _, userSessions := range sessions {
+		for _, sess := range userSessions {
+			n++
+			wg.Add(1)
+			go func(sess *session) {
+				defer wg.Done()
+				sess.wait()
+				sess.end()
+			}(sess)
+		}
+	}
+	wg.Wait()
+
+	for _, user := range s.ustorage.GetAllOnlineUsers() {
+		s.logout(user.Nick, "")
+	}
+	s.publish(topicNodes, &pb.ClusterMsg{Leaving: true})
+	s.log.Info("chat service shut down", "sessions", n)
+}
+
+func (s *Service) shuttingDown() bool {
+	select {
+	case <-s.shutdown:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendShutdown sends what is left in the session queue followed by the
+// shutdown message.
+func (s *Service) sendShutdown(sess *session, stream msgStream) error {
+	for {
+		select {
+		case msg := <-sess.msgChan:
+			msg, ok := sess.outgoing(msg)
+			if !ok {
+				continue
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		default:
+			return stream.Send(sess.record(s.shutdownMsg))
+		}
+	}
+}
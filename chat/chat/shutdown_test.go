@@ -0,0 +1,87 @@
+package chat_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	msgs chan *pb.ChatServerMsg
+}
+
+func (s *fakeStream) Send(msg *pb.ChatServerMsg) error {
+	s.msgs <- msg
+	return nil
+}
+
+func (s *fakeStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestShutdown(t *testing.T) {
+	us := storage.NewInMemoryUserStorage()
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, chat.DefaultSessionTimeout, logging.Discard())
+	users := user.NewService(cs, us, true, logging.Discard())
+	creds, err := users.Login(context.Background(), &pb.LoginRequest{Nick: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := &fakeStream{msgs: make(chan *pb.ChatServerMsg, 16)}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- cs.ListenForMessages(creds, stream)
+	}()
+	select {
+	case <-stream.msgs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message on listening stream")
+	}
+
+	cs.Shutdown("maintenance", 30*time.Second)
+
+	select {
+	case err = <-errc:
+		if err != nil {
+			t.Fatalf("listening stream ended with error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listening stream not ended by shutdown")
+	}
+	var last *pb.ChatServerMsg
+	for len(stream.msgs) > 0 {
+		last = <-stream.msgs
+	}
+	shutdown := last.GetServerShutdown()
+	if shutdown == nil {
+		t.Fatalf("last message on stream: got %v, want server shutdown", last)
+	}
+	if shutdown.Reason != "maintenance" || shutdown.ReconnectAfter != 30 {
+		t.Errorf("shutdown message: got %v", shutdown)
+	}
+
+	if u, _ := us.GetUser("alice"); u.Online {
+		t.Error("user still online after shutdown")
+	}
+
+	creds, err = users.Login(context.Background(), &pb.LoginRequest{Nick: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cs.ListenForMessages(creds, stream)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("listening after shutdown: got error %v, want code Unavailable", err)
+	}
+}
@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Replies waiting to be sent on a Chat stream before reading more requests.
+const replyQueueSize = 64
+
+var (
+	errNotOpened      = status.Error(codes.InvalidArgument, "the chat stream must be opened first")
+	errAlreadyOpened  = status.Error(codes.InvalidArgument, "the chat stream is already open")
+	errUnknownRequest = status.Error(codes.InvalidArgument, "unknown request")
+)
+
+// Chat serves a listening session like ListenForMessages, or
+// ResumeListening if so asked in the open message, and answers the
+// requests sent on the stream with replies in between the messages.
+func (s *Service) Chat(stream pb.ChatService_ChatServer) error {
+	s.log.WithContext(stream.Context()).Debug("chat request")
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	open := first.GetOpen()
+	if open == nil {
+		return errNotOpened
+	}
+	user, err := s.ustorage.CheckCredentials(open.Creds)
+	if err != nil {
+		return err
+	}
+
+	var sess *session
+	if open.Resume {
+		sess, err = s.resumableSession(user.Nick, open.Creds.Session)
+	} else {
+		sess, err = s.newSession(user.Nick, open.Creds.Session)
+		s.touch(user.Nick)
+	}
+	if err != nil {
+		return err
+	}
+
+	replies := make(chan *pb.ChatServerMsg, replyQueueSize)
+	go s.serveRequests(open.Creds, stream, replies)
+	return s.serveSession(user.Nick, sess, open.Resume, open.Cursor, stream, replies)
+}
+
+// serveRequests handles the requests on stream in order until it is closed,
+// queueing the replies for the stream.
+func (s *Service) serveRequests(creds *pb.Credentials, stream pb.ChatService_ChatServer, replies chan<- *pb.ChatServerMsg) {
+	ctx := stream.Context()
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return
+		}
+		reply := &pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_Reply{
+				Reply: s.handleRequest(ctx, creds, msg),
+			},
+		}
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Service) handleRequest(ctx context.Context, creds *pb.Credentials, msg *pb.ChatClientMsg) *pb.Reply {
+	reply := &pb.Reply{Id: msg.Id}
+	var err error
+	switch m := msg.Msg.(type) {
+	case *pb.ChatClientMsg_Open:
+		err = errAlreadyOpened
+	case *pb.ChatClientMsg_Public:
+		m.Public.Creds = creds
+		reply.Sent, err = s.SendPublic(ctx, m.Public)
+	case *pb.ChatClientMsg_Private:
+		m.Private.Creds = creds
+		reply.Sent, err = s.SendPrivate(ctx, m.Private)
+	case *pb.ChatClientMsg_Room:
+		m.Room.Creds = creds
+		reply.Sent, err = s.SendToRoom(ctx, m.Room)
+	case *pb.ChatClientMsg_CreateRoom:
+		m.CreateRoom.Creds = creds
+		_, err = s.CreateRoom(ctx, m.CreateRoom)
+	case *pb.ChatClientMsg_JoinRoom:
+		m.JoinRoom.Creds = creds
+		_, err = s.JoinRoom(ctx, m.JoinRoom)
+	case *pb.ChatClientMsg_LeaveRoom:
+		m.LeaveRoom.Creds = creds
+		_, err = s.LeaveRoom(ctx, m.LeaveRoom)
+	case *pb.ChatClientMsg_Ack:
+		m.Ack.Creds = creds
+		_, err = s.AckMessages(ctx, m.Ack)
+	case *pb.ChatClientMsg_MarkRead:
+		m.MarkRead.Creds = creds
+		_, err = s.MarkRead(ctx, m.MarkRead)
+	case *pb.ChatClientMsg_Presence:
+		m.Presence.Creds = creds
+		reply.Presence, err = s.SetPresence(ctx, m.Presence)
+	case *pb.ChatClientMsg_Typing:
+		m.Typing.Creds = creds
+		_, err = s.SendTyping(ctx, m.Typing)
+	case *pb.ChatClientMsg_Ping:
+		m.Ping.Creds = creds
+		_, err = s.Ping(ctx, m.Ping)
+	default:
+		err = errUnknownRequest
+	}
+	if err != nil {
+		st := status.Convert(err)
+		reply.Code = int32(st.Code())
+		reply.Error = st.Message()
+	}
+	return reply
+}
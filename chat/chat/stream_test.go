@@ -0,0 +1,96 @@
+package chat_test
+
+import (
+	"io"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// fakeChatStream is the server side of a Chat stream, reading the client
+// messages from in until it is closed.
+type fakeChatStream struct {
+	fakeStream
+	in chan *pb.ChatClientMsg
+}
+
+func newFakeChatStream() *fakeChatStream {
+	return &fakeChatStream{
+		fakeStream: fakeStream{msgs: make(chan *pb.ChatServerMsg, 64)},
+		in:         make(chan *pb.ChatClientMsg, 16),
+	}
+}
+
+func (s *fakeChatStream) Recv() (*pb.ChatClientMsg, error) {
+	msg, ok := <-s.in
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func replyTo(id uint64) func(*pb.ChatServerMsg) bool {
+	return func(msg *pb.ChatServerMsg) bool {
+		return msg.GetReply() != nil && msg.GetReply().Id == id
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	ts := newTestServer(t)
+	_, bobStream := ts.listen("bob")
+	alice, err := ts.users.Login(context.Background(), &pb.LoginRequest{Nick: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := newFakeChatStream()
+	defer close(stream.in)
+	stream.in <- &pb.ChatClientMsg{Msg: &pb.ChatClientMsg_Open{Open: &pb.ChatOpen{Creds: alice}}}
+	go ts.chat.Chat(stream)
+
+	stream.in <- &pb.ChatClientMsg{
+		Id:  1,
+		Msg: &pb.ChatClientMsg_Public{Public: &pb.PublicMsgRequest{Msg: "hello"}},
+	}
+	reply := ts.next(&stream.fakeStream, replyTo(1)).GetReply()
+	if reply.Code != 0 || reply.Sent.GetStatus() != pb.SendMsgResponse_DELIVERED {
+		t.Errorf("public message: got reply %v", reply)
+	}
+	msg := ts.next(bobStream, func(msg *pb.ChatServerMsg) bool { return msg.GetPublicMsg() != nil })
+	if from := msg.GetPublicMsg().GetFrom().GetNick(); from != "alice" {
+		t.Errorf("public message from %q, want alice", from)
+	}
+
+	stream.in <- &pb.ChatClientMsg{
+		Id:  2,
+		Msg: &pb.ChatClientMsg_Private{Private: &pb.PrivateMsgRequest{To: "bob", Msg: "psst"}},
+	}
+	stream.in <- &pb.ChatClientMsg{
+		Id:  3,
+		Msg: &pb.ChatClientMsg_JoinRoom{JoinRoom: &pb.RoomRequest{Room: "nowhere"}},
+	}
+	if reply = ts.next(&stream.fakeStream, replyTo(2)).GetReply(); reply.Sent.GetId() == 0 {
+		t.Errorf("private message: got reply %v", reply)
+	}
+	ts.next(bobStream, privateMsg)
+	if reply = ts.next(&stream.fakeStream, replyTo(3)).GetReply(); reply.Code == 0 || reply.Error == "" {
+		t.Errorf("joining missing room: got reply %v, want error", reply)
+	}
+
+	// Events arrive on the same stream
+	ts.listen("carol")
+	ts.next(&stream.fakeStream, func(msg *pb.ChatServerMsg) bool {
+		return msg.GetUserEvent().GetUser().GetNick() == "carol"
+	})
+}
+
+func TestChatStreamNotOpened(t *testing.T) {
+	ts := newTestServer(t)
+	stream := newFakeChatStream()
+	stream.in <- &pb.ChatClientMsg{Msg: &pb.ChatClientMsg_Ping{Ping: &pb.PingRequest{}}}
+	if err := ts.chat.Chat(stream); err == nil {
+		t.Error("request before open: got nil error")
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+var errPasswordsDiffer = errors.New("passwords do not match")
+
+// login logs in as nick, prompting for a password if the nick turns out to
+// be registered and none was given.
+func login(nick string) (*pb.Credentials, error) {
+	creds, err := attemptLogin(nick)
+	if status.Code(err) != codes.Unauthenticated || getPassword() != "" || *batchFile != "" {
+		return creds, err
+	}
+	setPassword(cui.promptForPassword("password"))
+	return attemptLogin(nick)
+}
+
+func registerNick(nick string) error {
+	pw := getPassword()
+	if pw == "" {
+		if *batchFile != "" {
+			return errors.New("the -register flag requires -password-file with -batch")
+		}
+		pw = cui.promptForPassword("new password")
+		if cui.promptForPassword("new password again") != pw {
+			return errPasswordsDiffer
+		}
+	}
+	rreq := &pb.RegisterRequest{
+		Nick:     nick,
+		Password: pw,
+	}
+	_, err := userService.Register(context.Background(), rreq)
+	if err != nil {
+		return err
+	}
+	setPassword(pw)
+	return nil
+}
+
+func readPasswordFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if line == "" && err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// registerSelf registers the nick logged in as a guest.
+func registerSelf(pw string) {
+	creds := getCredentials()
+	rreq := &pb.RegisterRequest{
+		Creds:    creds,
+		Nick:     creds.Nick,
+		Password: pw,
+	}
+	_, err := userService.Register(context.Background(), rreq)
+	if err != nil {
+		cui.ln("Unable to register:", err)
+		return
+	}
+	setPassword(pw)
+	cui.ln("Registered", creds.Nick)
+}
+
+func changePassword(oldPw, newPw string) {
+	areq := &pb.AccountRequest{
+		Creds:       getCredentials(),
+		Password:    oldPw,
+		NewPassword: newPw,
+	}
+	_, err := userService.ChangePassword(context.Background(), areq)
+	if err != nil {
+		cui.ln("Unable to change password:", err)
+		return
+	}
+	setPassword(newPw)
+	cui.ln("Password changed")
+}
+
+// deleteAccount deletes the account and reports whether it succeeded, the
+// client is logged out if so.
+func deleteAccount(pw string) bool {
+	areq := &pb.AccountRequest{
+		Creds:    getCredentials(),
+		Password: pw,
+	}
+	_, err := userService.DeleteAccount(context.Background(), areq)
+	if err != nil {
+		cui.ln("Unable to delete account:", err)
+		return false
+	}
+	setCredentials(nil)
+	cui.ln("Account deleted")
+	return true
+}
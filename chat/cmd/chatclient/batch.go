@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+
+	"github.com/golang/protobuf/jsonpb"
+)
+
+var batchMarshaler = jsonpb.Marshaler{OrigName: true}
+
+// runBatch runs the commands read from path, or stdin if path is "-", until
+// the end of input or a quit command. Incoming messages are written to
+// stdout as one JSON encoded ChatServerMsg per line, command output and
+// client notices go to stderr.
+func runBatch(path string) error {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	lines := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		s := bufio.NewScanner(in)
+		for s.Scan() {
+			lines <- s.Text()
+		}
+		readErr <- s.Err()
+		close(lines)
+	}()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				return writeBatchMsgs(out, <-readErr)
+			}
+			if runCmd(line) == errQuit {
+				return writeBatchMsgs(out, nil)
+			}
+		case msg := <-tocuiChan:
+			err := writeBatchMsg(out, msg)
+			if err == nil {
+				err = writeBatchMsgs(out, nil)
+			}
+			if err == nil {
+				err = out.Flush()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// writeBatchMsgs writes the messages already received and returns err.
+func writeBatchMsgs(out *bufio.Writer, err error) error {
+	for len(tocuiChan) > 0 {
+		if werr := writeBatchMsg(out, <-tocuiChan); werr != nil {
+			return werr
+		}
+	}
+	return err
+}
+
+func writeBatchMsg(out io.Writer, msg uiMsg) error {
+	if msg.msg == nil {
+		cui.ln(msg.text)
+		return nil
+	}
+	err := batchMarshaler.Marshal(out, msg.msg)
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(out, "\n"); err != nil {
+		return err
+	}
+	if msg.readID != 0 {
+		privateMsgShown(msg.readID)
+	}
+	return nil
+}
@@ -1,60 +1,127 @@
 package main
 
 import (
-	"bytes"
-	"fmt"
-	"time"
-)
+	"errors"
 
-const (
-	listAllUsers = iota
-	sendPublicMessage
-	sendPrivateMessage
-	logout
+	"github.com/tormoder/chat/command"
 )
 
-var cmdTypes = [...]string{
-	"List all online users",
-	"Send a public message",
-	"Send a private message",
-	"Logout",
-}
+var errQuit = errors.New("quit")
 
-var cmdTypesShort = [...]string{
-	"List users",
-	"Send public",
-	"Send private",
-	"Logout",
-}
-
-var cmdsShort string
+var parser = command.NewParser(
+	"say",
+	command.Spec{Name: "say", Usage: "<text>", Desc: "Send a public message (same as typing without a command)", NArgs: 1},
+	command.Spec{Name: "msg", Usage: "<nick> <text>", Desc: "Send a private message", NArgs: 2},
+	command.Spec{Name: "users", Desc: "List all online users"},
+	command.Spec{Name: "history", Desc: "Show recent messages"},
+	command.Spec{Name: "rooms", Desc: "List all rooms"},
+	command.Spec{Name: "create", Usage: "<room>", Desc: "Create a room", NArgs: 1},
+	command.Spec{Name: "join", Usage: "<room>", Desc: "Join a room", NArgs: 1},
+	command.Spec{Name: "leave", Usage: "<room>", Desc: "Leave a room", NArgs: 1},
+	command.Spec{Name: "room", Usage: "<room> <text>", Desc: "Send a message to a room", NArgs: 2},
+	command.Spec{Name: "stats", Desc: "Show delivery statistics"},
+	command.Spec{Name: "ping", Desc: "Show the round-trip time to the chat server"},
+	command.Spec{Name: "fingerprint", Usage: "[<nick>]", Desc: "Show your key fingerprint or the one of nick, to compare with them", NArgs: 1, Optional: true},
+	command.Spec{Name: "status", Usage: "<online|away|busy|invisible> [<text>]", Desc: "Set your presence", NArgs: 2, Optional: true},
+	command.Spec{Name: "register", Usage: "<password>", Desc: "Protect your nick with a password", NArgs: 1},
+	command.Spec{Name: "passwd", Usage: "<old> <new>", Desc: "Change your password", NArgs: 2},
+	command.Spec{Name: "unregister", Usage: "[<password>]", Desc: "Delete your account and exit", NArgs: 1, Optional: true},
+	command.Spec{Name: "kick", Usage: "<nick> [<reason>]", Desc: "Disconnect a user (moderators)", NArgs: 2, Optional: true},
+	command.Spec{Name: "ban", Usage: "<nick|address> <duration> [<reason>]", Desc: "Ban a nick or IP address, 0 for permanent (moderators)", NArgs: 3, Optional: true},
+	command.Spec{Name: "unban", Usage: "<nick|address>", Desc: "Lift a ban (moderators)", NArgs: 1},
+	command.Spec{Name: "bans", Desc: "List all bans (moderators)"},
+	command.Spec{Name: "mute", Usage: "<nick> <duration>", Desc: "Stop a user sending public and room messages, 0 until unmuted (moderators)", NArgs: 2},
+	command.Spec{Name: "unmute", Usage: "<nick>", Desc: "Unmute a user (moderators)", NArgs: 1},
+	command.Spec{Name: "role", Usage: "<nick> <user|moderator|admin>", Desc: "Set the role of a user (admins)", NArgs: 2},
+	command.Spec{Name: "help", Desc: "Show available commands"},
+	command.Spec{Name: "quit", Desc: "Logout and exit"},
+)
 
-func init() {
-	var output bytes.Buffer
-	output.WriteString("[menu] ")
-	for i, userOption := range cmdTypesShort {
-		output.WriteString(
-			fmt.Sprintf("%d. %s ", i+1, userOption),
-		)
+// runCmd runs the command on an input line, it returns errQuit for the quit
+// command.
+func runCmd(line string) error {
+	cmd, err := parser.Parse(line)
+	if err != nil {
+		cui.ln("Error:", err)
+		return nil
 	}
-	cmdsShort = output.String()
-}
-
-func getAvailableCmds() string {
-	var out bytes.Buffer
-	out.WriteString("Available commands:\n")
-	for i, userOption := range cmdTypes {
-		out.WriteString(
-			fmt.Sprintf("\t%d. %s\n", i+1, userOption),
-		)
+	if cmd == nil {
+		return nil
 	}
-	return out.String()
-}
 
-func getAvailableCmdsShort() string {
-	var out bytes.Buffer
-	out.WriteString(time.Now().Format(tformat))
-	out.WriteString(" ")
-	out.WriteString(cmdsShort)
-	return out.String()
+	switch cmd.Name {
+	case "say":
+		sendPublicMsg(cmd.Args[0])
+	case "msg":
+		sendPrivateMsg(cmd.Args[0], cmd.Args[1])
+	case "users":
+		printAllUsers()
+	case "history":
+		printHistory()
+	case "rooms":
+		printAllRooms()
+	case "create":
+		roomAction("create", cmd.Args[0])
+	case "join":
+		roomAction("join", cmd.Args[0])
+	case "leave":
+		roomAction("leave", cmd.Args[0])
+	case "room":
+		sendRoomMsg(cmd.Args[0], cmd.Args[1])
+	case "stats":
+		printStats()
+	case "ping":
+		printPing()
+	case "fingerprint":
+		nick := ""
+		if len(cmd.Args) > 0 {
+			nick = cmd.Args[0]
+		}
+		printFingerprint(nick)
+	case "status":
+		text := ""
+		if len(cmd.Args) > 1 {
+			text = cmd.Args[1]
+		}
+		setPresence(cmd.Args[0], text)
+	case "register":
+		registerSelf(cmd.Args[0])
+	case "passwd":
+		changePassword(cmd.Args[0], cmd.Args[1])
+	case "unregister":
+		pw := ""
+		if len(cmd.Args) > 0 {
+			pw = cmd.Args[0]
+		}
+		if deleteAccount(pw) {
+			return errQuit
+		}
+	case "kick":
+		reason := ""
+		if len(cmd.Args) > 1 {
+			reason = cmd.Args[1]
+		}
+		kickUser(cmd.Args[0], reason)
+	case "ban":
+		reason := ""
+		if len(cmd.Args) > 2 {
+			reason = cmd.Args[2]
+		}
+		banTarget(cmd.Args[0], cmd.Args[1], reason)
+	case "unban":
+		unbanTarget(cmd.Args[0])
+	case "bans":
+		printBans()
+	case "mute":
+		muteUser(cmd.Args[0], cmd.Args[1])
+	case "unmute":
+		unmuteUser(cmd.Args[0])
+	case "role":
+		setRole(cmd.Args[0], cmd.Args[1])
+	case "help":
+		cui.ln(parser.Help())
+	case "quit":
+		return errQuit
+	}
+	return nil
 }
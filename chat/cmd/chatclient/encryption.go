@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+	"golang.org/x/net/context"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Private messages are sealed with NaCl box from our key to the recipient's,
+// see pb.EncryptedMsg. The server vouches for which key belongs to which
+// nick, users verify that by comparing fingerprints.
+
+var (
+	publicKey, privateKey *[32]byte // Set by loadKey
+
+	knownKeys   = make(map[string][]byte) // Key last seen per nick
+	knownKeysMu sync.Mutex                // Protects knownKeys
+)
+
+var (
+	errBadKeyFile = errors.New("key file must hold a hex encoded 32 byte key")
+	errBadKey     = errors.New("malformed key")
+	errOtherKey   = errors.New("encrypted for another key")
+	errForged     = errors.New("forged or corrupted")
+)
+
+// loadKey reads the private key from the file at path, creating the file
+// with a new key if it does not exist. Without a path the key is new for
+// every run.
+func loadKey(path string) error {
+	if path == "" {
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		publicKey, privateKey = pub, priv
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		pub, priv, err := box.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(path, []byte(hex.EncodeToString(priv[:])+"\n"), 0600)
+		if err != nil {
+			return err
+		}
+		publicKey, privateKey = pub, priv
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return errBadKeyFile
+	}
+	pub, err := curve25519.X25519(key, curve25519.Basepoint)
+	if err != nil {
+		return err
+	}
+	publicKey, privateKey = new([32]byte), new([32]byte)
+	copy(publicKey[:], pub)
+	copy(privateKey[:], key)
+	return nil
+}
+
+// fingerprint returns a short hash of key for users to compare.
+func fingerprint(key []byte) string {
+	sum := sha256.Sum256(key)
+	digits := hex.EncodeToString(sum[:16])
+	groups := make([]string, 0, len(digits)/4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+	return strings.Join(groups, " ")
+}
+
+// learnKey remembers key as the key of nick, warning if it has changed.
+func learnKey(nick string, key []byte) {
+	knownKeysMu.Lock()
+	old, known := knownKeys[nick]
+	knownKeys[nick] = key
+	knownKeysMu.Unlock()
+	if known && !bytes.Equal(old, key) {
+		notifyUI(fmt.Sprintf(
+			"The key of %s has changed, its fingerprint is now %s. Compare it with %s before trusting private messages.",
+			nick, fingerprint(key), nick,
+		))
+	}
+}
+
+// fetchKey returns the key nick has published.
+func fetchKey(nick string) ([]byte, error) {
+	kreq := &pb.PublicKeyRequest{
+		Creds: getCredentials(),
+		Nick:  nick,
+	}
+	kresp, err := userService.GetPublicKey(context.Background(), kreq)
+	if err != nil {
+		return nil, err
+	}
+	learnKey(nick, kresp.PublicKey)
+	return kresp.PublicKey, nil
+}
+
+func encrypt(text string, recipientKey []byte) (*pb.EncryptedMsg, error) {
+	var peer [32]byte
+	if len(recipientKey) != len(peer) {
+		return nil, errBadKey
+	}
+	copy(peer[:], recipientKey)
+	var nonce [24]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	return &pb.EncryptedMsg{
+		SenderKey:    publicKey[:],
+		RecipientKey: recipientKey,
+		Nonce:        nonce[:],
+		Box:          box.Seal(nil, []byte(text), &nonce, &peer, privateKey),
+	}, nil
+}
+
+func decrypt(enc *pb.EncryptedMsg) (string, error) {
+	if !bytes.Equal(enc.RecipientKey, publicKey[:]) {
+		return "", errOtherKey
+	}
+	var (
+		peer  [32]byte
+		nonce [24]byte
+	)
+	if len(enc.SenderKey) != len(peer) || len(enc.Nonce) != len(nonce) {
+		return "", errBadKey
+	}
+	copy(peer[:], enc.SenderKey)
+	copy(nonce[:], enc.Nonce)
+	text, ok := box.Open(nil, enc.Box, &nonce, &peer, privateKey)
+	if !ok {
+		return "", errForged
+	}
+	return string(text), nil
+}
+
+// decryptPrivateMsg replaces the text of an encrypted private message with
+// the decrypted text, or a note on why it cannot be read. The sender's key
+// is remembered for messages that just arrived, live, as opposed to
+// messages from the history that may predate a key change.
+func decryptPrivateMsg(pmsg *pb.PrivateMsg, live bool) {
+	enc := pmsg.Encrypted
+	if enc == nil {
+		return
+	}
+	from := pmsg.GetFrom().GetNick()
+	if from == getCredentials().GetNick() && !bytes.Equal(enc.RecipientKey, publicKey[:]) {
+		pmsg.Msg = fmt.Sprintf("(encrypted for %s)", pmsg.To)
+		return
+	}
+	text, err := decrypt(enc)
+	if err != nil {
+		pmsg.Msg = fmt.Sprintf("(unable to decrypt: %v)", err)
+		return
+	}
+	if live {
+		learnKey(from, enc.SenderKey)
+	}
+	pmsg.Msg = text
+}
+
+func printFingerprint(nick string) {
+	if nick == "" {
+		cui.ln("Your key fingerprint:", fingerprint(publicKey[:]))
+		return
+	}
+	key, err := fetchKey(nick)
+	if err != nil {
+		cui.ln("Unable to get key:", err)
+		return
+	}
+	cui.f("Key fingerprint of %s: %s\n", nick, fingerprint(key))
+}
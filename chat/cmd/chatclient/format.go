@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"time"
 
 	pb "github.com/tormoder/chat/proto"
@@ -25,8 +26,10 @@ func formatUserList(users []*pb.User) string {
 		output.WriteString("One logged-in user:\n")
 		output.WriteString(
 			fmt.Sprintf(
-				"\t1. %s (last seen %s)",
+				"\t1. %s%s%s (last seen %s)",
+				formatRolePrefix(users[0].Role),
 				users[0].Nick,
+				formatPresenceSuffix(users[0].Presence),
 				formatUnixTime(users[0].TimeLastSeen),
 			),
 		)
@@ -37,9 +40,11 @@ func formatUserList(users []*pb.User) string {
 		for i, user := range users {
 			output.WriteString(
 				fmt.Sprintf(
-					"\n\t%d. %s\t(last seen %s)",
+					"\n\t%d. %s%s%s\t(last seen %s)",
 					i+1,
+					formatRolePrefix(user.Role),
 					user.Nick,
+					formatPresenceSuffix(user.Presence),
 					formatUnixTime(user.TimeLastSeen),
 				),
 			)
@@ -49,26 +54,122 @@ func formatUserList(users []*pb.User) string {
 	return output.String()
 }
 
+func formatSendResult(resp *pb.SendMsgResponse) string {
+	var output bytes.Buffer
+	output.WriteString(time.Now().Format(tformat))
+	output.WriteString(" [info] ")
+	if resp.Id != 0 {
+		output.WriteString(fmt.Sprintf("Private message #%d ", resp.Id))
+	} else {
+		output.WriteString("Message ")
+	}
+	switch resp.Status {
+	case pb.SendMsgResponse_DELIVERED:
+		output.WriteString(fmt.Sprintf("delivered to %d", resp.Delivered))
+	case pb.SendMsgResponse_QUEUED:
+		output.WriteString("queued")
+	case pb.SendMsgResponse_DROPPED:
+		output.WriteString("dropped")
+	case pb.SendMsgResponse_RELAYED:
+		output.WriteString("relayed to another server")
+	default:
+		output.WriteString("sent")
+	}
+	if resp.Reason != "" {
+		output.WriteString(": " + resp.Reason)
+	}
+	return output.String()
+}
+
+func formatStats(stats *pb.StatsResponse) string {
+	return fmt.Sprintf(
+		"%s [info] Messages dropped for you: %d, currently queued: %d",
+		time.Now().Format(tformat),
+		stats.DroppedMsgs,
+		stats.QueueLen,
+	)
+}
+
+func formatPing(rtt time.Duration) string {
+	return fmt.Sprintf("%s [info] Round-trip time to chat server: %s", time.Now().Format(tformat), formatRTT(rtt))
+}
+
+// formatRTT formats a round-trip time in milliseconds.
+func formatRTT(rtt time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(rtt)/float64(time.Millisecond))
+}
+
+func formatRoomList(rooms []*pb.Room) string {
+	var output bytes.Buffer
+	output.WriteString(time.Now().Format(tformat))
+	output.WriteString(" [info] ")
+	if len(rooms) == 0 {
+		output.WriteString("No rooms")
+		return output.String()
+	}
+	output.WriteString(
+		fmt.Sprintf("%d rooms:", len(rooms)),
+	)
+	for i, room := range rooms {
+		output.WriteString(
+			fmt.Sprintf(
+				"\n\t%d. #%s\t(%d members: %s)",
+				i+1,
+				room.Name,
+				len(room.Members),
+				strings.Join(room.Members, ", "),
+			),
+		)
+	}
+	return output.String()
+}
+
+func formatHistory(msgs []*pb.ChatServerMsg) string {
+	var output bytes.Buffer
+	output.WriteString(time.Now().Format(tformat))
+	output.WriteString(" [info] ")
+	if len(msgs) == 0 {
+		output.WriteString("No messages in history")
+		return output.String()
+	}
+	output.WriteString(
+		fmt.Sprintf("Last %d messages:", len(msgs)),
+	)
+	for _, msg := range msgs {
+		output.WriteString("\n\t")
+		output.WriteString(formatMsg(msg))
+	}
+	return output.String()
+}
+
 func formatMsg(msg *pb.ChatServerMsg) string {
 	var output bytes.Buffer
 	switch msg.Msg.(type) {
 	case *pb.ChatServerMsg_PublicMsg:
 		pmsg := msg.GetPublicMsg()
+		output.WriteString(formatUnixTime(pmsg.TimeSent))
+		if msg.Room != "" {
+			output.WriteString(" [#" + msg.Room + "]")
+		}
 		output.WriteString(
 			fmt.Sprintf(
-				"%s [%s] %s",
-				formatUnixTime(pmsg.TimeSent),
+				" [%s] %s",
 				pmsg.GetFrom().Nick,
 				pmsg.Msg,
 			),
 		)
 	case *pb.ChatServerMsg_PrivateMsg:
 		pmsg := msg.GetPrivateMsg()
+		tag := "[private]"
+		if pmsg.Encrypted != nil {
+			tag = "[private, encrypted]"
+		}
 		output.WriteString(
 			fmt.Sprintf(
-				"%s [%s] [private] %s",
+				"%s [%s] %s %s",
 				formatUnixTime(pmsg.TimeSent),
 				pmsg.GetFrom().Nick,
+				tag,
 				pmsg.Msg,
 			),
 		)
@@ -86,14 +187,150 @@ func formatMsg(msg *pb.ChatServerMsg) string {
 			output.WriteString("logged-in. ")
 		case pb.UserEvent_LOGOUT:
 			output.WriteString("logged-out. ")
+		case pb.UserEvent_JOIN:
+			output.WriteString("joined #" + msg.Room + ". ")
+		case pb.UserEvent_LEAVE:
+			output.WriteString("left #" + msg.Room + ". ")
+		case pb.UserEvent_PRESENCE:
+			presence := formatPresence(uevent.GetUser().GetPresence())
+			if presence == "" {
+				presence = "online"
+			}
+			output.WriteString("changed presence to " + presence + ". ")
+		case pb.UserEvent_KICK:
+			output.WriteString("got kicked. ")
 		default:
 			output.WriteString("did somthing unknown. ")
 		}
 		output.WriteString("Last seen ")
 		output.WriteString(formatUnixTime(uevent.GetUser().TimeLastSeen))
+	case *pb.ChatServerMsg_Receipt:
+		receipt := msg.GetReceipt()
+		output.WriteString(
+			fmt.Sprintf(
+				"%s [info] Private message #%d to %s was ",
+				formatUnixTime(receipt.Time),
+				receipt.Id,
+				receipt.To,
+			),
+		)
+		switch receipt.Type {
+		case pb.Receipt_DELIVERED:
+			output.WriteString("delivered")
+		case pb.Receipt_READ:
+			output.WriteString("read")
+		default:
+			output.WriteString("handled")
+		}
+	case *pb.ChatServerMsg_Typing:
+		typing := msg.GetTyping()
+		output.WriteString(
+			fmt.Sprintf(
+				"%s [info] %s is typing",
+				formatUnixTime(typing.Time),
+				typing.From,
+			),
+		)
+		switch {
+		case msg.Room != "":
+			output.WriteString(" in #" + msg.Room)
+		case typing.To != "":
+			output.WriteString(" a private message")
+		}
+	case *pb.ChatServerMsg_ServerShutdown:
+		shutdown := msg.GetServerShutdown()
+		output.WriteString(time.Now().Format(tformat))
+		output.WriteString(" [info] Chat server is shutting down")
+		if shutdown.Reason != "" {
+			output.WriteString(": " + shutdown.Reason)
+		}
+		if shutdown.ReconnectAfter > 0 {
+			output.WriteString(
+				fmt.Sprintf(", reconnecting in %ds", shutdown.ReconnectAfter),
+			)
+		}
 	default:
 		output.WriteString("Unkown type of message received from chat server")
 	}
 
 	return output.String()
 }
+
+// formatPresence describes presence, it is empty for online users without
+// status text.
+func formatPresence(presence *pb.Presence) string {
+	if presence == nil {
+		return ""
+	}
+	var desc []string
+	if presence.Status != pb.Presence_ONLINE {
+		desc = append(desc, strings.ToLower(presence.Status.String()))
+	}
+	if presence.Idle {
+		desc = append(desc, "idle")
+	}
+	text := strings.Join(desc, ", ")
+	if presence.Text != "" {
+		if text != "" {
+			text += ": "
+		}
+		text += presence.Text
+	}
+	return text
+}
+
+func formatPresenceSuffix(presence *pb.Presence) string {
+	if desc := formatPresence(presence); desc != "" {
+		return " [" + desc + "]"
+	}
+	return ""
+}
+
+// formatRolePrefix marks admins with @ and moderators with %, like IRC.
+func formatRolePrefix(role pb.User_Role) string {
+	switch role {
+	case pb.User_ADMIN:
+		return "@"
+	case pb.User_MODERATOR:
+		return "%"
+	}
+	return ""
+}
+
+func formatBanList(bans []*pb.Ban) string {
+	var output bytes.Buffer
+	output.WriteString(time.Now().Format(tformat))
+	output.WriteString(" [info] ")
+	if len(bans) == 0 {
+		output.WriteString("No bans")
+		return output.String()
+	}
+	output.WriteString(
+		fmt.Sprintf("%d bans:", len(bans)),
+	)
+	for i, ban := range bans {
+		target := ban.Nick
+		if ban.Addr != "" {
+			target = "address " + ban.Addr
+		}
+		until := "permanent"
+		if ban.Expiry != 0 {
+			until = "until " + time.Unix(ban.Expiry, 0).Format("2006-01-02 15:04:05")
+		}
+		output.WriteString(
+			fmt.Sprintf("\n\t%d. %s by %s, %s", i+1, target, ban.By, until),
+		)
+		if ban.Reason != "" {
+			output.WriteString(": " + ban.Reason)
+		}
+	}
+	return output.String()
+}
+
+func formatPresenceSet(presence *pb.Presence) string {
+	desc := formatPresence(presence)
+	if desc == "" {
+		desc = "online"
+	}
+	return fmt.Sprintf("%s [info] Presence set to %s", time.Now().Format(tformat), desc)
+}
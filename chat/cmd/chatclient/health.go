@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const pingInterval = 5 * time.Second
+
+var health connHealth
+
+// connHealth is the state of the connection to the chat server, shown in
+// the input line title: when the listening stream last received anything,
+// the server sends a heartbeat every second, and the round-trip time of
+// the last ping.
+type connHealth struct {
+	mu         sync.Mutex
+	lastRecv   time.Time
+	rtt        time.Duration
+	pingFailed bool
+	lost       bool // Listening stream broken, reconnecting
+	stalled    bool // Warned about missing heartbeats
+}
+
+// monitorConnection pings the server every pingInterval and warns when
+// heartbeats have stopped arriving for stallTimeout.
+func monitorConnection() {
+	health.received()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	var lastPing time.Time
+	for now := range ticker.C {
+		health.checkStall(now)
+		if now.Sub(lastPing) >= pingInterval {
+			lastPing = now
+			// Keeps checking for stalls while waiting for the reply
+			go health.ping()
+		}
+		redrawTUI()
+	}
+}
+
+func (h *connHealth) received() {
+	h.mu.Lock()
+	resumed := h.stalled
+	h.lastRecv = time.Now()
+	h.lost, h.stalled = false, false
+	h.mu.Unlock()
+	if resumed {
+		notifyUI("Heartbeats from chat server resumed")
+	}
+}
+
+func (h *connHealth) disconnected() {
+	h.mu.Lock()
+	h.lost = true
+	h.mu.Unlock()
+	redrawTUI()
+}
+
+func (h *connHealth) checkStall(now time.Time) {
+	h.mu.Lock()
+	silence := now.Sub(h.lastRecv)
+	warn := !h.lost && !h.stalled && silence >= *stallTimeout
+	if warn {
+		h.stalled = true
+	}
+	h.mu.Unlock()
+	if warn {
+		notifyUI(fmt.Sprintf(
+			"No heartbeat from chat server for %s, the connection may be lost",
+			silence.Round(time.Second),
+		))
+	}
+}
+
+// ping measures the round-trip time to the server.
+func (h *connHealth) ping() (time.Duration, error) {
+	start := time.Now()
+	_, err := request(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Ping{
+			Ping: &pb.PingRequest{},
+		},
+	})
+	rtt := time.Since(start)
+	if status.Code(err) == codes.ResourceExhausted {
+		// Rate limited, says nothing about the connection
+		return 0, err
+	}
+
+	h.mu.Lock()
+	h.rtt, h.pingFailed = rtt, err != nil
+	h.mu.Unlock()
+	return rtt, err
+}
+
+func (h *connHealth) String() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch {
+	case h.lost:
+		return "reconnecting"
+	case h.stalled:
+		return "no heartbeat for " + time.Since(h.lastRecv).Round(time.Second).String()
+	case h.pingFailed:
+		return "ping failed"
+	case h.rtt > 0:
+		return formatRTT(h.rtt)
+	}
+	return ""
+}
+
+func printPing() {
+	rtt, err := health.ping()
+	if err != nil {
+		cui.ln("Unable to ping chat server:", err)
+		return
+	}
+	cui.ln(formatPing(rtt))
+}
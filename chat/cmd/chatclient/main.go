@@ -2,35 +2,67 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"golang.org/x/net/context"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpccreds "google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 
+	c "github.com/tormoder/chat/common"
 	pb "github.com/tormoder/chat/proto"
 )
 
-var serverAddr = flag.String("saddr", "127.0.0.1:10000", "The chat server address in the format of host:port")
+var (
+	serverAddr = flag.String("saddr", "127.0.0.1:10000", "The chat server address in the format of host:port")
+	nickFlag   = flag.String("nick", "", "login as `nick` instead of prompting for it (required with -batch)")
+	batchFile  = flag.String("batch", "", "run the commands in `file` (\"-\" for stdin) without the terminal UI, incoming messages are written to stdout as JSON lines")
+	receipts   = flag.Bool("receipts", false, "request delivered and read receipts for private messages")
+	register   = flag.Bool("register", false, "register the nick with a password before logging in")
+	passFile   = flag.String("password-file", "", "read the password for a registered nick from the first line of `file` instead of prompting")
+	keyFile    = flag.String("key-file", "", "keep the private key for encrypted private messages in `file`, created if missing (a new key every run if empty)")
+
+	stallTimeout = flag.Duration("stall-timeout", 5*time.Second, "warn when no heartbeat has arrived from the chat server for `duration`")
+
+	tlsCert = flag.String("tls-cert", "", "present the client certificate in `file`, its common name must match the nick")
+	tlsKey  = flag.String("tls-key", "", "private key `file` for -tls-cert")
+	tlsCA   = flag.String("tls-ca", "", "verify the server against the CA in `file` (system roots if empty)")
+	useTLS  = flag.Bool("tls", false, "connect using TLS (implied by the other -tls flags)")
+)
+
+const historyLimit = 20
 
 var (
 	cui         = ui{os.Stdout}
-	tocuiChan   = make(chan string, 2048)
+	tocuiChan   = make(chan uiMsg, 2048)
 	userService pb.UserServiceClient
 	chatService pb.ChatServiceClient
-	credentials *pb.Credentials
+	modService  pb.ModerationServiceClient
 )
 
 func main() {
 	flag.Parse()
 	setupSignalHandlers()
+	if *batchFile == "" {
+		cui.ln("---------------------------------")
+		cui.ln("\tSimple Chat Client")
+		cui.ln("---------------------------------")
+	}
 
-	cui.ln("---------------------------------")
-	cui.ln("\tSimple Chat Client")
-	cui.ln("---------------------------------")
-
-	nick := cui.promptForString("nick")
+	nick := *nickFlag
+	if *batchFile != "" {
+		if nick == "" {
+			fatal("The -batch flag requires -nick")
+		}
+		cui = ui{os.Stderr}
+	} else if nick == "" {
+		nick = cui.promptForString("nick")
+	}
 
 	cui.ln("Dialing chat server...")
 	err := dialServer()
@@ -38,12 +70,51 @@ func main() {
 		fatalWithErr("Dialing chat server failed:", err)
 	}
 
+	if *passFile != "" {
+		pw, err := readPasswordFile(*passFile)
+		if err != nil {
+			fatalWithErr("Reading password failed", err)
+		}
+		setPassword(pw)
+	}
+	if *register {
+		cui.ln("Registering nick...")
+		err = registerNick(nick)
+		if err != nil {
+			fatalWithErr("Registration failed", err)
+		}
+	}
+
+	err = loadKey(*keyFile)
+	if err != nil {
+		fatalWithErr("Loading key failed", err)
+	}
+
 	cui.ln("Attempting to login...")
-	credentials, err = attemptLogin(nick)
+	creds, err := login(nick)
 	if err != nil {
 		fatalWithErr("Login failed", err)
 	}
+	setCredentials(creds)
+	cui.ln("Your key fingerprint:", fingerprint(publicKey[:]))
 
+	if *batchFile != "" {
+		err = setupMsgListener()
+		if err != nil {
+			fatalWithErr("Message listener setup failed", err)
+		}
+		err = runBatch(*batchFile)
+		if err != nil {
+			fatalWithErr("Batch failed", err)
+		}
+		attemptLogout()
+		return
+	}
+
+	err = startTUI(nick)
+	if err != nil {
+		fatalWithErr("Unable to start terminal UI", err)
+	}
 	cui.f("Hello %s, login success!\n", nick)
 
 	cui.ln("Setting up message listener...")
@@ -51,18 +122,28 @@ func main() {
 	if err != nil {
 		fatalWithErr("Message listener setup failed", err)
 	}
-	cui.ln("Ready")
-	cui.ln()
-	cui.ln(getAvailableCmds())
-	cui.ln("")
+	refreshUserList()
+	cui.ln("Ready, type /help for available commands")
 
-	clientLoop()
+	err = runTUI()
+	if err != nil {
+		fatalWithErr("Terminal UI failed", err)
+	}
+	attemptLogout()
 }
 
 func dialServer() error {
+	transportOpt := grpc.WithInsecure()
+	if *useTLS || *tlsCert != "" || *tlsKey != "" || *tlsCA != "" {
+		tlsConfig, err := c.ClientTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			return err
+		}
+		transportOpt = grpc.WithTransportCredentials(grpccreds.NewTLS(tlsConfig))
+	}
 	clientConn, err := grpc.Dial(
 		*serverAddr,
-		grpc.WithInsecure(),
+		transportOpt,
 		grpc.WithBlock(),
 		grpc.WithTimeout(500*time.Millisecond),
 	)
@@ -71,11 +152,16 @@ func dialServer() error {
 	}
 	userService = pb.NewUserServiceClient(clientConn)
 	chatService = pb.NewChatServiceClient(clientConn)
+	modService = pb.NewModerationServiceClient(clientConn)
 	return nil
 }
 
 func attemptLogin(nick string) (*pb.Credentials, error) {
-	lreq := &pb.LoginRequest{Nick: nick}
+	lreq := &pb.LoginRequest{
+		Nick:      nick,
+		Password:  getPassword(),
+		PublicKey: publicKey[:],
+	}
 	creds, err := userService.Login(context.Background(), lreq)
 	if err != nil {
 		return nil, err
@@ -84,29 +170,115 @@ func attemptLogin(nick string) (*pb.Credentials, error) {
 }
 
 func setupMsgListener() error {
-	msgStream, err := chatService.ListenForMessages(context.Background(), credentials)
+	msgStream, err := openChat(false, 0)
 	if err != nil {
 		return err
 	}
-	go func() {
-		for {
-			msg, err := msgStream.Recv()
-			if err != nil {
-				fatal("chat server connection error")
-			}
-			if heartbeat(msg) {
+	go listenForMessages(msgStream)
+	go monitorConnection()
+
+	return nil
+}
+
+func listenForMessages(stream msgReceiver) {
+	var (
+		cursor, lastPrivateID uint64
+		reconnectDelay        = minReconnectDelay
+	)
+	for {
+		msg, err := stream.Recv()
+		if status.Code(err) == codes.PermissionDenied {
+			// Kicked or banned, logging in again will not help
+			fatalWithErr("Disconnected by chat server", err)
+		}
+		if err != nil && getCredentials() == nil {
+			// Account deleted
+			return
+		}
+		if err != nil {
+			health.disconnected()
+			notifyUI(fmt.Sprint("Connection to chat server lost, reconnecting... (", err, ")"))
+			stream, cursor = reconnect(cursor, reconnectDelay)
+			reconnectDelay = minReconnectDelay
+			continue
+		}
+		if shutdown := msg.GetServerShutdown(); shutdown != nil && shutdown.ReconnectAfter > 0 {
+			reconnectDelay = time.Duration(shutdown.ReconnectAfter) * time.Second
+		}
+		health.received()
+		if msg.Cursor != 0 {
+			cursor = msg.Cursor
+		}
+		if heartbeat(msg) {
+			continue
+		}
+		if uevent := msg.GetUserEvent(); uevent != nil {
+			users.event(uevent)
+			if uevent.Event == pb.UserEvent_PRESENCE && gui != nil {
+				// Shown in the user list
 				continue
 			}
-			select {
-			case tocuiChan <- formatMsg(msg):
-				// Send OK
-			default:
-				// UI queue full, drop message
+		}
+		if typing := msg.GetTyping(); typing != nil && gui != nil {
+			typists.add(typing.From, msg.Room, typing.To != "")
+			continue
+		}
+		if pmsg := msg.GetPrivateMsg(); pmsg != nil {
+			decryptPrivateMsg(pmsg, true)
+		}
+		umsg := uiMsg{text: formatMsg(msg), msg: msg}
+		if pmsg := msg.GetPrivateMsg(); pmsg != nil {
+			if pmsg.Id != 0 && pmsg.Id <= lastPrivateID {
+				// Sent again after a reconnect
+				continue
 			}
+			umsg.readID = pmsg.Id
+		}
+		if umsg.readID != 0 {
+			// Private messages are never dropped, they are acknowledged
+			// up to the last one shown
+			lastPrivateID = umsg.readID
+			tocuiChan <- umsg
+			continue
 		}
-	}()
+		select {
+		case tocuiChan <- umsg:
+		default:
+			// UI queue full, drop message
+		}
+	}
+}
 
-	return nil
+// privateMsgShown lets the private messages up to id leave the mailbox and
+// marks them read, once they have been shown.
+func privateMsgShown(id uint64) {
+	ackPrivateMsg(id)
+	markPrivateMsgRead(id)
+}
+
+// ackPrivateMsg and markPrivateMsgRead do not wait for the reply, so that
+// showing messages never waits for the server. The mailbox keeps the
+// messages if the acknowledgement is lost.
+func ackPrivateMsg(id uint64) {
+	err := post(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Ack{
+			Ack: &pb.AckRequest{UpTo: id},
+		},
+	})
+	if err != nil {
+		notifyUI(fmt.Sprint("Unable to acknowledge private message: ", err))
+	}
+}
+
+func markPrivateMsgRead(id uint64) {
+	err := post(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_MarkRead{
+			MarkRead: &pb.AckRequest{UpTo: id},
+		},
+	})
+	if err != nil {
+		notifyUI(fmt.Sprint("Unable to mark private message as read: ", err))
+	}
 }
 
 func heartbeat(msg *pb.ChatServerMsg) bool {
@@ -116,98 +288,216 @@ func heartbeat(msg *pb.ChatServerMsg) bool {
 	return false
 }
 
-func clientLoop() {
-	var (
-		userChoice      int
-		stopRefreshChan = make(chan bool)
-	)
-
-	for {
-		cui.ln(getAvailableCmdsShort())
-		go func() {
-			for {
-				select {
-				case <-time.After(300 * time.Millisecond):
-					msgsWasPrinted := pumpNewMsgToUI()
-					if msgsWasPrinted {
-						cui.ln(getAvailableCmdsShort())
-					}
-				case <-stopRefreshChan:
-					return
-				}
-			}
+// uiMsg is a formatted message waiting to be printed, msg is nil for
+// notices from the client itself. A non-zero readID is the id of a private
+// message to acknowledge once printed.
+type uiMsg struct {
+	text   string
+	msg    *pb.ChatServerMsg
+	readID uint64
+}
 
-		}()
-		cui.sln(&userChoice)
-		stopRefreshChan <- true
-
-		switch userChoice - 1 {
-		case listAllUsers:
-			printAllUsers()
-			pumpNewMsgToUI()
-		case sendPublicMessage:
-			sendPublicMsg()
-			pumpNewMsgToUI()
-		case sendPrivateMessage:
-			sendPrivateMsg()
-			pumpNewMsgToUI()
-		case logout:
-			attemptLogout()
-			os.Exit(0)
-		default:
-			pumpNewMsgToUI()
-		}
+func notifyUI(text string) {
+	select {
+	case tocuiChan <- uiMsg{text: time.Now().Format(tformat) + " [info] " + text}:
+	default:
 	}
 }
 
-func pumpNewMsgToUI() bool {
-	hasPrintedMsg := false
-	for {
-		select {
-		case msg := <-tocuiChan:
-			cui.ln(msg)
-			hasPrintedMsg = true
-		default:
-			return hasPrintedMsg
+// pumpMsgsToUI prints incoming messages and acknowledges private messages
+// once printed, once for every batch of queued messages.
+func pumpMsgsToUI() {
+	for msg := range tocuiChan {
+		cui.ln(msg.text)
+		lastReadID := msg.readID
+	drain:
+		for {
+			select {
+			case msg := <-tocuiChan:
+				cui.ln(msg.text)
+				if msg.readID > lastReadID {
+					lastReadID = msg.readID
+				}
+			default:
+				break drain
+			}
+		}
+		if lastReadID != 0 {
+			privateMsgShown(lastReadID)
 		}
 	}
 }
 
 func printAllUsers() {
-	luresp, err := userService.ListUsers(context.Background(), credentials)
+	luresp, err := userService.ListUsers(context.Background(), getCredentials())
 	if err != nil {
 		cui.ln("Unable to list users:", err)
 		return
 	}
+	users.set(luresp.Users)
 	cui.ln(formatUserList(luresp.Users))
 }
 
-func sendPublicMsg() {
-	pmsg := cui.promptForString("public message")
-	msg := new(pb.PublicMsgRequest)
-	msg.Msg = pmsg
-	msg.Creds = credentials
-	_, err := chatService.SendPublic(context.Background(), msg)
+func refreshUserList() {
+	luresp, err := userService.ListUsers(context.Background(), getCredentials())
 	if err != nil {
-		cui.ln("Error sending message:", err)
+		notifyUI(fmt.Sprint("Unable to list users: ", err))
+		return
 	}
+	users.set(luresp.Users)
 }
 
-func sendPrivateMsg() {
-	rnick := cui.promptForString("nick of message receiver")
-	pmsg := cui.promptForString("private message")
+func printHistory() {
+	hreq := &pb.HistoryRequest{
+		Creds: getCredentials(),
+		Limit: historyLimit,
+	}
+	hresp, err := chatService.GetHistory(context.Background(), hreq)
+	if err != nil {
+		cui.ln("Unable to get message history:", err)
+		return
+	}
+	for _, msg := range hresp.Msgs {
+		if pmsg := msg.GetPrivateMsg(); pmsg != nil {
+			decryptPrivateMsg(pmsg, false)
+		}
+	}
+	cui.ln(formatHistory(hresp.Msgs))
+}
+
+func sendPublicMsg(pmsg string) {
+	msg := new(pb.PublicMsgRequest)
+	msg.Msg = pmsg
+	sendMsg(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Public{Public: msg},
+	})
+}
+
+func sendPrivateMsg(rnick, pmsg string) {
 	msg := new(pb.PrivateMsgRequest)
 	msg.To = rnick
-	msg.Msg = pmsg
-	msg.Creds = credentials
-	_, err := chatService.SendPrivate(context.Background(), msg)
+	msg.WantReceipts = *receipts
+	key, err := fetchKey(rnick)
+	switch {
+	case status.Code(err) == codes.FailedPrecondition:
+		// Users of other clients, like IRC, may have no key
+		cui.f("Sending unencrypted, %s has not published a key\n", rnick)
+		msg.Msg = pmsg
+	case err != nil:
+		cui.ln("Unable to encrypt message:", err)
+		return
+	default:
+		msg.Encrypted, err = encrypt(pmsg, key)
+		if err != nil {
+			cui.ln("Unable to encrypt message:", err)
+			return
+		}
+	}
+	sendMsg(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Private{Private: msg},
+	})
+}
+
+func sendMsg(req *pb.ChatClientMsg) {
+	reply, err := request(req)
 	if err != nil {
 		cui.ln("Error sending message:", err)
+		return
+	}
+	printSendResult(reply.Sent)
+}
+
+func printSendResult(resp *pb.SendMsgResponse) {
+	if resp.Status == pb.SendMsgResponse_DELIVERED && resp.Dropped == 0 && resp.Id == 0 {
+		return
+	}
+	cui.ln(formatSendResult(resp))
+}
+
+func printStats() {
+	sresp, err := chatService.GetStats(context.Background(), getCredentials())
+	if err != nil {
+		cui.ln("Unable to get stats:", err)
+		return
 	}
+	cui.ln(formatStats(sresp))
+}
+
+func printAllRooms() {
+	lrresp, err := chatService.ListRooms(context.Background(), getCredentials())
+	if err != nil {
+		cui.ln("Unable to list rooms:", err)
+		return
+	}
+	cui.ln(formatRoomList(lrresp.Rooms))
+}
+
+// roomAction creates, joins or leaves room.
+func roomAction(action string, room string) {
+	rreq := &pb.RoomRequest{Room: room}
+	req := new(pb.ChatClientMsg)
+	switch action {
+	case "create":
+		req.Msg = &pb.ChatClientMsg_CreateRoom{CreateRoom: rreq}
+	case "join":
+		req.Msg = &pb.ChatClientMsg_JoinRoom{JoinRoom: rreq}
+	case "leave":
+		req.Msg = &pb.ChatClientMsg_LeaveRoom{LeaveRoom: rreq}
+	}
+	_, err := request(req)
+	if err != nil {
+		cui.f("Unable to %s room: %v\n", action, err)
+	}
+}
+
+func sendRoomMsg(room, rmsg string) {
+	msg := new(pb.RoomMsgRequest)
+	msg.Room = room
+	msg.Msg = rmsg
+	sendMsg(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Room{Room: msg},
+	})
+}
+
+func setPresence(status, text string) {
+	st, found := pb.Presence_Status_value[strings.ToUpper(status)]
+	if !found || pb.Presence_Status(st) == pb.Presence_OFFLINE {
+		cui.ln("Unknown presence status", status)
+		return
+	}
+	preq := &pb.PresenceRequest{
+		Status: pb.Presence_Status(st),
+		Text:   text,
+	}
+	reply, err := request(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Presence{Presence: preq},
+	})
+	if err != nil {
+		cui.ln("Unable to set presence:", err)
+		return
+	}
+	cui.ln(formatPresenceSet(reply.Presence))
+}
+
+// sendTyping tells the recipients of a message that is being typed, to
+// the user to if set, the room if set and everyone otherwise.
+func sendTyping(to, room string) {
+	treq := &pb.TypingRequest{
+		To:   to,
+		Room: room,
+	}
+	// Best effort, the message itself reports any problem
+	post(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Typing{Typing: treq},
+	})
 }
 
 func attemptLogout() {
-	_, err := userService.Logout(context.Background(), credentials)
+	if getCredentials() == nil {
+		// Account deleted
+		return
+	}
+	_, err := userService.Logout(context.Background(), getCredentials())
 	if err != nil {
 		fatalWithErr("Error on logout", err)
 	}
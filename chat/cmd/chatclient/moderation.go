@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+func kickUser(nick, reason string) {
+	mreq := &pb.ModerationRequest{
+		Creds:  getCredentials(),
+		Nick:   nick,
+		Reason: reason,
+	}
+	_, err := modService.Kick(context.Background(), mreq)
+	if err != nil {
+		cui.ln("Unable to kick user:", err)
+	}
+}
+
+// banRequest returns a request banning target, an IP address or a nick.
+func banRequest(target string) *pb.BanRequest {
+	breq := &pb.BanRequest{Creds: getCredentials()}
+	if net.ParseIP(target) != nil {
+		breq.Addr = target
+	} else {
+		breq.Nick = target
+	}
+	return breq
+}
+
+func banTarget(target, duration, reason string) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		cui.ln("Invalid duration:", err)
+		return
+	}
+	breq := banRequest(target)
+	breq.Reason = reason
+	breq.Duration = int64(d / time.Second)
+	_, err = modService.Ban(context.Background(), breq)
+	if err != nil {
+		cui.ln("Unable to ban:", err)
+	}
+}
+
+func unbanTarget(target string) {
+	_, err := modService.Unban(context.Background(), banRequest(target))
+	if err != nil {
+		cui.ln("Unable to unban:", err)
+	}
+}
+
+func printBans() {
+	lbresp, err := modService.ListBans(context.Background(), getCredentials())
+	if err != nil {
+		cui.ln("Unable to list bans:", err)
+		return
+	}
+	cui.ln(formatBanList(lbresp.Bans))
+}
+
+func muteUser(nick, duration string) {
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		cui.ln("Invalid duration:", err)
+		return
+	}
+	mreq := &pb.ModerationRequest{
+		Creds:    getCredentials(),
+		Nick:     nick,
+		Duration: int64(d / time.Second),
+	}
+	_, err = modService.Mute(context.Background(), mreq)
+	if err != nil {
+		cui.ln("Unable to mute user:", err)
+	}
+}
+
+func unmuteUser(nick string) {
+	mreq := &pb.ModerationRequest{
+		Creds: getCredentials(),
+		Nick:  nick,
+	}
+	_, err := modService.Unmute(context.Background(), mreq)
+	if err != nil {
+		cui.ln("Unable to unmute user:", err)
+	}
+}
+
+func setRole(nick, role string) {
+	r, found := pb.User_Role_value[strings.ToUpper(role)]
+	if !found {
+		cui.ln("Unknown role", role)
+		return
+	}
+	rreq := &pb.RoleRequest{
+		Creds: getCredentials(),
+		Nick:  nick,
+		Role:  pb.User_Role(r),
+	}
+	_, err := modService.SetRole(context.Background(), rreq)
+	if err != nil {
+		cui.ln("Unable to set role:", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const (
+	minReconnectDelay = 250 * time.Millisecond
+	maxReconnectDelay = 30 * time.Second
+)
+
+var (
+	credentials   *pb.Credentials
+	password      string     // Empty for guests
+	credentialsMu sync.Mutex // Protects credentials and password, replaced on re-login
+)
+
+func getCredentials() *pb.Credentials {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	return credentials
+}
+
+func setCredentials(creds *pb.Credentials) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	credentials = creds
+}
+
+func getPassword() string {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	return password
+}
+
+func setPassword(pw string) {
+	credentialsMu.Lock()
+	defer credentialsMu.Unlock()
+	password = pw
+}
+
+type msgReceiver interface {
+	Recv() (*pb.ChatServerMsg, error)
+}
+
+// primedReceiver returns an already received message before reading from
+// the underlying stream.
+type primedReceiver struct {
+	msgReceiver
+	first *pb.ChatServerMsg
+}
+
+func (r *primedReceiver) Recv() (*pb.ChatServerMsg, error) {
+	if r.first != nil {
+		msg := r.first
+		r.first = nil
+		return msg, nil
+	}
+	return r.msgReceiver.Recv()
+}
+
+// reconnect retries with exponential backoff, starting after delay, until
+// the listening session is resumed from cursor, or, if the server no longer
+// has the session, until logged in again with the same nick. It returns the
+// new stream and the cursor to continue from.
+func reconnect(cursor uint64, delay time.Duration) (msgReceiver, uint64) {
+	for {
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+
+		stream, err := resumeListening(cursor)
+		if err == nil {
+			notifyUI("Reconnected to chat server")
+			return stream, cursor
+		}
+		if retryable(err) {
+			delay = retryDelay(err, delay)
+			continue
+		}
+
+		stream, err = relogin()
+		if err == nil {
+			notifyUI("Session expired, logged in again. Messages may have been missed.")
+			refreshUserList()
+			return stream, 0
+		}
+		if retryable(err) {
+			delay = retryDelay(err, delay)
+			continue
+		}
+		fatalWithErr("Unable to log in again", err)
+	}
+}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// retryDelay returns how long to wait before retrying after err, at least
+// delay or as long as the server asked for when rate limited.
+func retryDelay(err error, delay time.Duration) time.Duration {
+	for _, detail := range status.Convert(err).Details() {
+		retry, ok := detail.(*errdetails.RetryInfo)
+		if !ok {
+			continue
+		}
+		if d, err := ptypes.Duration(retry.RetryDelay); err == nil && d > delay {
+			return d
+		}
+	}
+	return delay
+}
+
+func resumeListening(cursor uint64) (msgReceiver, error) {
+	return openChat(true, cursor)
+}
+
+func relogin() (msgReceiver, error) {
+	creds, err := attemptLogin(getCredentials().Nick)
+	if err != nil {
+		return nil, err
+	}
+	setCredentials(creds)
+	return openChat(false, 0)
+}
+
+// primeStream waits for the first message so that errors from the server
+// are reported here rather than on a later Recv.
+func primeStream(stream msgReceiver) (msgReceiver, error) {
+	msg, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return &primedReceiver{stream, msg}, nil
+}
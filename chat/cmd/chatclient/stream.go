@@ -0,0 +1,185 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Requests on the chat stream fail if not answered within requestTimeout.
+const requestTimeout = 10 * time.Second
+
+var errConnLost = status.Error(codes.Unavailable, "connection to chat server lost")
+
+var (
+	conn   *chatConn
+	connMu sync.Mutex // Protects conn, replaced on reconnect
+)
+
+func currentConn() *chatConn {
+	connMu.Lock()
+	defer connMu.Unlock()
+	return conn
+}
+
+func setConn(cc *chatConn) {
+	connMu.Lock()
+	defer connMu.Unlock()
+	conn = cc
+}
+
+// chatConn is a Chat stream. Requests sent on it are answered by replies,
+// which Recv hands to the waiting request instead of returning them.
+type chatConn struct {
+	stream pb.ChatService_ChatClient
+	sendMu sync.Mutex // Serializes sending
+
+	mu      sync.Mutex // Protects the fields below
+	nextID  uint64
+	pending map[uint64]chan *pb.Reply
+	err     error // Set once receiving failed
+}
+
+// openChat opens a Chat stream with the current credentials, resuming the
+// listening session from cursor if resume is set, and makes it the stream
+// requests are sent on.
+func openChat(resume bool, cursor uint64) (msgReceiver, error) {
+	stream, err := chatService.Chat(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	err = stream.Send(&pb.ChatClientMsg{
+		Msg: &pb.ChatClientMsg_Open{
+			Open: &pb.ChatOpen{
+				Creds:  getCredentials(),
+				Resume: resume,
+				Cursor: cursor,
+			},
+		},
+	})
+	if err != nil && err != io.EOF {
+		// On io.EOF the server's error is returned by Recv
+		return nil, err
+	}
+	cc := &chatConn{
+		stream:  stream,
+		pending: make(map[uint64]chan *pb.Reply),
+	}
+	primed, err := primeStream(cc)
+	if err != nil {
+		return nil, err
+	}
+	setConn(cc)
+	return primed, nil
+}
+
+func (cc *chatConn) Recv() (*pb.ChatServerMsg, error) {
+	for {
+		msg, err := cc.stream.Recv()
+		if err != nil {
+			cc.fail(err)
+			return nil, err
+		}
+		reply := msg.GetReply()
+		if reply == nil {
+			return msg, nil
+		}
+		cc.mu.Lock()
+		ch := cc.pending[reply.Id]
+		delete(cc.pending, reply.Id)
+		cc.mu.Unlock()
+		if ch != nil {
+			ch <- reply
+		}
+	}
+}
+
+// fail fails the pending requests.
+func (cc *chatConn) fail(err error) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.err != nil {
+		return
+	}
+	cc.err = err
+	for id, ch := range cc.pending {
+		close(ch)
+		delete(cc.pending, id)
+	}
+}
+
+// send sends req with a fresh id, the reply is handed to ch if not nil.
+func (cc *chatConn) send(req *pb.ChatClientMsg, ch chan *pb.Reply) error {
+	cc.mu.Lock()
+	if cc.err != nil {
+		cc.mu.Unlock()
+		return errConnLost
+	}
+	cc.nextID++
+	req.Id = cc.nextID
+	if ch != nil {
+		cc.pending[req.Id] = ch
+	}
+	cc.mu.Unlock()
+
+	cc.sendMu.Lock()
+	err := cc.stream.Send(req)
+	cc.sendMu.Unlock()
+	if err != nil {
+		cc.forget(req.Id)
+		return errConnLost
+	}
+	return nil
+}
+
+func (cc *chatConn) forget(id uint64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	delete(cc.pending, id)
+}
+
+// request sends req on the chat stream and waits for the reply. A failed
+// request returns a gRPC status error, like the unary RPCs.
+func request(req *pb.ChatClientMsg) (*pb.Reply, error) {
+	cc := currentConn()
+	if cc == nil {
+		return nil, errConnLost
+	}
+	ch := make(chan *pb.Reply, 1)
+	if err := cc.send(req, ch); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(requestTimeout)
+	defer timeout.Stop()
+	select {
+	case reply, ok := <-ch:
+		if !ok {
+			return nil, errConnLost
+		}
+		if reply.Code != 0 {
+			return reply, status.Error(codes.Code(reply.Code), reply.Error)
+		}
+		return reply, nil
+	case <-timeout.C:
+		cc.forget(req.Id)
+		return nil, status.Error(codes.DeadlineExceeded, "no reply from chat server")
+	}
+}
+
+// post sends req on the chat stream without waiting for the reply, which is
+// dropped. Only failing to send is reported, so it is for requests the
+// caller need not know the outcome of.
+func post(req *pb.ChatClientMsg) error {
+	cc := currentConn()
+	if cc == nil {
+		return errConnLost
+	}
+	return cc.send(req, nil)
+}
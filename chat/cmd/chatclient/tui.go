@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jroimartin/gocui"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const (
+	msgView   = "messages"
+	usersView = "users"
+	inputView = "input"
+
+	sidebarWidth = 20
+	inputHeight  = 3
+
+	// Typing is sent at most once per typingInterval while typing a
+	// message, and shown for typingTimeout after it was last received.
+	typingInterval = 3 * time.Second
+	typingTimeout  = 5 * time.Second
+)
+
+var (
+	gui      *gocui.Gui
+	guiClose sync.Once
+	users    = userList{nicks: make(map[string]*pb.User)}
+	typists  = typingList{typing: make(map[string]time.Time)}
+	typed    typingSender
+)
+
+// startTUI takes over the terminal and directs all client output to the
+// message pane. Call runTUI to start handling input.
+func startTUI(nick string) error {
+	g, err := gocui.NewGui(gocui.OutputNormal)
+	if err != nil {
+		return err
+	}
+	g.Cursor = true
+	g.SetManagerFunc(func(g *gocui.Gui) error {
+		return layout(g, nick)
+	})
+
+	bindings := []struct {
+		view    string
+		key     interface{}
+		handler func(*gocui.Gui, *gocui.View) error
+	}{
+		{"", gocui.KeyCtrlC, quit},
+		{"", gocui.KeyPgup, scrollMsgs(-1)},
+		{"", gocui.KeyPgdn, scrollMsgs(1)},
+		{inputView, gocui.KeyEnter, submitInput},
+	}
+	for _, b := range bindings {
+		if err := g.SetKeybinding(b.view, b.key, gocui.ModNone, b.handler); err != nil {
+			g.Close()
+			return err
+		}
+	}
+
+	gui = g
+	cui = ui{&paneWriter{g: g, view: msgView}}
+	return nil
+}
+
+// runTUI handles input until the user quits.
+func runTUI() error {
+	defer closeTUI()
+	go pumpMsgsToUI()
+	err := gui.MainLoop()
+	if err == gocui.ErrQuit {
+		return nil
+	}
+	return err
+}
+
+// closeTUI restores the terminal, it is safe to call before the TUI is
+// started and more than once.
+func closeTUI() {
+	if gui == nil {
+		return
+	}
+	guiClose.Do(func() {
+		gui.Close()
+		cui = ui{os.Stdout}
+	})
+}
+
+func redrawTUI() {
+	if gui != nil {
+		gui.Update(func(*gocui.Gui) error { return nil })
+	}
+}
+
+func layout(g *gocui.Gui, nick string) error {
+	maxX, maxY := g.Size()
+
+	v, err := g.SetView(msgView, 0, 0, maxX-sidebarWidth-1, maxY-inputHeight-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Messages (PgUp/PgDn to scroll)"
+		v.Wrap = true
+		v.Autoscroll = true
+	}
+
+	v, err = g.SetView(usersView, maxX-sidebarWidth, 0, maxX-1, maxY-inputHeight-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = "Users"
+	}
+	v.Clear()
+	for _, unick := range users.sorted() {
+		user := users.user(unick)
+		fmt.Fprintln(v, formatRolePrefix(user.GetRole())+unick+formatPresenceSuffix(user.GetPresence()))
+	}
+
+	v, err = g.SetView(inputView, 0, maxY-inputHeight, maxX-1, maxY-1)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Editable = true
+		v.Editor = gocui.EditorFunc(inputEditor)
+		if _, err := g.SetCurrentView(inputView); err != nil {
+			return err
+		}
+	}
+	v.Title = nick
+	if status := health.String(); status != "" {
+		v.Title += " [" + status + "]"
+	}
+	if typing := typists.String(); typing != "" {
+		v.Title += " - " + typing
+	}
+
+	return nil
+}
+
+// inputEditor is the default editor, also telling the recipients of the
+// message being typed.
+func inputEditor(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	gocui.DefaultEditor.Edit(v, key, ch, mod)
+	if ch != 0 || key == gocui.KeySpace {
+		typed.typing(v.Buffer())
+	}
+}
+
+func quit(*gocui.Gui, *gocui.View) error {
+	return gocui.ErrQuit
+}
+
+func submitInput(g *gocui.Gui, v *gocui.View) error {
+	line := v.Buffer()
+	typed.reset()
+	v.Clear()
+	v.SetCursor(0, 0)
+	v.SetOrigin(0, 0)
+	// Commands call the chat server, keep them off the UI goroutine
+	go func() {
+		if runCmd(line) == errQuit {
+			g.Update(func(*gocui.Gui) error { return gocui.ErrQuit })
+		}
+	}()
+	return nil
+}
+
+// scrollMsgs returns a handler scrolling the message pane by a page in
+// direction dir. Autoscroll is resumed when scrolled to the bottom.
+func scrollMsgs(dir int) func(*gocui.Gui, *gocui.View) error {
+	return func(g *gocui.Gui, _ *gocui.View) error {
+		v, err := g.View(msgView)
+		if err != nil {
+			return err
+		}
+		_, height := v.Size()
+		lines := len(v.ViewBufferLines())
+		_, oy := v.Origin()
+		if v.Autoscroll {
+			oy = lines - height
+		}
+
+		oy += dir * height
+		if oy >= lines-height {
+			v.Autoscroll = true
+			return nil
+		}
+		if oy < 0 {
+			oy = 0
+		}
+		v.Autoscroll = false
+		return v.SetOrigin(0, oy)
+	}
+}
+
+// paneWriter writes to a view from any goroutine. Writes are buffered and
+// copied to the view by the UI goroutine, in order.
+type paneWriter struct {
+	g       *gocui.Gui
+	view    string
+	mu      sync.Mutex
+	pending bytes.Buffer
+}
+
+func (w *paneWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	w.pending.Write(p)
+	w.mu.Unlock()
+	w.g.Update(w.flush)
+	return len(p), nil
+}
+
+func (w *paneWriter) flush(g *gocui.Gui) error {
+	v, err := g.View(w.view)
+	if err != nil {
+		// Not laid out yet, flushed by a later update
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.pending.WriteTo(v)
+	return err
+}
+
+// userList is the set of online users shown in the sidebar.
+type userList struct {
+	mu    sync.Mutex
+	nicks map[string]*pb.User
+}
+
+func (l *userList) set(ulist []*pb.User) {
+	l.mu.Lock()
+	l.nicks = make(map[string]*pb.User)
+	for _, user := range ulist {
+		l.nicks[user.Nick] = user
+	}
+	l.mu.Unlock()
+	redrawTUI()
+}
+
+func (l *userList) event(uevent *pb.UserEvent) {
+	user := uevent.GetUser()
+	l.mu.Lock()
+	switch uevent.Event {
+	case pb.UserEvent_LOGIN:
+		l.nicks[user.GetNick()] = user
+	case pb.UserEvent_LOGOUT, pb.UserEvent_KICK:
+		delete(l.nicks, user.GetNick())
+	case pb.UserEvent_PRESENCE:
+		if user.GetPresence().GetStatus() == pb.Presence_OFFLINE {
+			delete(l.nicks, user.GetNick())
+		} else {
+			l.nicks[user.GetNick()] = user
+		}
+	}
+	l.mu.Unlock()
+	redrawTUI()
+}
+
+func (l *userList) user(nick string) *pb.User {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.nicks[nick]
+}
+
+func (l *userList) sorted() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	nicks := make([]string, 0, len(l.nicks))
+	for nick := range l.nicks {
+		nicks = append(nicks, nick)
+	}
+	sort.Strings(nicks)
+	return nicks
+}
+
+// typingList is who is currently typing, shown in the input line title.
+type typingList struct {
+	mu     sync.Mutex
+	typing map[string]time.Time // Description to time last received
+}
+
+func (l *typingList) add(nick, room string, private bool) {
+	desc := nick
+	switch {
+	case room != "":
+		desc += " in #" + room
+	case private:
+		desc += " to you"
+	}
+	l.mu.Lock()
+	l.typing[desc] = time.Now()
+	l.mu.Unlock()
+	redrawTUI()
+	time.AfterFunc(typingTimeout, redrawTUI)
+}
+
+func (l *typingList) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var typing []string
+	for desc, t := range l.typing {
+		if time.Since(t) >= typingTimeout {
+			delete(l.typing, desc)
+			continue
+		}
+		typing = append(typing, desc)
+	}
+	if len(typing) == 0 {
+		return ""
+	}
+	sort.Strings(typing)
+	return strings.Join(typing, ", ") + " typing..."
+}
+
+// typingSender sends typing notifications for the input line, only called
+// from the UI goroutine.
+type typingSender struct {
+	target string
+	sent   time.Time
+}
+
+func (t *typingSender) typing(line string) {
+	var to, room, text string
+	cmd, rest := cutWord(line)
+	switch {
+	case !strings.HasPrefix(cmd, "/") || strings.HasPrefix(cmd, "//"):
+		text = line
+	case cmd == "/say":
+		text = rest
+	case cmd == "/msg":
+		to, text = cutWord(rest)
+	case cmd == "/room":
+		room, text = cutWord(rest)
+	}
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+
+	target := to + "/" + room
+	if target == t.target && time.Since(t.sent) < typingInterval {
+		return
+	}
+	t.target, t.sent = target, time.Now()
+	go sendTyping(to, room)
+}
+
+func (t *typingSender) reset() {
+	t.target, t.sent = "", time.Time{}
+}
+
+func cutWord(s string) (word, rest string) {
+	s = strings.TrimLeft(s, " ")
+	i := strings.Index(s, " ")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], strings.TrimLeft(s[i+1:], " ")
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+
+	"golang.org/x/term"
 )
 
 var scanner = bufio.NewScanner(os.Stdin)
@@ -21,17 +23,28 @@ func (ui *ui) ln(a ...interface{}) (int, error) {
 	return fmt.Fprintln(ui.w, a...)
 }
 
-func (ui *ui) sln(a ...interface{}) (int, error) {
-	return fmt.Scanln(a...)
-}
-
 func (ui *ui) promptForString(stringName string) string {
 	ui.f("Enter %s:\n", stringName)
 	scanner.Scan()
 	input := scanner.Text()
 	if err := scanner.Err(); err != nil {
-		ui.f("Error reading %s:\n", stringName, err)
+		ui.f("Error reading %s: %v\n", stringName, err)
 		return ui.promptForString(stringName)
 	}
 	return input
 }
+
+// promptForPassword reads a line without echoing it if stdin is a terminal.
+func (ui *ui) promptForPassword(name string) string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return ui.promptForString(name)
+	}
+	ui.f("Enter %s:\n", name)
+	input, err := term.ReadPassword(fd)
+	if err != nil {
+		ui.f("Error reading %s: %v\n", name, err)
+		return ui.promptForPassword(name)
+	}
+	return string(input)
+}
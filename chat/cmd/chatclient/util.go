@@ -13,6 +13,7 @@ func setupSignalHandlers() {
 		for {
 			select {
 			case signal := <-signalChan:
+				closeTUI()
 				cui.ln("Received", signal, "- exiting...")
 				os.Exit(0)
 			}
@@ -21,6 +22,7 @@ func setupSignalHandlers() {
 }
 
 func fatalWithErr(desc string, err error) {
+	closeTUI()
 	cui.ln(desc)
 	cui.ln("Reason:", err)
 	cui.ln("Bye...")
@@ -29,6 +31,7 @@ func fatalWithErr(desc string, err error) {
 }
 
 func fatal(desc string) {
+	closeTUI()
 	cui.ln(desc)
 	cui.ln("Bye...")
 	cui.ln("")
@@ -1,66 +1,323 @@
 package main
 
 import (
+	"crypto/tls"
 	"flag"
 	"fmt"
-	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/tormoder/chat/backplane"
 	"github.com/tormoder/chat/chat"
 	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/gateway"
+	"github.com/tormoder/chat/irc"
+	"github.com/tormoder/chat/logging"
+	"github.com/tormoder/chat/metrics"
+	"github.com/tormoder/chat/moderation"
 	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/ratelimit"
 	"github.com/tormoder/chat/storage"
 	"github.com/tormoder/chat/user"
 
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
 )
 
 var (
 	port    = flag.Int("port", 10000, "The chat server `port`")
-	verbose = flag.Bool("v", false, "show verbose debugging output")
+	dataDir = flag.String("datadir", "", "persist users and messages in `dir` (in-memory only if empty)")
+	verbose = flag.Bool("v", false, "show verbose debugging output (same as -log-level debug)")
+
+	logLevel  = flag.String("log-level", "info", "log entries at `level` and above: debug, info, warn or error")
+	logFormat = flag.String("log-format", "logfmt", "log in `format`: logfmt or json")
+
+	admins = flag.String("admins", "", "give the comma separated registered `nicks` the admin role")
+	guests = flag.Bool("guests", true, "let unregistered nicks log in without a password")
+
+	metricsAddr = flag.String("metrics-addr", "", "serve metrics over HTTP at /metrics on `address`, e.g. :9100 (disabled if empty)")
+	httpAddr    = flag.String("http", "", "serve the user and chat services as JSON over HTTP on `address`, e.g. :8080 (disabled if empty)")
+	httpOrigin  = flag.String("http-origin", "", "let web pages from `origin` call the -http services, e.g. https://chat.example.com or * for any (same origin only if empty)")
+	ircAddr     = flag.String("irc", "", "serve IRC clients on `address`, e.g. :6667 (disabled if empty)")
+
+	shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "on SIGTERM or interrupt, wait at most `duration` for in-flight requests before stopping")
+	reconnectAfter  = flag.Duration("reconnect-after", 0, "on shutdown, tell clients to reconnect after `duration` (no hint if zero)")
+
+	sessionTimeout = flag.Duration("session-timeout", chat.DefaultSessionTimeout, "log out sessions that have not been listening for messages for `duration`")
+	keepaliveTime  = flag.Duration("keepalive", 30*time.Second, "ping clients after `duration` without activity and disconnect those not answering")
+
+	backplaneAddr   = flag.String("backplane", "", "exchange messages with the other servers of a cluster through the Redis compatible pub/sub server at `address`")
+	backplaneListen = flag.String("backplane-listen", "", "serve an embedded pub/sub broker for the cluster on `address`, also used by this server unless -backplane is set")
+
+	userRate  = flag.Float64("user-rate", 5, "limit chat requests to `n` per second per user, 0 for no limit")
+	userBurst = flag.Int("user-burst", 20, "allow bursts of `n` chat requests per user")
+	peerRate  = flag.Float64("peer-rate", 20, "limit chat requests to `n` per second per client address, 0 for no limit")
+	peerBurst = flag.Int("peer-burst", 50, "allow bursts of `n` chat requests per client address")
+
+	tlsCert = flag.String("tls-cert", "", "serve TLS using the certificate in `file`")
+	tlsKey  = flag.String("tls-key", "", "private key `file` for -tls-cert")
+	tlsCA   = flag.String("tls-ca", "", "require client certificates signed by the CA in `file`; the certificate common name must match the nick")
 )
 
+// How long to wait for a client to answer a keepalive ping.
+const keepaliveTimeout = 10 * time.Second
+
+var logger *logging.Logger
+
 func main() {
 	flag.Parse()
-	log.SetPrefix("[chatserver] ")
-	if *verbose {
-		c.SetVerbose()
-	}
+	logger = newLogger()
 
 	listener, err := net.Listen(
 		"tcp",
 		fmt.Sprintf(":%d", *port),
 	)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		logger.Fatal("failed to listen", "err", err)
+	}
+
+	if *sessionTimeout <= 0 || *keepaliveTime <= 0 {
+		logger.Fatal("-session-timeout and -keepalive must be positive")
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    *keepaliveTime,
+			Timeout: keepaliveTimeout,
+		}),
+	}
+	var tlsConfig *tls.Config
+	if *tlsCert != "" || *tlsKey != "" {
+		tlsConfig, err = c.ServerTLSConfig(*tlsCert, *tlsKey, *tlsCA)
+		if err != nil {
+			logger.Fatal("failed to set up TLS", "err", err)
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	} else if *tlsCA != "" {
+		logger.Fatal("-tls-ca requires -tls-cert and -tls-key")
+	}
+
+	logger.Debug("setting up storage, chat and user service")
+	var (
+		userStorage    storage.UserStorage
+		msgStorage     storage.MessageStorage
+		mailboxStorage storage.MailboxStorage
+	)
+	if *dataDir == "" {
+		userStorage = storage.NewInMemoryUserStorage()
+		msgStorage = storage.NewInMemoryMessageStorage()
+		mailboxStorage = storage.NewInMemoryMailboxStorage()
+	} else {
+		err = os.MkdirAll(*dataDir, 0700)
+		if err != nil {
+			logger.Fatal("failed to create data directory", "err", err)
+		}
+		userStorage, err = storage.NewFileUserStorage(*dataDir)
+		if err != nil {
+			logger.Fatal("failed to open user storage", "err", err)
+		}
+		msgStorage, err = storage.NewFileMessageStorage(*dataDir)
+		if err != nil {
+			logger.Fatal("failed to open message storage", "err", err)
+		}
+		mailboxStorage, err = storage.NewFileMailboxStorage(*dataDir)
+		if err != nil {
+			logger.Fatal("failed to open mailbox storage", "err", err)
+		}
+	}
+	for _, nick := range strings.Split(*admins, ",") {
+		if nick = strings.TrimSpace(nick); nick == "" {
+			continue
+		}
+		if err = moderation.GrantAdmin(userStorage, nick); err != nil {
+			logger.Fatal("failed to grant admin role", "nick", nick, "err", err)
+		}
+	}
+	bp := newBackplane()
+	chatService := chat.NewService(userStorage, msgStorage, mailboxStorage, bp, *sessionTimeout, logger)
+	userService := user.NewService(chatService, userStorage, *guests, logger)
+	modService := moderation.NewService(chatService, userStorage, logger)
+
+	limiter := ratelimit.NewInterceptor(
+		ratelimit.Config{
+			UserRate:  *userRate,
+			UserBurst: *userBurst,
+			PeerRate:  *peerRate,
+			PeerBurst: *peerBurst,
+		},
+		userStorage,
+		logger,
+	)
+	requestLogger := logging.NewInterceptor(logger)
+	reg := metrics.NewRegistry()
+	rpcMetrics := metrics.NewRPCMetrics(reg)
+	registerMetrics(reg, chatService, userService, limiter)
+	unary := []grpc.UnaryServerInterceptor{requestLogger.Unary, rpcMetrics.Unary, limiter.Unary}
+	stream := []grpc.StreamServerInterceptor{requestLogger.Stream, rpcMetrics.Stream, limiter.Stream}
+	opts = append(
+		opts,
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	grpcServer := grpc.NewServer(opts...)
+
+	logger.Debug("registering services with grpc")
+	pb.RegisterUserServiceServer(grpcServer, userService)
+	pb.RegisterChatServiceServer(grpcServer, chatService)
+	pb.RegisterModerationServiceServer(grpcServer, modService)
+
+	var httpServer *http.Server
+	if *httpAddr != "" {
+		httpServer = &http.Server{
+			Addr:      *httpAddr,
+			Handler:   gateway.New(userService, chatService, unary, stream, *httpOrigin, logger),
+			TLSConfig: tlsConfig,
+		}
+		go serveGateway(httpServer)
+	}
+	var ircServer *irc.Server
+	if *ircAddr != "" {
+		ln, err := net.Listen("tcp", *ircAddr)
+		if err != nil {
+			logger.Fatal("failed to listen for IRC clients", "err", err)
+		}
+		if tlsConfig != nil {
+			ln = tls.NewListener(ln, tlsConfig)
+		}
+		ircServer = irc.NewServer(ln, userService, chatService, unary, stream, logger)
+		go ircServer.Serve()
+		logger.Info("serving IRC", "addr", ln.Addr())
 	}
 
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, os.Interrupt, os.Kill, syscall.SIGTERM)
+	shutdownDone := make(chan struct{})
 	go func() {
-		for {
-			select {
-			case signal := <-signalChan:
-				log.Println("Received", signal, "- exiting...")
-				os.Exit(0)
-			}
+		signal := <-signalChan
+		logger.Info("shutting down", "signal", signal)
+		go func() {
+			signal := <-signalChan
+			logger.Info("exiting", "signal", signal)
+			os.Exit(1)
+		}()
+		shutdown(grpcServer, httpServer, ircServer, chatService, userStorage, msgStorage, mailboxStorage)
+		if bp != nil {
+			bp.Close()
 		}
+		close(shutdownDone)
 	}()
 
-	grpcServer := grpc.NewServer()
+	if *metricsAddr != "" {
+		go serveMetrics(*metricsAddr, reg)
+	}
+
+	logger.Info("listening", "addr", listener.Addr())
+	err = grpcServer.Serve(listener)
+	if err != nil {
+		logger.Fatal("failed to serve", "err", err)
+	}
+	<-shutdownDone
+}
 
-	c.Debugln("setting up storage, chat and user service")
-	userStorage := storage.NewInMemoryUserStorage()
-	chatService := chat.NewService(userStorage)
-	userService := user.NewService(chatService, userStorage)
+type closer interface {
+	Close() error
+}
 
-	c.Debugln("registering services with grpc")
-	pb.RegisterUserServiceServer(grpcServer, userService)
-	pb.RegisterChatServiceServer(grpcServer, chatService)
+// shutdown notifies listening clients, waits for in-flight requests for at
+// most shutdownTimeout and flushes the storage. The HTTP and IRC servers
+// are nil if disabled.
+func shutdown(grpcServer *grpc.Server, httpServer *http.Server, ircServer *irc.Server, chatService *chat.Service, storages ...closer) {
+	chatService.Shutdown("server shutting down", *reconnectAfter)
+	if ircServer != nil {
+		ircServer.Close()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		if httpServer != nil {
+			httpServer.Shutdown(context.Background())
+		}
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(*shutdownTimeout):
+		logger.Warn("requests still in flight, stopping", "timeout", *shutdownTimeout)
+		if httpServer != nil {
+			httpServer.Close()
+		}
+		grpcServer.Stop()
+	}
+
+	for _, s := range storages {
+		if err := s.Close(); err != nil {
+			logger.Error("failed to close storage", "err", err)
+		}
+	}
+	logger.Info("shutdown complete")
+}
+
+// serveGateway serves the JSON over HTTP gateway until the server is shut
+// down, using TLS if the gRPC server does.
+func serveGateway(server *http.Server) {
+	logger.Info("serving HTTP gateway", "addr", server.Addr)
+	var err error
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		logger.Fatal("failed to serve HTTP gateway", "err", err)
+	}
+}
 
-	c.Debugln("listening on", listener.Addr())
-	grpcServer.Serve(listener)
+// newBackplane starts the embedded broker if -backplane-listen is set and
+// connects to the backplane, returning nil if the server is not clustered.
+func newBackplane() backplane.Backplane {
+	addr := *backplaneAddr
+	if *backplaneListen != "" {
+		ln, err := net.Listen("tcp", *backplaneListen)
+		if err != nil {
+			logger.Fatal("failed to listen for backplane", "err", err)
+		}
+		go backplane.NewBroker(ln, logger).Serve()
+		logger.Info("serving backplane", "addr", ln.Addr())
+		if addr == "" {
+			addr = ln.Addr().String()
+		}
+	}
+	if addr == "" {
+		return nil
+	}
+	bp, err := backplane.DialRedis(addr, logger)
+	if err != nil {
+		logger.Fatal("failed to connect to backplane", "addr", addr, "err", err)
+	}
+	return bp
+}
+
+func newLogger() *logging.Logger {
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *verbose {
+		level = logging.LevelDebug
+	}
+	format, err := logging.ParseFormat(*logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	return logging.New(os.Stderr, format, level)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/metrics"
+	"github.com/tormoder/chat/ratelimit"
+	"github.com/tormoder/chat/user"
+)
+
+// registerMetrics registers the metrics collected by the services, which
+// are read when scraped.
+func registerMetrics(reg *metrics.Registry, chatService *chat.Service, userService *user.Service, limiter *ratelimit.Interceptor) {
+	reg.NewGaugeFunc(
+		"chat_connected_clients",
+		"Client sessions currently listening for messages.",
+		func() float64 { return float64(chatService.AttachedCount()) },
+	)
+	reg.NewGaugeVecFunc(
+		"chat_client_queue_length",
+		"Messages queued for each connected user, summed over its sessions.",
+		"nick",
+		func() map[string]float64 {
+			lens := make(map[string]float64)
+			for nick, n := range chatService.QueueLens() {
+				lens[nick] = float64(n)
+			}
+			return lens
+		},
+	)
+	reg.NewCounterVecFunc(
+		"chat_messages_sent_total",
+		"Messages sent by users, by type of message.",
+		"type",
+		func() map[string]float64 { return toFloats(chatService.SentCounts()) },
+	)
+	reg.NewCounterVecFunc(
+		"chat_messages_dropped_total",
+		"Messages dropped because the recipient's queue or mailbox was full, by recipient.",
+		"nick",
+		func() map[string]float64 { return toFloats(chatService.DropCounts()) },
+	)
+	reg.NewCounterFunc(
+		"chat_logins_total",
+		"Successful logins.",
+		func() float64 { return float64(userService.LoginCount()) },
+	)
+	reg.NewCounterFunc(
+		"chat_logouts_total",
+		"Login sessions ended, by logging out, kicks, expiry or shutdown.",
+		func() float64 { return float64(chatService.LogoutCount()) },
+	)
+	reg.NewCounterVecFunc(
+		"chat_rate_limited_total",
+		"Requests rejected by the rate limits, by limit.",
+		"limit",
+		func() map[string]float64 {
+			stats := limiter.Stats()
+			return map[string]float64{
+				"user": float64(stats.UserRejected),
+				"peer": float64(stats.PeerRejected),
+			}
+		},
+	)
+}
+
+func toFloats(counts map[string]uint64) map[string]float64 {
+	floats := make(map[string]float64, len(counts))
+	for key, n := range counts {
+		floats[key] = float64(n)
+	}
+	return floats
+}
+
+func serveMetrics(addr string, reg *metrics.Registry) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	logger.Info("serving metrics", "addr", addr)
+	err := http.ListenAndServe(addr, mux)
+	if err != nil {
+		logger.Fatal("failed to serve metrics", "err", err)
+	}
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Spec describes a command taking exactly NArgs arguments, or one less if
+// Optional is set. The last argument holds the rest of the line and may
+// contain spaces.
+type Spec struct {
+	Name     string
+	Usage    string
+	Desc     string
+	NArgs    int
+	Optional bool
+}
+
+type Command struct {
+	Name string
+	Args []string
+}
+
+type Parser struct {
+	specs      []Spec
+	defaultCmd string
+}
+
+// NewParser returns a parser for commands written as /name followed by
+// their arguments. Lines not starting with a slash are parsed as the
+// argument of the one-argument command defaultCmd, if not empty. A line
+// starting with two slashes escapes the first one.
+func NewParser(defaultCmd string, specs ...Spec) *Parser {
+	return &Parser{
+		specs:      specs,
+		defaultCmd: defaultCmd,
+	}
+}
+
+// Parse parses line, it returns nil and no error for a blank line.
+func (p *Parser) Parse(line string) (*Command, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(line, "/") || strings.HasPrefix(line, "//") {
+		if p.defaultCmd == "" {
+			return nil, errors.New("commands must start with /")
+		}
+		return &Command{
+			Name: p.defaultCmd,
+			Args: []string{strings.TrimPrefix(line, "/")},
+		}, nil
+	}
+
+	name, rest := splitWord(line[1:])
+	spec, found := p.lookup(name)
+	if !found {
+		return nil, fmt.Errorf("unknown command /%s", name)
+	}
+	args := splitArgs(rest, spec.NArgs, spec.Optional)
+	if args == nil {
+		return nil, fmt.Errorf("usage: %s", formatUsage(spec))
+	}
+	return &Command{
+		Name: spec.Name,
+		Args: args,
+	}, nil
+}
+
+// Help returns a description of all commands, one per line.
+func (p *Parser) Help() string {
+	var out bytes.Buffer
+	out.WriteString("Available commands:")
+	for _, spec := range p.specs {
+		out.WriteString(
+			fmt.Sprintf("\n\t%-23s %s", formatUsage(spec), spec.Desc),
+		)
+	}
+	return out.String()
+}
+
+func (p *Parser) lookup(name string) (Spec, bool) {
+	for _, spec := range p.specs {
+		if spec.Name == name {
+			return spec, true
+		}
+	}
+	return Spec{}, false
+}
+
+func formatUsage(spec Spec) string {
+	if spec.Usage == "" {
+		return "/" + spec.Name
+	}
+	return "/" + spec.Name + " " + spec.Usage
+}
+
+func splitWord(s string) (word, rest string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+// splitArgs splits s into n arguments, or n-1 if the last one is optional.
+// It returns nil if there are too few or too many.
+func splitArgs(s string, n int, optional bool) []string {
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		var arg string
+		if i == n-1 {
+			arg, s = strings.TrimSpace(s), ""
+		} else {
+			arg, s = splitWord(s)
+		}
+		if arg == "" && i == n-1 && optional {
+			break
+		}
+		if arg == "" {
+			return nil
+		}
+		args = append(args, arg)
+	}
+	if s != "" {
+		return nil
+	}
+	return args
+}
@@ -0,0 +1,88 @@
+package command_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tormoder/chat/command"
+)
+
+var parser = command.NewParser(
+	"say",
+	command.Spec{Name: "say", Usage: "<text>", NArgs: 1},
+	command.Spec{Name: "msg", Usage: "<nick> <text>", NArgs: 2},
+	command.Spec{Name: "users", NArgs: 0},
+	command.Spec{Name: "status", Usage: "<status> [<text>]", NArgs: 2, Optional: true},
+)
+
+func cmd(name string, args ...string) *command.Command {
+	return &command.Command{
+		Name: name,
+		Args: append([]string{}, args...),
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line string
+		want *command.Command
+	}{
+		{"", nil},
+		{"  \t ", nil},
+		{"hello world", cmd("say", "hello world")},
+		{"  padded  ", cmd("say", "padded")},
+		{"//not a command", cmd("say", "/not a command")},
+		{"/say hi  there", cmd("say", "hi  there")},
+		{"/msg bob hi there", cmd("msg", "bob", "hi there")},
+		{"/msg\tbob   hi", cmd("msg", "bob", "hi")},
+		{"/users", cmd("users")},
+		{" /users ", cmd("users")},
+		{"/status away", cmd("status", "away")},
+		{"/status away  out for lunch", cmd("status", "away", "out for lunch")},
+	}
+	for _, test := range tests {
+		got, err := parser.Parse(test.line)
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error: %v", test.line, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q) = %v, want %v", test.line, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"/bogus", "unknown command /bogus"},
+		{"/", "unknown command /"},
+		{"/msg", "usage: /msg <nick> <text>"},
+		{"/msg bob", "usage: /msg <nick> <text>"},
+		{"/say", "usage: /say <text>"},
+		{"/users all", "usage: /users"},
+		{"/status", "usage: /status <status> [<text>]"},
+	}
+	for _, test := range tests {
+		got, err := parser.Parse(test.line)
+		if err == nil {
+			t.Errorf("Parse(%q) = %v, want error", test.line, got)
+			continue
+		}
+		if err.Error() != test.want {
+			t.Errorf("Parse(%q): got error %q, want %q", test.line, err, test.want)
+		}
+	}
+}
+
+func TestParseWithoutDefault(t *testing.T) {
+	p := command.NewParser("", command.Spec{Name: "users"})
+	if _, err := p.Parse("hello"); err == nil {
+		t.Error("plain text accepted without a default command")
+	}
+	if cmd, err := p.Parse("/users"); err != nil || cmd.Name != "users" {
+		t.Errorf("Parse(%q) = %v, %v", "/users", cmd, err)
+	}
+}
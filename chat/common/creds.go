@@ -0,0 +1,19 @@
+package common
+
+import pb "github.com/tormoder/chat/proto"
+
+// RequestCreds returns the credentials sent with a request, or nil if it
+// has none.
+func RequestCreds(req interface{}) *pb.Credentials {
+	switch r := req.(type) {
+	case *pb.Credentials:
+		return r
+	case *pb.ChatClientMsg:
+		return r.GetOpen().GetCreds()
+	case interface {
+		GetCreds() *pb.Credentials
+	}:
+		return r.GetCreds()
+	}
+	return nil
+}
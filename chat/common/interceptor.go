@@ -0,0 +1,79 @@
+package common
+
+import (
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// ChainUnary returns handler wrapped in interceptors, the first outermost,
+// for frontends calling the services without a gRPC server.
+func ChainUnary(interceptors []grpc.UnaryServerInterceptor, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) grpc.UnaryHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, info, next)
+		}
+	}
+	return handler
+}
+
+// ChainStream is ChainUnary for streams.
+func ChainStream(interceptors []grpc.StreamServerInterceptor, info *grpc.StreamServerInfo, handler grpc.StreamHandler) grpc.StreamHandler {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], handler
+		handler = func(srv interface{}, ss grpc.ServerStream) error {
+			return interceptor(srv, ss, info, next)
+		}
+	}
+	return handler
+}
+
+// RequestStream is a server stream for frontends calling the services
+// without a gRPC server. Its request is received once and the messages sent
+// on it are passed to send.
+type RequestStream struct {
+	ctx  context.Context
+	req  proto.Message // nil once received
+	send func(m interface{}) error
+}
+
+func NewRequestStream(ctx context.Context, req proto.Message, send func(m interface{}) error) *RequestStream {
+	return &RequestStream{ctx: ctx, req: req, send: send}
+}
+
+func (s *RequestStream) SetHeader(metadata.MD) error  { return nil }
+func (s *RequestStream) SendHeader(metadata.MD) error { return nil }
+func (s *RequestStream) SetTrailer(metadata.MD)       {}
+func (s *RequestStream) Context() context.Context     { return s.ctx }
+
+func (s *RequestStream) SendMsg(m interface{}) error {
+	// Writes to a gone client may not fail for a while
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	return s.send(m)
+}
+
+func (s *RequestStream) RecvMsg(m interface{}) error {
+	if s.req == nil {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.req)
+	s.req = nil
+	return nil
+}
+
+// MsgStream is the typed stream the chat service sends messages on.
+type MsgStream struct {
+	grpc.ServerStream
+}
+
+func (s *MsgStream) Send(msg *pb.ChatServerMsg) error {
+	return s.SendMsg(msg)
+}
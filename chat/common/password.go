@@ -0,0 +1,88 @@
+package common
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Argon2id parameters, as recommended for interactive logins.
+const (
+	argonTime    = 1
+	argonMemory  = 64 * 1024 // KiB
+	argonThreads = 4
+	argonKeyLen  = 32
+	saltSize     = 16
+)
+
+const MinPasswordLen = 8
+
+var (
+	ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLen)
+	errBadPasswordHash  = errors.New("malformed password hash")
+)
+
+var b64 = base64.RawStdEncoding
+
+// kdfSlots bounds the number of concurrent key derivations, each of which
+// uses argonMemory.
+var kdfSlots = make(chan struct{}, 4)
+
+func idKey(password string, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+	kdfSlots <- struct{}{}
+	defer func() { <-kdfSlots }()
+	return argon2.IDKey([]byte(password), salt, time, memory, threads, keyLen)
+}
+
+// HashPassword derives a hash of password with Argon2id and a random salt.
+// The hash is encoded together with its parameters in the PHC string
+// format.
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLen {
+		return "", ErrPasswordTooShort
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := idKey(password, salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		b64.EncodeToString(salt), b64.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword reports whether password matches hash from HashPassword.
+func CheckPassword(hash, password string) (bool, error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 || fields[0] != "" || fields[1] != "argon2id" {
+		return false, errBadPasswordHash
+	}
+	var (
+		version      int
+		memory, time uint32
+		threads      uint8
+	)
+	if _, err := fmt.Sscanf(fields[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errBadPasswordHash
+	}
+	if _, err := fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, errBadPasswordHash
+	}
+	salt, err := b64.DecodeString(fields[4])
+	if err != nil {
+		return false, errBadPasswordHash
+	}
+	want, err := b64.DecodeString(fields[5])
+	if err != nil || len(want) == 0 {
+		return false, errBadPasswordHash
+	}
+	key := idKey(password, salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(key, want) == 1, nil
+}
@@ -0,0 +1,21 @@
+package common
+
+import (
+	"net"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerHost returns the IP address of the peer in ctx, if any.
+func PeerHost(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "", false
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String(), true
+	}
+	return host, true
+}
@@ -0,0 +1,30 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	TokenSize     = 32
+	SessionIDSize = 8
+)
+
+func NewToken() ([]byte, error) {
+	token := make([]byte, TokenSize)
+	_, err := rand.Read(token)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// NewSessionID returns a random hex encoded session id.
+func NewSessionID() (string, error) {
+	id := make([]byte, SessionIDSize)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
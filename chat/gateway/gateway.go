@@ -0,0 +1,293 @@
+// Package gateway serves the user and chat services as JSON over HTTP, for
+// clients that cannot speak gRPC.
+package gateway
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+)
+
+// Requests larger than this are rejected.
+const maxBodySize = 1 << 20
+
+var (
+	marshaler   = &jsonpb.Marshaler{OrigName: true}
+	unmarshaler = &jsonpb.Unmarshaler{}
+)
+
+// method is a unary RPC served by the gateway.
+type method struct {
+	fullMethod string // As seen by the interceptors, e.g. /proto.UserService/Login
+	req        proto.Message
+	call       func(ctx context.Context, req proto.Message) (proto.Message, error)
+}
+
+// Gateway is an http.Handler calling the unary methods of the services for
+// POST /v1/<service>/<method> requests, with the request message in the
+// body and the response message as the reply, both as JSON. The messages of
+// a listening session are sent as server-sent events, see listen. The
+// bidirectional Chat stream is not available.
+//
+// Requests go through the same interceptors as gRPC requests. Only pages
+// from origin may call the gateway from other sites, none if it is empty.
+type Gateway struct {
+	users   pb.UserServiceServer
+	chat    pb.ChatServiceServer
+	unary   []grpc.UnaryServerInterceptor
+	stream  []grpc.StreamServerInterceptor
+	methods map[string]*method
+	origin  string
+	log     *logging.Logger
+}
+
+func New(users pb.UserServiceServer, chat pb.ChatServiceServer, unary []grpc.UnaryServerInterceptor, stream []grpc.StreamServerInterceptor, origin string, logger *logging.Logger) *Gateway {
+	g := &Gateway{
+		users:   users,
+		chat:    chat,
+		unary:   unary,
+		stream:  stream,
+		methods: make(map[string]*method),
+		origin:  origin,
+		log:     logger,
+	}
+	g.addUserMethods()
+	g.addChatMethods()
+	return g
+}
+
+func (g *Gateway) add(service, name string, req proto.Message, call func(ctx context.Context, req proto.Message) (proto.Message, error)) {
+	g.methods["/v1/"+service+"/"+name] = &method{
+		fullMethod: "/proto." + service + "/" + name,
+		req:        req,
+		call:       call,
+	}
+}
+
+func (g *Gateway) addUserMethods() {
+	users := g.users
+	g.add("UserService", "Login", new(pb.LoginRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.Login(ctx, req.(*pb.LoginRequest))
+	})
+	g.add("UserService", "Logout", new(pb.Credentials), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.Logout(ctx, req.(*pb.Credentials))
+	})
+	g.add("UserService", "ListUsers", new(pb.Credentials), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.ListUsers(ctx, req.(*pb.Credentials))
+	})
+	g.add("UserService", "Register", new(pb.RegisterRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.Register(ctx, req.(*pb.RegisterRequest))
+	})
+	g.add("UserService", "ChangePassword", new(pb.AccountRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.ChangePassword(ctx, req.(*pb.AccountRequest))
+	})
+	g.add("UserService", "DeleteAccount", new(pb.AccountRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.DeleteAccount(ctx, req.(*pb.AccountRequest))
+	})
+	g.add("UserService", "GetPublicKey", new(pb.PublicKeyRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return users.GetPublicKey(ctx, req.(*pb.PublicKeyRequest))
+	})
+}
+
+func (g *Gateway) addChatMethods() {
+	chat := g.chat
+	g.add("ChatService", "SendPrivate", new(pb.PrivateMsgRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.SendPrivate(ctx, req.(*pb.PrivateMsgRequest))
+	})
+	g.add("ChatService", "SendPublic", new(pb.PublicMsgRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.SendPublic(ctx, req.(*pb.PublicMsgRequest))
+	})
+	g.add("ChatService", "GetHistory", new(pb.HistoryRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.GetHistory(ctx, req.(*pb.HistoryRequest))
+	})
+	g.add("ChatService", "CreateRoom", new(pb.RoomRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.CreateRoom(ctx, req.(*pb.RoomRequest))
+	})
+	g.add("ChatService", "JoinRoom", new(pb.RoomRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.JoinRoom(ctx, req.(*pb.RoomRequest))
+	})
+	g.add("ChatService", "LeaveRoom", new(pb.RoomRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.LeaveRoom(ctx, req.(*pb.RoomRequest))
+	})
+	g.add("ChatService", "ListRooms", new(pb.Credentials), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.ListRooms(ctx, req.(*pb.Credentials))
+	})
+	g.add("ChatService", "SendToRoom", new(pb.RoomMsgRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.SendToRoom(ctx, req.(*pb.RoomMsgRequest))
+	})
+	g.add("ChatService", "AckMessages", new(pb.AckRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.AckMessages(ctx, req.(*pb.AckRequest))
+	})
+	g.add("ChatService", "MarkRead", new(pb.AckRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.MarkRead(ctx, req.(*pb.AckRequest))
+	})
+	g.add("ChatService", "GetStats", new(pb.Credentials), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.GetStats(ctx, req.(*pb.Credentials))
+	})
+	g.add("ChatService", "SetPresence", new(pb.PresenceRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.SetPresence(ctx, req.(*pb.PresenceRequest))
+	})
+	g.add("ChatService", "SendTyping", new(pb.TypingRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.SendTyping(ctx, req.(*pb.TypingRequest))
+	})
+	g.add("ChatService", "Ping", new(pb.PingRequest), func(ctx context.Context, req proto.Message) (proto.Message, error) {
+		return chat.Ping(ctx, req.(*pb.PingRequest))
+	})
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if g.origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", g.origin)
+	}
+	if r.Method == "OPTIONS" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID")
+		w.Header().Set("Access-Control-Max-Age", "86400")
+		return
+	}
+
+	ctx := peerContext(r)
+	if r.URL.Path == listenPath {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			writeError(w, status.Error(codes.InvalidArgument, "listen with GET"), http.StatusMethodNotAllowed)
+			return
+		}
+		g.listen(ctx, w, r)
+		return
+	}
+
+	m, found := g.methods[r.URL.Path]
+	if !found {
+		writeError(w, status.Error(codes.Unimplemented, "unknown method"), 0)
+		return
+	}
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		writeError(w, status.Error(codes.InvalidArgument, "call methods with POST"), http.StatusMethodNotAllowed)
+		return
+	}
+	req := reflect.New(reflect.TypeOf(m.req).Elem()).Interface().(proto.Message)
+	err := unmarshaler.Unmarshal(http.MaxBytesReader(w, r.Body, maxBodySize), req)
+	if err != nil && err != io.EOF { // An empty body is an empty request
+		writeError(w, status.Error(codes.InvalidArgument, "malformed request: "+err.Error()), 0)
+		return
+	}
+
+	info := &grpc.UnaryServerInfo{Server: g, FullMethod: m.fullMethod}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return m.call(ctx, req.(proto.Message))
+	}
+	resp, err := c.ChainUnary(g.unary, info, handler)(ctx, req)
+	if err != nil {
+		writeError(w, err, 0)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := marshaler.Marshal(w, resp.(proto.Message)); err != nil {
+		g.log.WithContext(ctx).Warn("writing response failed", "method", m.fullMethod, "err", err)
+	}
+}
+
+// peerContext returns the context of r with the client address and TLS
+// state, as a gRPC request would have it.
+func peerContext(r *http.Request) context.Context {
+	p := &peer.Peer{Addr: httpAddr(r.RemoteAddr)}
+	if addr, err := net.ResolveTCPAddr("tcp", r.RemoteAddr); err == nil {
+		p.Addr = addr
+	}
+	if r.TLS != nil {
+		p.AuthInfo = credentials.TLSInfo{State: *r.TLS}
+	}
+	return peer.NewContext(r.Context(), p)
+}
+
+// httpAddr is a client address that is not a TCP address.
+type httpAddr string
+
+func (a httpAddr) Network() string { return "http" }
+func (a httpAddr) String() string  { return string(a) }
+
+// errorBody is the reply to failed requests.
+type errorBody struct {
+	Code  codes.Code `json:"code"`
+	Error string     `json:"error"`
+}
+
+// writeError replies with err and httpStatus, or the HTTP status matching
+// the gRPC status code of err if zero.
+func writeError(w http.ResponseWriter, err error, httpStatus int) {
+	st := statusOf(err)
+	if httpStatus == 0 {
+		httpStatus = httpStatusOf(st.Code())
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok {
+			if delay, err := ptypes.Duration(info.RetryDelay); err == nil {
+				secs := (delay + time.Second - 1) / time.Second
+				w.Header().Set("Retry-After", strconv.Itoa(int(secs)))
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	json.NewEncoder(w).Encode(errorBody{Code: st.Code(), Error: st.Message()})
+}
+
+// statusOf returns the gRPC status of err.
+func statusOf(err error) *status.Status {
+	switch err := err.(type) {
+	case c.AuthenticationError:
+		return status.New(codes.Unauthenticated, err.Error())
+	case c.InternalServerError:
+		return status.New(codes.Internal, err.Error())
+	}
+	return status.Convert(err)
+}
+
+func httpStatusOf(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	// Errors without a code are about the request, like a taken nick
+	case codes.Unknown, codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotFound
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
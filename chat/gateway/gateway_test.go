@@ -0,0 +1,178 @@
+package gateway_test
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/gateway"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+func newTestGateway(t *testing.T, methods chan<- string, origin string) *httptest.Server {
+	us := storage.NewInMemoryUserStorage()
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, chat.DefaultSessionTimeout, logging.Discard())
+	unary := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		methods <- info.FullMethod
+		return handler(ctx, req)
+	}
+	stream := func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		methods <- info.FullMethod
+		return handler(srv, ss)
+	}
+	gw := gateway.New(
+		user.NewService(cs, us, true, logging.Discard()),
+		cs,
+		[]grpc.UnaryServerInterceptor{unary},
+		[]grpc.StreamServerInterceptor{stream},
+		origin,
+		logging.Discard(),
+	)
+	return httptest.NewServer(gw)
+}
+
+// call posts body to the method and returns the response status and body.
+func call(t *testing.T, srv *httptest.Server, method, body string) (int, string) {
+	resp, err := http.Post(srv.URL+"/v1/"+method, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestGateway(t *testing.T) {
+	methods := make(chan string, 16)
+	srv := newTestGateway(t, methods, "https://chat.example.com")
+	defer srv.Close()
+
+	code, body := call(t, srv, "UserService/Login", `{"nick": "alice"}`)
+	if code != http.StatusOK {
+		t.Fatalf("login: got status %d: %s", code, body)
+	}
+	if m := <-methods; m != "/proto.UserService/Login" {
+		t.Errorf("interceptor called for %s, want /proto.UserService/Login", m)
+	}
+	creds := new(pb.Credentials)
+	if err := jsonpb.UnmarshalString(body, creds); err != nil {
+		t.Fatal(err)
+	}
+
+	query := url.Values{
+		"nick":    {creds.Nick},
+		"session": {creds.Session},
+		"token":   {base64.StdEncoding.EncodeToString(creds.Token)},
+	}
+	resp, err := http.Get(srv.URL + "/v1/ChatService/ListenForMessages?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != http.StatusOK || ct != "text/event-stream" {
+		t.Fatalf("listen: got status %d, content type %q", resp.StatusCode, ct)
+	}
+	if o := resp.Header.Get("Access-Control-Allow-Origin"); o != "https://chat.example.com" {
+		t.Errorf("got allowed origin %q, want https://chat.example.com", o)
+	}
+	if m := <-methods; m != "/proto.ChatService/ListenForMessages" {
+		t.Errorf("interceptor called for %s, want /proto.ChatService/ListenForMessages", m)
+	}
+
+	credsJSON, _ := (&jsonpb.Marshaler{}).MarshalToString(creds)
+	code, body = call(t, srv, "ChatService/SendPublic", `{"creds": `+credsJSON+`, "msg": "hello"}`)
+	if code != http.StatusOK {
+		t.Fatalf("send public: got status %d: %s", code, body)
+	}
+
+	events := make(chan *pb.ChatServerMsg)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if data := strings.TrimPrefix(scanner.Text(), "data: "); data != scanner.Text() {
+				msg := new(pb.ChatServerMsg)
+				if err := jsonpb.UnmarshalString(data, msg); err != nil {
+					t.Error(err)
+					return
+				}
+				events <- msg
+			}
+		}
+	}()
+	timeout := time.After(5 * time.Second)
+	for found := false; !found; {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				t.Fatal("event stream ended")
+			}
+			found = msg.GetPublicMsg().GetMsg() == "hello"
+		case <-timeout:
+			t.Fatal("timed out waiting for public message")
+		}
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	srv := newTestGateway(t, make(chan string, 16), "")
+	defer srv.Close()
+
+	tests := []struct {
+		method, body string
+		want         int
+	}{
+		{"UserService/ListUsers", `{"nick": "alice", "token": "AAAA"}`, http.StatusUnauthorized},
+		{"UserService/Login", `{"nick": `, http.StatusBadRequest},
+		{"UserService/Login", `{"nick": "alice", "color": "red"}`, http.StatusBadRequest},
+		{"ModerationService/Kick", `{}`, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		code, body := call(t, srv, test.method, test.body)
+		if code != test.want {
+			t.Errorf("%s %s: got status %d, want %d", test.method, test.body, code, test.want)
+		}
+		var reply struct {
+			Code  int    `json:"code"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(body), &reply); err != nil || reply.Error == "" {
+			t.Errorf("%s %s: got body %q, want an error", test.method, test.body, body)
+		}
+	}
+
+	resp, err := http.Get(srv.URL + "/v1/ChatService/ListenForMessages?nick=alice&token=AAAA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if o, found := resp.Header["Access-Control-Allow-Origin"]; found {
+		t.Errorf("got allowed origin %q without one configured", o)
+	}
+	// The stream is accepted before the credentials are checked
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "retry: ") || !strings.Contains(string(data), "event: error\ndata: {\"code\":16,") {
+		t.Errorf("listen with bad credentials: got status %d and %q, want a retry hint and an Unauthenticated error event", resp.StatusCode, data)
+	}
+}
@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+	pb "github.com/tormoder/chat/proto"
+)
+
+const listenPath = "/v1/ChatService/ListenForMessages"
+
+// How long an EventSource waits before reconnecting a dropped stream.
+const sseRetry = 3 * time.Second
+
+// listen serves the messages of a listening session as server-sent events,
+// for GET /v1/ChatService/ListenForMessages?nick=&session=&token= with the
+// token base64 encoded. Every message is a JSON encoded ChatServerMsg with
+// its cursor as the event id, so that an EventSource reconnecting with
+// Last-Event-ID resumes the session, as does passing the cursor parameter.
+// The response starts once the interceptors accept the stream; if it fails
+// after that, an error event with the same JSON as a failed request ends it.
+func (g *Gateway) listen(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, status.Error(codes.Internal, "streaming unsupported"), 0)
+		return
+	}
+	query := r.URL.Query()
+	creds, err := queryCreds(query)
+	if err != nil {
+		writeError(w, err, 0)
+		return
+	}
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = query.Get("cursor")
+	}
+
+	var (
+		sse     = &sseWriter{w: w, flusher: flusher}
+		info    = &grpc.StreamServerInfo{IsServerStream: true}
+		req     proto.Message
+		handler grpc.StreamHandler
+	)
+	if cursor == "" {
+		info.FullMethod = "/proto.ChatService/ListenForMessages"
+		req = creds
+		handler = func(srv interface{}, ss grpc.ServerStream) error {
+			creds := new(pb.Credentials)
+			if err := ss.RecvMsg(creds); err != nil {
+				return err
+			}
+			sse.start()
+			return g.chat.ListenForMessages(creds, &c.MsgStream{ServerStream: ss})
+		}
+	} else {
+		n, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			writeError(w, status.Error(codes.InvalidArgument, "malformed cursor"), 0)
+			return
+		}
+		info.FullMethod = "/proto.ChatService/ResumeListening"
+		req = &pb.ResumeRequest{Creds: creds, Cursor: n}
+		handler = func(srv interface{}, ss grpc.ServerStream) error {
+			resumeReq := new(pb.ResumeRequest)
+			if err := ss.RecvMsg(resumeReq); err != nil {
+				return err
+			}
+			sse.start()
+			return g.chat.ResumeListening(resumeReq, &c.MsgStream{ServerStream: ss})
+		}
+	}
+	err = c.ChainStream(g.stream, info, handler)(g, c.NewRequestStream(ctx, req, sse.send))
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+	if !sse.started {
+		writeError(w, err, 0)
+		return
+	}
+	st := statusOf(err)
+	data, _ := json.Marshal(errorBody{Code: st.Code(), Error: st.Message()})
+	fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+	flusher.Flush()
+}
+
+// queryCreds returns the credentials in the query parameters.
+func queryCreds(query url.Values) (*pb.Credentials, error) {
+	encoded := query.Get("token")
+	token, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Also accept the URL safe encoding, it needs no escaping
+		token, err = base64.RawURLEncoding.DecodeString(encoded)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "malformed token")
+	}
+	return &pb.Credentials{
+		Nick:    query.Get("nick"),
+		Token:   token,
+		Session: query.Get("session"),
+	}, nil
+}
+
+// sseWriter writes the messages of a stream as server-sent events.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool // Whether the response has been started
+}
+
+// start sends the response header and the reconnection delay, so that the
+// client knows the stream is accepted before the first message.
+func (s *sseWriter) start() {
+	if s.started {
+		return
+	}
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(s.w, "retry: %d\n\n", sseRetry/time.Millisecond)
+	s.flusher.Flush()
+	s.started = true
+}
+
+func (s *sseWriter) send(m interface{}) error {
+	msg := m.(*pb.ChatServerMsg)
+	data, err := marshaler.MarshalToString(msg)
+	if err != nil {
+		return err
+	}
+	s.start()
+	if msg.Cursor != 0 {
+		_, err = fmt.Fprintf(s.w, "id: %d\n", msg.Cursor)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err = fmt.Fprintf(s.w, "data: %s\n\n", data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,657 @@
+package irc
+
+import (
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+	pb "github.com/tormoder/chat/proto"
+)
+
+// The channel of the public chat. Rooms are the other channels.
+const publicChannel = "#chat"
+
+// Numeric replies, see RFC 2812 section 5.
+const (
+	numWelcome           = "001"
+	numYourHost          = "002"
+	numMyInfo            = "004"
+	numISupport          = "005"
+	numUModeIs           = "221"
+	numAway              = "301"
+	numEndOfWho          = "315"
+	numList              = "322"
+	numListEnd           = "323"
+	numChannelModeIs     = "324"
+	numNamReply          = "353"
+	numEndOfNames        = "366"
+	numNoSuchNick        = "401"
+	numNoSuchChannel     = "403"
+	numCannotSendToChan  = "404"
+	numNoRecipient       = "411"
+	numNoTextToSend      = "412"
+	numUnknownCommand    = "421"
+	numNoMotd            = "422"
+	numNoNicknameGiven   = "431"
+	numErroneusNickname  = "432"
+	numNicknameInUse     = "433"
+	numNotOnChannel      = "442"
+	numNotRegistered     = "451"
+	numNeedMoreParams    = "461"
+	numAlreadyRegistered = "462"
+	numPasswdMismatch    = "464"
+	numChanOPrivsNeeded  = "482"
+)
+
+// client is the connection of an IRC client.
+type client struct {
+	srv    *Server
+	conn   net.Conn
+	ctx    context.Context // With the peer, done when the connection is closed
+	cancel context.CancelFunc
+	once   sync.Once
+
+	// Set while registering, only accessed by the reading goroutine
+	pass, nick, username string
+	// Set once registered and logged in
+	creds *pb.Credentials
+
+	writeMu sync.Mutex
+
+	mu       sync.Mutex      // Protects channels and echoes
+	channels map[string]bool // Joined, publicChannel included
+	// Messages sent by the client and not yet delivered back to it, IRC
+	// clients show what they send themselves
+	echoes map[echo]int
+}
+
+// echo is a message sent to a channel, or privately to a chat nick.
+type echo struct {
+	target, text string
+}
+
+// sending records that the client sends text to target and returns a
+// function to call if sending fails.
+func (cl *client) sending(target, text string) func() {
+	e := echo{target, text}
+	cl.mu.Lock()
+	cl.echoes[e]++
+	cl.mu.Unlock()
+	return func() {
+		cl.isEcho(e.target, e.text)
+	}
+}
+
+// isEcho reports whether a message from the client's own nick was sent by
+// this client, forgetting it if so.
+func (cl *client) isEcho(target, text string) bool {
+	e := echo{target, text}
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.echoes[e] == 0 {
+		return false
+	}
+	if cl.echoes[e]--; cl.echoes[e] == 0 {
+		delete(cl.echoes, e)
+	}
+	return true
+}
+
+func (cl *client) close() {
+	cl.once.Do(func() {
+		cl.cancel()
+		cl.conn.Close()
+	})
+}
+
+// send writes a message with the given prefix, the server's if empty.
+func (cl *client) send(prefix, command string, params ...string) error {
+	if prefix == "" {
+		prefix = cl.srv.name
+	}
+	line := (&message{prefix: prefix, command: command, params: params}).String()
+	cl.writeMu.Lock()
+	defer cl.writeMu.Unlock()
+	cl.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := io.WriteString(cl.conn, line+"\r\n")
+	if err != nil {
+		cl.close()
+	}
+	return err
+}
+
+// reply sends a numeric reply.
+func (cl *client) reply(numeric string, params ...string) error {
+	nick := cl.nick
+	if nick == "" {
+		nick = "*"
+	}
+	return cl.send("", numeric, append([]string{nick}, params...)...)
+}
+
+// sendText sends text as one or more messages to target.
+func (cl *client) sendText(prefix, command, target, text string) error {
+	for _, line := range splitText(text) {
+		if err := cl.send(prefix, command, target, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cl *client) ping() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cl.send("", "PING", cl.srv.name)
+		case <-cl.ctx.Done():
+			return
+		}
+	}
+}
+
+// call calls the unary method fullMethod through the interceptors.
+func (cl *client) call(fullMethod string, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	info := &grpc.UnaryServerInfo{Server: cl.srv, FullMethod: fullMethod}
+	return c.ChainUnary(cl.srv.unary, info, handler)(cl.ctx, req)
+}
+
+// joined reports whether the client is in channel.
+func (cl *client) joined(channel string) bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.channels[channel]
+}
+
+// setJoined records whether the client is in channel and, if that changed,
+// tells the client.
+func (cl *client) setJoined(channel string, joined bool) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.channels[channel] == joined {
+		return
+	}
+	if joined {
+		cl.channels[channel] = true
+	} else {
+		delete(cl.channels, channel)
+	}
+	command := "PART"
+	if joined {
+		command = "JOIN"
+	}
+	cl.send(cl.srv.userPrefix(cl.creds.Nick), command, channel)
+}
+
+// handle handles a message from the client and reports whether to keep
+// reading.
+func (cl *client) handle(m *message) bool {
+	switch m.command {
+	case "PASS":
+		if cl.creds != nil {
+			cl.reply(numAlreadyRegistered, "You may not reregister")
+			return true
+		}
+		cl.pass = m.param(0)
+		return true
+	case "NICK":
+		return cl.setNick(m.param(0))
+	case "USER":
+		if cl.creds != nil {
+			cl.reply(numAlreadyRegistered, "You may not reregister")
+			return true
+		}
+		if len(m.params) < 4 {
+			cl.reply(numNeedMoreParams, m.command, "Not enough parameters")
+			return true
+		}
+		cl.username = m.param(0)
+		return cl.register()
+	case "PING":
+		cl.send("", "PONG", cl.srv.name, m.param(0))
+		return true
+	case "PONG":
+		return true
+	case "QUIT":
+		cl.send("", "ERROR", "Closing link: quit")
+		return false
+	}
+
+	if cl.creds == nil {
+		cl.reply(numNotRegistered, "You have not registered")
+		return true
+	}
+	switch m.command {
+	case "PRIVMSG":
+		cl.privmsg(m, false)
+	case "NOTICE":
+		cl.privmsg(m, true)
+	case "JOIN":
+		cl.join(m)
+	case "PART":
+		cl.part(m)
+	case "NAMES":
+		cl.namesCmd(m)
+	case "LIST":
+		cl.list()
+	case "WHO":
+		cl.reply(numEndOfWho, m.param(0), "End of WHO list")
+	case "MODE":
+		cl.mode(m)
+	default:
+		cl.reply(numUnknownCommand, m.command, "Unknown command")
+	}
+	return true
+}
+
+func (cl *client) setNick(nick string) bool {
+	switch {
+	case nick == "":
+		cl.reply(numNoNicknameGiven, "No nickname given")
+	case cl.creds != nil:
+		cl.reply(numErroneusNickname, nick, "Nick changes are not supported, reconnect to use another nick")
+	case !validNick(nick):
+		cl.reply(numErroneusNickname, nick, "Erroneous nickname")
+	default:
+		cl.nick = nick
+		return cl.register()
+	}
+	return true
+}
+
+// register logs the client in once it has sent both NICK and USER, and
+// reports whether to keep reading.
+func (cl *client) register() bool {
+	if cl.nick == "" || cl.username == "" {
+		return true
+	}
+	lreq := &pb.LoginRequest{Nick: cl.nick, Password: cl.pass}
+	resp, err := cl.call("/proto.UserService/Login", lreq, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return cl.srv.users.Login(ctx, req.(*pb.LoginRequest))
+	})
+	if err != nil {
+		if _, ok := err.(c.AuthenticationError); ok {
+			// Like a nick taken by someone else, let the client try another
+			cl.reply(numNicknameInUse, cl.nick, err.Error())
+			cl.nick = ""
+			return true
+		}
+		if status.Code(err) == codes.Unauthenticated {
+			cl.reply(numPasswdMismatch, status.Convert(err).Message())
+		}
+		cl.send("", "ERROR", "Closing link: "+status.Convert(err).Message())
+		return false
+	}
+	cl.creds = resp.(*pb.Credentials)
+
+	name := cl.srv.name
+	cl.reply(numWelcome, "Welcome to the chat, "+cl.nick)
+	cl.reply(numYourHost, "Your host is "+name)
+	cl.reply(numMyInfo, name, "chat", "o", "o")
+	cl.reply(numISupport, "CHANTYPES=#", "NETWORK=chat", "are supported by this server")
+	cl.reply(numNoMotd, "MOTD File is missing")
+	cl.setJoined(publicChannel, true)
+	cl.names(publicChannel)
+	go cl.listen()
+	return true
+}
+
+func (cl *client) logout() {
+	if cl.creds == nil {
+		return
+	}
+	cl.call("/proto.UserService/Logout", cl.creds, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return cl.srv.users.Logout(ctx, req.(*pb.Credentials))
+	})
+}
+
+// listen passes the messages of the client's listening session on until the
+// connection is closed.
+func (cl *client) listen() {
+	stream := c.NewRequestStream(cl.ctx, cl.creds, func(m interface{}) error {
+		return cl.deliver(m.(*pb.ChatServerMsg))
+	})
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.ChatService/ListenForMessages", IsServerStream: true}
+	err := c.ChainStream(cl.srv.stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		creds := new(pb.Credentials)
+		if err := ss.RecvMsg(creds); err != nil {
+			return err
+		}
+		return cl.srv.chat.ListenForMessages(creds, &c.MsgStream{ServerStream: ss})
+	})(cl.srv, stream)
+	if cl.ctx.Err() != nil {
+		return
+	}
+	reason := "connection closed"
+	if err != nil {
+		reason = status.Convert(err).Message()
+	}
+	cl.send("", "ERROR", "Closing link: "+reason)
+	cl.close()
+}
+
+// deliver sends msg from the listening session to the client.
+func (cl *client) deliver(msg *pb.ChatServerMsg) error {
+	self := cl.creds.Nick
+	switch m := msg.Msg.(type) {
+	case *pb.ChatServerMsg_PublicMsg:
+		from := m.PublicMsg.GetFrom().GetNick()
+		channel := publicChannel
+		if msg.Room != "" {
+			channel = "#" + msg.Room
+		}
+		if from == self && cl.isEcho(channel, m.PublicMsg.Msg) {
+			return nil
+		}
+		if msg.Room != "" {
+			// The nick may have joined from another client
+			cl.setJoined(channel, true)
+		} else if !cl.joined(publicChannel) {
+			return nil
+		}
+		return cl.sendText(cl.srv.userPrefix(from), "PRIVMSG", channel, m.PublicMsg.Msg)
+	case *pb.ChatServerMsg_PrivateMsg:
+		return cl.deliverPrivate(m.PrivateMsg)
+	case *pb.ChatServerMsg_UserEvent:
+		return cl.userEvent(msg.Room, m.UserEvent)
+	case *pb.ChatServerMsg_ServerShutdown:
+		cl.send("", "ERROR", "Closing link: "+m.ServerShutdown.Reason)
+		cl.close()
+	}
+	return nil
+}
+
+func (cl *client) deliverPrivate(pmsg *pb.PrivateMsg) error {
+	from := pmsg.GetFrom().GetNick()
+	var err error
+	switch {
+	case from == cl.creds.Nick && pmsg.Encrypted == nil && cl.isEcho(pmsg.To, pmsg.Msg):
+	case pmsg.Encrypted != nil:
+		err = cl.send(cl.srv.userPrefix(from), "NOTICE", ircNick(cl.creds.Nick), "(encrypted private message, read it with chatclient)")
+	default:
+		err = cl.sendText(cl.srv.userPrefix(from), "PRIVMSG", ircNick(cl.creds.Nick), pmsg.Msg)
+	}
+	if err != nil || pmsg.Id == 0 {
+		return err
+	}
+	// Acknowledged by the frontend rather than the user, so not limited
+	_, err = cl.srv.chat.AckMessages(cl.ctx, &pb.AckRequest{Creds: cl.creds, UpTo: pmsg.Id})
+	if err != nil {
+		cl.srv.log.WithContext(cl.ctx).Warn("acknowledging private message failed", "err", err)
+	}
+	return nil
+}
+
+func (cl *client) userEvent(room string, event *pb.UserEvent) error {
+	nick := event.GetUser().GetNick()
+	self := nick == cl.creds.Nick
+	prefix := cl.srv.userPrefix(nick)
+	if room != "" {
+		channel := "#" + room
+		switch event.Event {
+		case pb.UserEvent_JOIN:
+			if self {
+				cl.setJoined(channel, true)
+				return nil
+			}
+			return cl.send(prefix, "JOIN", channel)
+		case pb.UserEvent_LEAVE:
+			if self {
+				cl.setJoined(channel, false)
+				return nil
+			}
+			return cl.send(prefix, "PART", channel)
+		}
+		return nil
+	}
+	if self || !cl.joined(publicChannel) {
+		return nil
+	}
+	switch event.Event {
+	case pb.UserEvent_LOGIN:
+		return cl.send(prefix, "JOIN", publicChannel)
+	case pb.UserEvent_LOGOUT:
+		return cl.send(prefix, "QUIT", "Logged out")
+	case pb.UserEvent_KICK:
+		return cl.send(prefix, "QUIT", "Kicked")
+	}
+	return nil
+}
+
+// privmsg sends a message to a channel or nick. Notices are sent the same
+// way, but never answered with errors.
+func (cl *client) privmsg(m *message, notice bool) {
+	target, text := m.param(0), m.param(1)
+	fail := func(numeric string, params ...string) {
+		if !notice {
+			cl.reply(numeric, params...)
+		}
+	}
+	if target == "" {
+		fail(numNoRecipient, "No recipient given ("+m.command+")")
+		return
+	}
+	if text == "" {
+		fail(numNoTextToSend, "No text to send")
+		return
+	}
+	if strings.HasPrefix(text, "\x01") {
+		// CTCP, of which only actions mean something to other clients
+		if !strings.HasPrefix(text, "\x01ACTION ") {
+			return
+		}
+		text = "*" + strings.TrimSuffix(text[len("\x01ACTION "):], "\x01") + "*"
+	}
+
+	var (
+		resp interface{}
+		err  error
+	)
+	to := target
+	if !strings.HasPrefix(target, "#") {
+		var ok bool
+		if to, ok = chatNick(target); !ok {
+			fail(numNoSuchNick, target, "No such nick")
+			return
+		}
+	}
+	// Private messages only come back when sent to the client's own nick
+	unsent := func() {}
+	if strings.HasPrefix(target, "#") || to == cl.creds.Nick {
+		unsent = cl.sending(to, text)
+	}
+	switch {
+	case target == publicChannel:
+		resp, err = cl.call("/proto.ChatService/SendPublic", &pb.PublicMsgRequest{Creds: cl.creds, Msg: text}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return cl.srv.chat.SendPublic(ctx, req.(*pb.PublicMsgRequest))
+		})
+	case strings.HasPrefix(target, "#"):
+		rreq := &pb.RoomMsgRequest{Creds: cl.creds, Room: target[1:], Msg: text}
+		resp, err = cl.call("/proto.ChatService/SendToRoom", rreq, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return cl.srv.chat.SendToRoom(ctx, req.(*pb.RoomMsgRequest))
+		})
+	default:
+		preq := &pb.PrivateMsgRequest{Creds: cl.creds, To: to, Msg: text}
+		resp, err = cl.call("/proto.ChatService/SendPrivate", preq, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return cl.srv.chat.SendPrivate(ctx, req.(*pb.PrivateMsgRequest))
+		})
+	}
+	if err != nil {
+		unsent()
+		if strings.HasPrefix(target, "#") {
+			fail(numCannotSendToChan, target, status.Convert(err).Message())
+		} else {
+			fail(numNoSuchNick, target, status.Convert(err).Message())
+		}
+		return
+	}
+	if sent := resp.(*pb.SendMsgResponse); sent.Status == pb.SendMsgResponse_QUEUED {
+		fail(numAway, target, "Offline, the message is delivered when they log in")
+	}
+}
+
+func (cl *client) join(m *message) {
+	if m.param(0) == "" {
+		cl.reply(numNeedMoreParams, m.command, "Not enough parameters")
+		return
+	}
+	for _, channel := range strings.Split(m.param(0), ",") {
+		if channel == publicChannel {
+			cl.setJoined(channel, true)
+			cl.names(channel)
+			continue
+		}
+		if !strings.HasPrefix(channel, "#") {
+			cl.reply(numNoSuchChannel, channel, "No such channel")
+			continue
+		}
+		rreq := &pb.RoomRequest{Creds: cl.creds, Room: channel[1:]}
+		_, err := cl.call("/proto.ChatService/JoinRoom", rreq, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return cl.srv.chat.JoinRoom(ctx, req.(*pb.RoomRequest))
+		})
+		if status.Code(err) == codes.NotFound {
+			// Joining a room that does not exist creates it
+			_, err = cl.call("/proto.ChatService/CreateRoom", rreq, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return cl.srv.chat.CreateRoom(ctx, req.(*pb.RoomRequest))
+			})
+		}
+		if err != nil {
+			cl.reply(numNoSuchChannel, channel, status.Convert(err).Message())
+			continue
+		}
+		cl.setJoined(channel, true)
+		cl.names(channel)
+	}
+}
+
+func (cl *client) part(m *message) {
+	if m.param(0) == "" {
+		cl.reply(numNeedMoreParams, m.command, "Not enough parameters")
+		return
+	}
+	for _, channel := range strings.Split(m.param(0), ",") {
+		if !cl.joined(channel) {
+			cl.reply(numNotOnChannel, channel, "You're not on that channel")
+			continue
+		}
+		if channel != publicChannel {
+			rreq := &pb.RoomRequest{Creds: cl.creds, Room: channel[1:]}
+			_, err := cl.call("/proto.ChatService/LeaveRoom", rreq, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return cl.srv.chat.LeaveRoom(ctx, req.(*pb.RoomRequest))
+			})
+			if err != nil {
+				cl.reply(numNotOnChannel, channel, status.Convert(err).Message())
+				continue
+			}
+		}
+		cl.setJoined(channel, false)
+	}
+}
+
+func (cl *client) namesCmd(m *message) {
+	if m.param(0) != "" {
+		for _, channel := range strings.Split(m.param(0), ",") {
+			cl.names(channel)
+		}
+		return
+	}
+	cl.mu.Lock()
+	channels := make([]string, 0, len(cl.channels))
+	for channel := range cl.channels {
+		channels = append(channels, channel)
+	}
+	cl.mu.Unlock()
+	sort.Strings(channels)
+	for _, channel := range channels {
+		cl.names(channel)
+	}
+}
+
+// names sends the nicks in channel.
+func (cl *client) names(channel string) {
+	var nicks []string
+	if channel == publicChannel {
+		for _, user := range cl.listUsers() {
+			nicks = append(nicks, ircNick(user.Nick))
+		}
+	} else {
+		for _, room := range cl.listRooms() {
+			if "#"+room.Name != channel {
+				continue
+			}
+			for _, nick := range room.Members {
+				nicks = append(nicks, ircNick(nick))
+			}
+		}
+	}
+	line := ""
+	for _, nick := range nicks {
+		if len(line)+len(nick) >= maxTextLen {
+			cl.reply(numNamReply, "=", channel, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += nick
+	}
+	if line != "" {
+		cl.reply(numNamReply, "=", channel, line)
+	}
+	cl.reply(numEndOfNames, channel, "End of NAMES list")
+}
+
+func (cl *client) list() {
+	cl.reply(numList, publicChannel, strconv.Itoa(len(cl.listUsers())), "Public chat")
+	for _, room := range cl.listRooms() {
+		cl.reply(numList, "#"+room.Name, strconv.Itoa(len(room.Members)), "")
+	}
+	cl.reply(numListEnd, "End of LIST")
+}
+
+func (cl *client) listUsers() []*pb.User {
+	resp, err := cl.call("/proto.UserService/ListUsers", cl.creds, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return cl.srv.users.ListUsers(ctx, req.(*pb.Credentials))
+	})
+	if err != nil {
+		return nil
+	}
+	return resp.(*pb.ListUsersResponse).Users
+}
+
+func (cl *client) listRooms() []*pb.Room {
+	resp, err := cl.call("/proto.ChatService/ListRooms", cl.creds, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return cl.srv.chat.ListRooms(ctx, req.(*pb.Credentials))
+	})
+	if err != nil {
+		return nil
+	}
+	return resp.(*pb.ListRoomsResponse).Rooms
+}
+
+// mode answers mode queries. Modes cannot be changed.
+func (cl *client) mode(m *message) {
+	target := m.param(0)
+	switch {
+	case target == "":
+		cl.reply(numNeedMoreParams, m.command, "Not enough parameters")
+	case !strings.HasPrefix(target, "#"):
+		cl.reply(numUModeIs, "+")
+	case len(m.params) > 1:
+		cl.reply(numChanOPrivsNeeded, target, "Channel modes cannot be changed")
+	default:
+		cl.reply(numChannelModeIs, target, "+")
+	}
+}
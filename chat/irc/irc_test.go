@@ -0,0 +1,243 @@
+package irc_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/irc"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+type ircConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// newServer starts an IRC server for new user and chat services, calling
+// them through unary.
+func newServer(t *testing.T, unary ...grpc.UnaryServerInterceptor) (srv *irc.Server, addr string, users *user.Service, cs *chat.Service) {
+	us := storage.NewInMemoryUserStorage()
+	cs = chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, chat.DefaultSessionTimeout, logging.Discard())
+	users = user.NewService(cs, us, true, logging.Discard())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv = irc.NewServer(ln, users, cs, unary, nil, logging.Discard())
+	go srv.Serve()
+	return srv, ln.Addr().String(), users, cs
+}
+
+func dial(t *testing.T, addr, nick string) *ircConn {
+	return dialPass(t, addr, nick, "")
+}
+
+func dialPass(t *testing.T, addr, nick, pass string) *ircConn {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ic := &ircConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+	if pass != "" {
+		ic.send("PASS " + pass)
+	}
+	ic.send("NICK " + nick)
+	ic.send("USER " + nick + " 0 * :Test User")
+	ic.expect(" 001 " + nick + " ")
+	ic.expect(" 366 " + nick + " #chat ")
+	return ic
+}
+
+func (ic *ircConn) send(line string) {
+	if _, err := fmt.Fprintf(ic.conn, "%s\r\n", line); err != nil {
+		ic.t.Fatal(err)
+	}
+}
+
+// expect reads lines until one contains want and returns it.
+func (ic *ircConn) expect(want string) string {
+	ic.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		line, err := ic.r.ReadString('\n')
+		if err != nil {
+			ic.t.Fatalf("waiting for %q: %v", want, err)
+		}
+		if strings.Contains(line, want) {
+			return strings.TrimRight(line, "\r\n")
+		}
+	}
+}
+
+func TestIRC(t *testing.T) {
+	srv, addr, users, cs := newServer(t)
+	defer srv.Close()
+
+	alice := dial(t, addr, "alice")
+	defer alice.conn.Close()
+	bob := dial(t, addr, "bob")
+	defer bob.conn.Close()
+	alice.expect(":bob!bob@")
+
+	alice.send("PRIVMSG #chat :hello everyone")
+	if line := bob.expect("PRIVMSG #chat"); !strings.HasPrefix(line, ":alice!") || !strings.HasSuffix(line, ":hello everyone") {
+		t.Errorf("bob got %q", line)
+	}
+	alice.send("PRIVMSG bob :psst")
+	bob.expect("PRIVMSG bob :psst")
+	alice.send("PRIVMSG nobody :hi")
+	alice.expect(" 401 alice nobody ")
+
+	// Users of the other frontends
+	ctx := context.Background()
+	carol, err := users.Login(ctx, &pb.LoginRequest{Nick: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob.expect(":carol!carol@")
+	bob.send("NAMES #chat")
+	if line := bob.expect(" 353 bob = #chat "); !strings.Contains(line, "carol") {
+		t.Errorf("carol not in names: %q", line)
+	}
+	if _, err = cs.SendPublic(ctx, &pb.PublicMsgRequest{Creds: carol, Msg: "hi from grpc"}); err != nil {
+		t.Fatal(err)
+	}
+	bob.expect(":carol!carol@")
+
+	// Channels other than #chat are rooms
+	alice.send("JOIN #dev")
+	alice.expect("JOIN #dev")
+	alice.expect(" 366 alice #dev ")
+	bob.send("JOIN #dev")
+	alice.expect(":bob!bob@")
+	bob.send("PRIVMSG #dev :in the room")
+	alice.expect("PRIVMSG #dev :in the room")
+	rooms, err := cs.ListRooms(ctx, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms.Rooms) != 1 || rooms.Rooms[0].Name != "dev" || len(rooms.Rooms[0].Members) != 2 {
+		t.Errorf("got rooms %v, want dev with two members", rooms.Rooms)
+	}
+
+	bob.send("QUIT :bye")
+	if line := alice.expect("QUIT"); !strings.HasPrefix(line, ":bob!") {
+		t.Errorf("alice got %q", line)
+	}
+}
+
+func TestIRCNicks(t *testing.T) {
+	srv, addr, users, cs := newServer(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	alice := dial(t, addr, "alice")
+	defer alice.conn.Close()
+	// Nicks IRC does not allow are escaped, so they differ from the nicks
+	// they would otherwise be shown as
+	spaced, err := users.Login(ctx, &pb.LoginRequest{Nick: "dave smith"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice.expect(":dave%20smith!dave%20smith@")
+	underscored, err := users.Login(ctx, &pb.LoginRequest{Nick: "dave_smith"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice.expect(":dave_smith!dave_smith@")
+	if _, err = cs.SendPublic(ctx, &pb.PublicMsgRequest{Creds: spaced, Msg: "from the spaced nick"}); err != nil {
+		t.Fatal(err)
+	}
+	if line := alice.expect("PRIVMSG #chat"); !strings.HasPrefix(line, ":dave%20smith!") {
+		t.Errorf("alice got %q", line)
+	}
+	if _, err = cs.SendPublic(ctx, &pb.PublicMsgRequest{Creds: underscored, Msg: "from the underscored nick"}); err != nil {
+		t.Fatal(err)
+	}
+	if line := alice.expect("PRIVMSG #chat"); !strings.HasPrefix(line, ":dave_smith!") {
+		t.Errorf("alice got %q", line)
+	}
+	alice.send("PRIVMSG dave%20smith :hi")
+	alice.send("PRIVMSG dave%2 :hi")
+	if line := alice.expect(" 401 alice "); !strings.Contains(line, " dave%2 ") {
+		t.Errorf("alice got %q", line)
+	}
+	resp, err := cs.GetHistory(ctx, &pb.HistoryRequest{Creds: spaced, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := resp.Msgs[len(resp.Msgs)-1].GetPrivateMsg()
+	if last.GetMsg() != "hi" || last.GetTo() != "dave smith" {
+		t.Errorf("got last message %v, want the private message from alice", resp.Msgs[len(resp.Msgs)-1])
+	}
+	alice.send("NICK erin%20x")
+	alice.expect(" 432 alice erin%20x ")
+
+	// Only what the IRC client sent itself is not sent back to it
+	if _, err = users.Register(ctx, &pb.RegisterRequest{Nick: "erin", Password: "password1"}); err != nil {
+		t.Fatal(err)
+	}
+	erin := dialPass(t, addr, "erin", "password1")
+	defer erin.conn.Close()
+	erinGRPC, err := users.Login(ctx, &pb.LoginRequest{Nick: "erin", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	erin.send("PRIVMSG #chat :from irc")
+	if _, err = cs.SendPublic(ctx, &pb.PublicMsgRequest{Creds: erinGRPC, Msg: "from grpc"}); err != nil {
+		t.Fatal(err)
+	}
+	if line := erin.expect("PRIVMSG #chat"); !strings.HasSuffix(line, ":from grpc") {
+		t.Errorf("erin got %q, want only the message sent from another client", line)
+	}
+	erin.send("PRIVMSG erin :note to self")
+	if _, err = cs.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: erinGRPC, To: "erin", Msg: "other note"}); err != nil {
+		t.Fatal(err)
+	}
+	if line := erin.expect("PRIVMSG erin"); !strings.HasSuffix(line, ":other note") {
+		t.Errorf("erin got %q, want only the message sent from another client", line)
+	}
+}
+
+func TestIRCJoinRefused(t *testing.T) {
+	refuse := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if info.FullMethod == "/proto.ChatService/JoinRoom" {
+			return nil, status.Error(codes.ResourceExhausted, "slow down")
+		}
+		return handler(ctx, req)
+	}
+	srv, addr, users, cs := newServer(t, refuse)
+	defer srv.Close()
+
+	alice := dial(t, addr, "alice")
+	defer alice.conn.Close()
+	// Only joining a room that does not exist creates it
+	alice.send("JOIN #dev")
+	if line := alice.expect(" 403 alice #dev "); !strings.HasSuffix(line, ":slow down") {
+		t.Errorf("alice got %q", line)
+	}
+	carol, err := users.Login(context.Background(), &pb.LoginRequest{Nick: "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rooms, err := cs.ListRooms(context.Background(), carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rooms.Rooms) != 0 {
+		t.Errorf("got rooms %v, want none", rooms.Rooms)
+	}
+}
@@ -0,0 +1,151 @@
+package irc
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Longest text sent in one message, leaving room for the prefix and target
+// within the 512 bytes of an IRC line.
+const maxTextLen = 400
+
+// message is a line of the IRC protocol, see RFC 2812 section 2.3.
+type message struct {
+	prefix  string
+	command string
+	params  []string
+}
+
+// parseMessage parses line, without the line ending. IRCv3 tags are
+// ignored.
+func parseMessage(line string) (*message, bool) {
+	if strings.HasPrefix(line, "@") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return nil, false
+		}
+		line = strings.TrimLeft(line[i:], " ")
+	}
+	m := new(message)
+	if strings.HasPrefix(line, ":") {
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			return nil, false
+		}
+		m.prefix, line = line[1:i], strings.TrimLeft(line[i:], " ")
+	}
+	for line != "" {
+		if line[0] == ':' {
+			m.params = append(m.params, line[1:])
+			break
+		}
+		i := strings.IndexByte(line, ' ')
+		if i < 0 {
+			i = len(line)
+		}
+		m.params = append(m.params, line[:i])
+		line = strings.TrimLeft(line[i:], " ")
+	}
+	if len(m.params) == 0 {
+		return nil, false
+	}
+	m.command, m.params = strings.ToUpper(m.params[0]), m.params[1:]
+	return m, true
+}
+
+func (m *message) param(i int) string {
+	if i >= len(m.params) {
+		return ""
+	}
+	return m.params[i]
+}
+
+func (m *message) String() string {
+	var b bytes.Buffer
+	if m.prefix != "" {
+		b.WriteString(":" + m.prefix + " ")
+	}
+	b.WriteString(m.command)
+	for i, p := range m.params {
+		b.WriteByte(' ')
+		// The last of several parameters is usually text, mark it as such
+		// even when it need not be
+		if i == len(m.params)-1 && (i > 0 || p == "" || p[0] == ':' || strings.IndexByte(p, ' ') >= 0) {
+			b.WriteByte(':')
+		}
+		b.WriteString(p)
+	}
+	return b.String()
+}
+
+// splitText splits text into lines short enough for a message each.
+func splitText(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		for len(line) > maxTextLen {
+			i := maxTextLen
+			for i > 0 && !utf8.RuneStart(line[i]) {
+				i--
+			}
+			lines = append(lines, line[:i])
+			line = line[i:]
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// ircNick returns nick with the characters IRC does not allow in nicks, and
+// the escape character itself, escaped as %XX. Leading channel prefixes are
+// escaped too. chatNick reverses it.
+func ircNick(nick string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(nick); i++ {
+		ch := nick[i]
+		if strings.IndexByte(" ,*?!@:%\r\n\x00", ch) >= 0 || i == 0 && (ch == '#' || ch == '&') {
+			fmt.Fprintf(&b, "%%%02X", ch)
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
+
+// chatNick returns the chat nick shown as nick over IRC, and false if no
+// chat nick is shown as nick.
+func chatNick(nick string) (string, bool) {
+	if strings.IndexByte(nick, '%') < 0 {
+		return nick, validNick(nick)
+	}
+	var b bytes.Buffer
+	for i := 0; i < len(nick); i++ {
+		if nick[i] != '%' {
+			b.WriteByte(nick[i])
+			continue
+		}
+		if i+3 > len(nick) {
+			return "", false
+		}
+		ch, err := strconv.ParseUint(nick[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", false
+		}
+		b.WriteByte(byte(ch))
+		i += 2
+	}
+	// Only the escapes ircNick makes
+	if ircNick(b.String()) != nick {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// validNick reports whether nick can be used over IRC, that is, whether it
+// is shown as itself.
+func validNick(nick string) bool {
+	return nick != "" && ircNick(nick) == nick
+}
@@ -0,0 +1,146 @@
+// Package irc lets IRC clients use the chat server. The public chat is the
+// #chat channel, rooms are the other channels and private messages are sent
+// to nicks, so IRC users and users of other clients see each other's
+// messages. Login and logout events show as joining #chat and quitting.
+package irc
+
+import (
+	"bufio"
+	"crypto/tls"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+)
+
+const (
+	// The server pings clients this often.
+	pingInterval = time.Minute
+	// Clients that send nothing for readTimeout, not even a PONG, are
+	// disconnected.
+	readTimeout  = 2 * pingInterval
+	writeTimeout = 10 * time.Second
+	// Longest line accepted, IRCv3 tags included.
+	maxLineLen = 8192
+)
+
+// Server serves IRC clients, calling the user and chat services through the
+// same interceptors as gRPC requests. See RFC 2812 for the protocol.
+type Server struct {
+	ln     net.Listener
+	users  pb.UserServiceServer
+	chat   pb.ChatServiceServer
+	unary  []grpc.UnaryServerInterceptor
+	stream []grpc.StreamServerInterceptor
+	name   string // Of the server, as shown to clients
+	log    *logging.Logger
+
+	mu      sync.Mutex // Protects clients
+	clients map[*client]bool
+}
+
+func NewServer(ln net.Listener, users pb.UserServiceServer, chat pb.ChatServiceServer, unary []grpc.UnaryServerInterceptor, stream []grpc.StreamServerInterceptor, logger *logging.Logger) *Server {
+	name, err := os.Hostname()
+	if err != nil || strings.IndexByte(name, ' ') >= 0 {
+		name = "chat"
+	}
+	return &Server{
+		ln:      ln,
+		users:   users,
+		chat:    chat,
+		unary:   unary,
+		stream:  stream,
+		name:    name,
+		log:     logger,
+		clients: make(map[*client]bool),
+	}
+}
+
+// Serve accepts connections until the listener is closed.
+func (s *Server) Serve() error {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serve(conn)
+	}
+}
+
+// Close stops accepting connections and disconnects all clients.
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for cl := range s.clients {
+		cl.close()
+	}
+	return err
+}
+
+func (s *Server) serve(conn net.Conn) {
+	p := &peer.Peer{Addr: conn.RemoteAddr()}
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn.SetDeadline(time.Now().Add(writeTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			s.log.Debug("irc TLS handshake failed", "peer", conn.RemoteAddr(), "err", err)
+			conn.Close()
+			return
+		}
+		conn.SetDeadline(time.Time{})
+		p.AuthInfo = credentials.TLSInfo{State: tlsConn.ConnectionState()}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cl := &client{
+		srv:      s,
+		conn:     conn,
+		ctx:      peer.NewContext(ctx, p),
+		cancel:   cancel,
+		channels: make(map[string]bool),
+		echoes:   make(map[echo]int),
+	}
+	s.mu.Lock()
+	s.clients[cl] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, cl)
+		s.mu.Unlock()
+		cl.close()
+	}()
+	s.log.WithContext(cl.ctx).Debug("irc client connected")
+	go cl.ping()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 512), maxLineLen)
+	for {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		if !scanner.Scan() {
+			break
+		}
+		m, ok := parseMessage(strings.TrimRight(scanner.Text(), "\r"))
+		if !ok {
+			continue
+		}
+		if !cl.handle(m) {
+			break
+		}
+	}
+	cl.logout()
+	s.log.WithContext(cl.ctx).Debug("irc client disconnected", "err", scanner.Err())
+}
+
+// userPrefix returns the prefix of messages from nick.
+func (s *Server) userPrefix(nick string) string {
+	nick = ircNick(nick)
+	return nick + "!" + nick + "@" + s.name
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+)
+
+// Interceptor adds the RPC method, peer address and nick of each request to
+// its context, for loggers derived with WithContext, and logs the outcome
+// of every request.
+type Interceptor struct {
+	log *Logger
+}
+
+func NewInterceptor(log *Logger) *Interceptor {
+	return &Interceptor{log: log}
+}
+
+func (i *Interceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	ctx = withRequest(ctx, info.FullMethod, req)
+	resp, err := handler(ctx, req)
+	i.logResult(ctx, start, err)
+	return resp, err
+}
+
+func (i *Interceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	stream := &loggedStream{
+		ServerStream: ss,
+		ctx:          withRequest(ss.Context(), info.FullMethod, nil),
+	}
+	err := handler(srv, stream)
+	i.logResult(stream.ctx, start, err)
+	return err
+}
+
+func (i *Interceptor) logResult(ctx context.Context, start time.Time, err error) {
+	log := i.log.WithContext(ctx)
+	code := status.Code(err)
+	switch code {
+	case codes.OK:
+		log.Debug("request handled", "duration", time.Since(start))
+	case codes.Internal, codes.DataLoss:
+		log.Warn("request failed", "duration", time.Since(start), "code", code, "err", err)
+	default:
+		log.Debug("request failed", "duration", time.Since(start), "code", code, "err", err)
+	}
+}
+
+// loggedStream adds the nick to the context once the request of a server
+// streaming call is received.
+type loggedStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	received bool
+}
+
+func (s *loggedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if !s.received {
+		s.received = true
+		if nick := requestNick(m); nick != "" {
+			s.ctx = ContextWith(s.ctx, "nick", nick)
+		}
+	}
+	return nil
+}
+
+func withRequest(ctx context.Context, method string, req interface{}) context.Context {
+	keyvals := []interface{}{"method", method}
+	if p, ok := peer.FromContext(ctx); ok {
+		keyvals = append(keyvals, "peer", p.Addr)
+	}
+	if nick := requestNick(req); nick != "" {
+		keyvals = append(keyvals, "nick", nick)
+	}
+	return ContextWith(ctx, keyvals...)
+}
+
+// requestNick returns the nick a request claims to be from.
+func requestNick(req interface{}) string {
+	if creds := c.RequestCreds(req); creds != nil {
+		return creds.Nick
+	}
+	if r, ok := req.(interface {
+		GetNick() string
+	}); ok {
+		return r.GetNick()
+	}
+	return ""
+}
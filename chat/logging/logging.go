@@ -0,0 +1,242 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < 0 || int(l) >= len(levelNames) {
+		return "level(" + strconv.Itoa(int(l)) + ")"
+	}
+	return levelNames[l]
+}
+
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return Level(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+type Format int
+
+const (
+	FormatLogfmt Format = iota
+	FormatJSON
+)
+
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "logfmt":
+		return FormatLogfmt, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return 0, fmt.Errorf("unknown log format %q", s)
+}
+
+// Logger writes leveled log entries with key-value fields, one entry per
+// line. Loggers derived by With share the output of their parent.
+type Logger struct {
+	out    *output
+	fields []interface{} // Alternating keys and values
+}
+
+type output struct {
+	mu     sync.Mutex // Serializes writes to w
+	w      io.Writer
+	format Format
+	level  Level
+}
+
+func New(w io.Writer, format Format, level Level) *Logger {
+	return &Logger{out: &output{w: w, format: format, level: level}}
+}
+
+// Discard returns a logger that writes nothing.
+func Discard() *Logger {
+	return New(ioutil.Discard, FormatLogfmt, LevelError+1)
+}
+
+// With returns a logger that adds the key-value pairs keyvals to every entry.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	if len(keyvals) == 0 {
+		return l
+	}
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{out: l.out, fields: fields}
+}
+
+// WithContext returns a logger that adds the request fields stored in ctx
+// by ContextWith.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	return l.With(contextFields(ctx)...)
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+// Fatal logs at error level and exits.
+func (l *Logger) Fatal(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+	os.Exit(1)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	kvs := make([]interface{}, 0, 6+len(l.fields)+len(keyvals))
+	kvs = append(kvs, "time", time.Now().UTC().Format(time.RFC3339Nano), "level", level, "msg", msg)
+	kvs = append(kvs, l.fields...)
+	kvs = append(kvs, keyvals...)
+	if len(kvs)%2 != 0 {
+		kvs = append(kvs, "(missing)")
+	}
+
+	var buf bytes.Buffer
+	if l.out.format == FormatJSON {
+		writeJSON(&buf, kvs)
+	} else {
+		writeLogfmt(&buf, kvs)
+	}
+	buf.WriteByte('\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(buf.Bytes())
+}
+
+func writeLogfmt(buf *bytes.Buffer, kvs []interface{}) {
+	for i := 0; i < len(kvs); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(logfmtKey(kvs[i]))
+		buf.WriteByte('=')
+		s := stringValue(kvs[i+1])
+		if s == "" || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, isControl) >= 0 {
+			s = strconv.Quote(s)
+		}
+		buf.WriteString(s)
+	}
+}
+
+func logfmtKey(key interface{}) string {
+	s := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, fmt.Sprint(key))
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+func isControl(r rune) bool {
+	return r < ' ' || r == 0x7f
+}
+
+func writeJSON(buf *bytes.Buffer, kvs []interface{}) {
+	buf.WriteByte('{')
+	for i := 0; i < len(kvs); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(kvs[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(jsonValue(kvs[i+1]))
+	}
+	buf.WriteByte('}')
+}
+
+func jsonValue(v interface{}) []byte {
+	switch v := v.(type) {
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		if b, err := json.Marshal(v); err == nil {
+			return b
+		}
+	}
+	b, _ := json.Marshal(stringValue(v))
+	return b
+}
+
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(v)
+}
+
+type contextKey struct{}
+
+// ContextWith returns a copy of ctx carrying the key-value pairs keyvals in
+// addition to those already in ctx, to be added to log entries by
+// Logger.WithContext.
+func ContextWith(ctx context.Context, keyvals ...interface{}) context.Context {
+	fields := contextFields(ctx)
+	all := make([]interface{}, 0, len(fields)+len(keyvals))
+	all = append(all, fields...)
+	all = append(all, keyvals...)
+	return context.WithValue(ctx, contextKey{}, all)
+}
+
+func contextFields(ctx context.Context) []interface{} {
+	fields, _ := ctx.Value(contextKey{}).([]interface{})
+	return fields
+}
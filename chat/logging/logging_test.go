@@ -0,0 +1,84 @@
+package logging_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/tormoder/chat/logging"
+)
+
+func TestLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	log := logging.New(&buf, logging.FormatLogfmt, logging.LevelInfo)
+
+	log.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug entry written at info level: %q", buf.String())
+	}
+
+	ctx := logging.ContextWith(context.Background(), "method", "/proto.ChatService/SendPublic")
+	ctx = logging.ContextWith(ctx, "nick", "bob")
+	log.With("component", "chat").WithContext(ctx).Warn(
+		"send failed",
+		"err", errors.New(`queue "full"`),
+		"took", 1500*time.Millisecond,
+		"empty", "",
+	)
+
+	line := buf.String()
+	if !strings.HasPrefix(line, "time=") || !strings.HasSuffix(line, "\n") {
+		t.Errorf("malformed entry: %q", line)
+	}
+	want := ` level=warn msg="send failed" component=chat method=/proto.ChatService/SendPublic nick=bob err="queue \"full\"" took=1.5s empty=""` + "\n"
+	if !strings.HasSuffix(line, want) {
+		t.Errorf("got %q, want suffix %q", line, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	log := logging.New(&buf, logging.FormatJSON, logging.LevelDebug)
+	log.With("nick", "alice").Debug("logged in", "sessions", 2, "resumed", true, "odd")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"level":    "debug",
+		"msg":      "logged in",
+		"nick":     "alice",
+		"sessions": 2.0,
+		"resumed":  true,
+		"odd":      "(missing)",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s: got %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, entry["time"].(string)); err != nil {
+		t.Errorf("time: %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if level, err := logging.ParseLevel("WARN"); err != nil || level != logging.LevelWarn {
+		t.Errorf("ParseLevel(WARN): got %v, %v", level, err)
+	}
+	if _, err := logging.ParseLevel("verbose"); err == nil {
+		t.Error("ParseLevel(verbose): no error")
+	}
+	if format, err := logging.ParseFormat("json"); err != nil || format != logging.FormatJSON {
+		t.Errorf("ParseFormat(json): got %v, %v", format, err)
+	}
+	if _, err := logging.ParseFormat("xml"); err == nil {
+		t.Error("ParseFormat(xml): no error")
+	}
+}
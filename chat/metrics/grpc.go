@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// RPCMetrics counts gRPC requests by method and status code, and times
+// unary requests. Streams last as long as a client listens, so they are only
+// counted.
+type RPCMetrics struct {
+	requests *CounterVec
+	latency  *HistogramVec
+}
+
+func NewRPCMetrics(r *Registry) *RPCMetrics {
+	return &RPCMetrics{
+		requests: r.NewCounterVec(
+			"chat_rpc_requests_total",
+			"RPC requests handled, by method and status code.",
+			"method", "code",
+		),
+		latency: r.NewHistogramVec(
+			"chat_rpc_duration_seconds",
+			"Time to handle unary RPC requests, by method.",
+			DefBuckets,
+			"method",
+		),
+	}
+}
+
+func (m *RPCMetrics) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.latency.With(info.FullMethod).Observe(time.Since(start).Seconds())
+	m.requests.With(info.FullMethod, status.Code(err).String()).Inc()
+	return resp, err
+}
+
+func (m *RPCMetrics) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := handler(srv, ss)
+	m.requests.With(info.FullMethod, status.Code(err).String()).Inc()
+	return err
+}
@@ -0,0 +1,329 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default histogram buckets, in seconds.
+var DefBuckets = []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds metrics and serves them in the Prometheus text exposition
+// format.
+type Registry struct {
+	mu      sync.Mutex // Protects metrics
+	metrics map[string]family
+}
+
+type metric interface {
+	write(w io.Writer, name string)
+}
+
+type family struct {
+	help, typ string
+	metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]family)}
+}
+
+func (r *Registry) register(name, help, typ string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, found := r.metrics[name]; found {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.metrics[name] = family{help: help, typ: typ, metric: m}
+}
+
+// Write writes all metrics, sorted by name.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	families := make([]family, len(names))
+	for i, name := range names {
+		families[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	for i, name := range names {
+		f := families[i]
+		fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(f.help))
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, f.typ)
+		f.write(w, name)
+	}
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	r.Write(bw)
+	bw.Flush()
+}
+
+type Counter struct {
+	n uint64 // Accessed atomically
+}
+
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	r.register(name, help, "counter", c)
+	return c
+}
+
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.n, 1)
+}
+
+func (c *Counter) Add(n uint64) {
+	atomic.AddUint64(&c.n, n)
+}
+
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+func (c *Counter) write(w io.Writer, name string) {
+	fmt.Fprintf(w, "%s %d\n", name, c.Value())
+}
+
+// CounterVec is a set of counters partitioned by label values.
+type CounterVec struct {
+	labels []string
+
+	mu       sync.Mutex // Protects counters
+	counters map[string]*Counter
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{labels: labels, counters: make(map[string]*Counter)}
+	r.register(name, help, "counter", v)
+	return v
+}
+
+// With returns the counter for the label values, in the order the labels
+// were given.
+func (v *CounterVec) With(values ...string) *Counter {
+	key := formatLabels(v.labels, values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, found := v.counters[key]
+	if !found {
+		c = &Counter{}
+		v.counters[key] = c
+	}
+	return c
+}
+
+func (v *CounterVec) write(w io.Writer, name string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.counters))
+	for key := range v.counters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%s%s %d\n", name, key, v.counters[key].Value())
+	}
+}
+
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex // Protects the fields below
+	counts []uint64   // Per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(buckets)
+	r.register(name, help, "histogram", h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) write(w io.Writer, name string) {
+	h.writeLabeled(w, name, nil, nil)
+}
+
+func (h *Histogram) writeLabeled(w io.Writer, name string, labels, values []string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	le := append(labels[:len(labels):len(labels)], "le")
+	leValues := func(bound string) []string {
+		return append(values[:len(values):len(values)], bound)
+	}
+	var cumulative uint64
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(le, leValues(formatFloat(bound))), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(le, leValues("+Inf")), h.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(labels, values), formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(labels, values), h.count)
+}
+
+// HistogramVec is a set of histograms partitioned by label values.
+type HistogramVec struct {
+	labels  []string
+	buckets []float64
+
+	mu         sync.Mutex // Protects histograms and values
+	histograms map[string]*Histogram
+	values     map[string][]string
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	v := &HistogramVec{
+		labels:     labels,
+		buckets:    buckets,
+		histograms: make(map[string]*Histogram),
+		values:     make(map[string][]string),
+	}
+	r.register(name, help, "histogram", v)
+	return v
+}
+
+func (v *HistogramVec) With(values ...string) *Histogram {
+	key := formatLabels(v.labels, values)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	h, found := v.histograms[key]
+	if !found {
+		h = newHistogram(v.buckets)
+		v.histograms[key] = h
+		v.values[key] = values
+	}
+	return h
+}
+
+func (v *HistogramVec) write(w io.Writer, name string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	keys := make([]string, 0, len(v.histograms))
+	for key := range v.histograms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v.histograms[key].writeLabeled(w, name, v.labels, v.values[key])
+	}
+}
+
+// funcMetric reports the values returned by a function when scraped, keyed
+// by the value of its label.
+type funcMetric struct {
+	label string
+	f     func() map[string]float64
+}
+
+// NewGaugeFunc registers a gauge whose value is returned by f.
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	r.register(name, help, "gauge", funcMetric{f: func() map[string]float64 {
+		return map[string]float64{"": f()}
+	}})
+}
+
+// NewCounterFunc registers a counter whose value is returned by f.
+func (r *Registry) NewCounterFunc(name, help string, f func() float64) {
+	r.register(name, help, "counter", funcMetric{f: func() map[string]float64 {
+		return map[string]float64{"": f()}
+	}})
+}
+
+// NewGaugeVecFunc registers a gauge with one label, f returns the value for
+// each label value.
+func (r *Registry) NewGaugeVecFunc(name, help, label string, f func() map[string]float64) {
+	r.register(name, help, "gauge", funcMetric{label: label, f: f})
+}
+
+// NewCounterVecFunc registers a counter with one label, f returns the value
+// for each label value.
+func (r *Registry) NewCounterVecFunc(name, help, label string, f func() map[string]float64) {
+	r.register(name, help, "counter", funcMetric{label: label, f: f})
+}
+
+func (m funcMetric) write(w io.Writer, name string) {
+	values := m.f()
+	if m.label == "" {
+		fmt.Fprintf(w, "%s %s\n", name, formatFloat(values[""]))
+		return
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		labels := formatLabels([]string{m.label}, []string{key})
+		fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(values[key]))
+	}
+}
+
+func formatLabels(labels, values []string) string {
+	if len(labels) != len(values) {
+		panic(fmt.Sprintf("metrics: got %d label values, want %d", len(values), len(labels)))
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = label + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tormoder/chat/metrics"
+)
+
+func TestRegistry(t *testing.T) {
+	reg := metrics.NewRegistry()
+	logins := reg.NewCounter("logins_total", "Logins.")
+	sent := reg.NewCounterVec("sent_total", "Messages sent.", "type")
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "method")
+	reg.NewGaugeFunc("clients", "Connected clients.", func() float64 { return 2 })
+	reg.NewGaugeVecFunc("queue_length", "Queue length.", "nick", func() map[string]float64 {
+		return map[string]float64{"bob": 1, `a"b`: 3}
+	})
+
+	logins.Inc()
+	logins.Add(2)
+	sent.With("public").Inc()
+	sent.With("room").Add(4)
+	latency.With("/Send").Observe(0.05)
+	latency.With("/Send").Observe(0.5)
+	latency.With("/Send").Observe(5)
+
+	want := `# HELP clients Connected clients.
+# TYPE clients gauge
+clients 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{method="/Send",le="0.1"} 1
+latency_seconds_bucket{method="/Send",le="1"} 2
+latency_seconds_bucket{method="/Send",le="+Inf"} 3
+latency_seconds_sum{method="/Send"} 5.55
+latency_seconds_count{method="/Send"} 3
+# HELP logins_total Logins.
+# TYPE logins_total counter
+logins_total 3
+# HELP queue_length Queue length.
+# TYPE queue_length gauge
+queue_length{nick="a\"b"} 3
+queue_length{nick="bob"} 1
+# HELP sent_total Messages sent.
+# TYPE sent_total counter
+sent_total{type="public"} 1
+sent_total{type="room"} 4
+`
+	var buf bytes.Buffer
+	reg.Write(&buf)
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("content type: got %q, want text/plain", ct)
+	}
+	if rec.Body.String() != want {
+		t.Errorf("served metrics differ from written metrics:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,331 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tormoder/chat/chat"
+	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+var (
+	errNotPermitted = status.Error(codes.PermissionDenied, "not permitted")
+	errUserNotFound = errors.New("requested user not found")
+	errNotOnline    = errors.New("user not logged-in")
+	errBanTarget    = errors.New("exactly one of nick and address must be set")
+	errBadAddr      = errors.New("invalid IP address")
+	errBadDuration  = errors.New("invalid duration")
+	errNoSuchBan    = errors.New("no such ban")
+	errGuestRole    = status.Error(codes.FailedPrecondition, "only registered nicks can be given a role")
+)
+
+type Service struct {
+	storage storage.UserStorage
+	chat    *chat.Service
+	log     *logging.Logger
+}
+
+func NewService(chatService *chat.Service, userStorage storage.UserStorage, logger *logging.Logger) *Service {
+	return &Service{
+		storage: userStorage,
+		chat:    chatService,
+		log:     logger,
+	}
+}
+
+// GrantAdmin gives the registered nick the admin role. Guests cannot be
+// admins, as anyone could log in as them.
+func GrantAdmin(userStorage storage.UserStorage, nick string) error {
+	errNotRegistered := fmt.Errorf("nick %q not registered", nick)
+	_, err := userStorage.UpdateUser(nick, func(user *storage.User) error {
+		if !user.Registered() {
+			return errNotRegistered
+		}
+		user.Role = pb.User_ADMIN
+		return nil
+	})
+	if err == storage.ErrUserNotFound {
+		return errNotRegistered
+	}
+	return err
+}
+
+func (s *Service) Kick(ctx context.Context, modReq *pb.ModerationRequest) (*pb.ModerationResponse, error) {
+	s.log.WithContext(ctx).Debug("kick request", "target", modReq.Nick)
+	mod, err := s.moderator(modReq.GetCreds(), pb.User_MODERATOR)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = s.target(mod, modReq.Nick); err != nil {
+		return nil, err
+	}
+
+	if !s.chat.Kick(modReq.Nick, action("kicked", mod.Nick, modReq.Reason)) {
+		return nil, errNotOnline
+	}
+	s.log.WithContext(ctx).Info("kicked user", "target", modReq.Nick, "reason", modReq.Reason)
+
+	return &pb.ModerationResponse{}, nil
+}
+
+func (s *Service) Ban(ctx context.Context, banReq *pb.BanRequest) (*pb.ModerationResponse, error) {
+	s.log.WithContext(ctx).Debug("ban request", "target", banReq.Nick, "addr", banReq.Addr)
+	mod, err := s.moderator(banReq.GetCreds(), pb.User_MODERATOR)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := banAddr(banReq.Nick, banReq.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if banReq.Duration < 0 {
+		return nil, errBadDuration
+	}
+	if banReq.Nick != "" {
+		// Nicks not yet taken may be banned too
+		if _, err = s.target(mod, banReq.Nick); err != nil && err != errUserNotFound {
+			return nil, err
+		}
+	}
+
+	ban := &pb.Ban{
+		Nick:   banReq.Nick,
+		Addr:   addr,
+		Reason: banReq.Reason,
+		By:     mod.Nick,
+		ByRole: mod.Role,
+	}
+	if banReq.Duration > 0 {
+		ban.Expiry = time.Now().Add(time.Duration(banReq.Duration) * time.Second).Unix()
+	}
+	if err = s.storage.AddBan(ban); err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+
+	reason := action("banned", mod.Nick, banReq.Reason)
+	if banReq.Nick != "" {
+		s.chat.Kick(banReq.Nick, reason)
+	} else {
+		// Like the ban itself, the kick spares users with at least the
+		// moderator's role
+		for nick, ids := range s.chat.SessionsFrom(addr) {
+			if user, found := s.storage.GetUser(nick); !found || user.Role >= mod.Role {
+				continue
+			}
+			for _, id := range ids {
+				s.chat.KickSession(nick, id, reason)
+			}
+		}
+	}
+	s.log.WithContext(ctx).Info(
+		"banned",
+		"target", banReq.Nick,
+		"addr", addr,
+		"reason", banReq.Reason,
+		"duration", time.Duration(banReq.Duration)*time.Second,
+	)
+
+	return &pb.ModerationResponse{}, nil
+}
+
+func (s *Service) Unban(ctx context.Context, banReq *pb.BanRequest) (*pb.ModerationResponse, error) {
+	s.log.WithContext(ctx).Debug("unban request", "target", banReq.Nick, "addr", banReq.Addr)
+	_, err := s.moderator(banReq.GetCreds(), pb.User_MODERATOR)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := banAddr(banReq.Nick, banReq.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := s.storage.RemoveBan(banReq.Nick, addr)
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	if !removed {
+		return nil, errNoSuchBan
+	}
+	s.log.WithContext(ctx).Info("unbanned", "target", banReq.Nick, "addr", addr)
+
+	return &pb.ModerationResponse{}, nil
+}
+
+func (s *Service) ListBans(ctx context.Context, creds *pb.Credentials) (*pb.ListBansResponse, error) {
+	s.log.WithContext(ctx).Debug("list bans request")
+	_, err := s.moderator(creds, pb.User_MODERATOR)
+	if err != nil {
+		return nil, err
+	}
+	bans := s.storage.GetBans()
+	sort.Sort(byTarget(bans))
+	return &pb.ListBansResponse{
+		Bans: bans,
+	}, nil
+}
+
+func (s *Service) Mute(ctx context.Context, modReq *pb.ModerationRequest) (*pb.ModerationResponse, error) {
+	s.log.WithContext(ctx).Debug("mute request", "target", modReq.Nick)
+	mod, err := s.moderator(modReq.GetCreds(), pb.User_MODERATOR)
+	if err != nil {
+		return nil, err
+	}
+	if modReq.Duration < 0 {
+		return nil, errBadDuration
+	}
+	var expiry int64
+	if modReq.Duration > 0 {
+		expiry = time.Now().Add(time.Duration(modReq.Duration) * time.Second).Unix()
+	}
+	err = s.updateTarget(mod, modReq.Nick, func(user *storage.User) error {
+		user.Muted = true
+		user.MuteExpiry = expiry
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithContext(ctx).Info(
+		"muted user",
+		"target", modReq.Nick,
+		"reason", modReq.Reason,
+		"duration", time.Duration(modReq.Duration)*time.Second,
+	)
+
+	return &pb.ModerationResponse{}, nil
+}
+
+func (s *Service) Unmute(ctx context.Context, modReq *pb.ModerationRequest) (*pb.ModerationResponse, error) {
+	s.log.WithContext(ctx).Debug("unmute request", "target", modReq.Nick)
+	mod, err := s.moderator(modReq.GetCreds(), pb.User_MODERATOR)
+	if err != nil {
+		return nil, err
+	}
+	err = s.updateTarget(mod, modReq.Nick, func(user *storage.User) error {
+		user.Muted = false
+		user.MuteExpiry = 0
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithContext(ctx).Info("unmuted user", "target", modReq.Nick)
+
+	return &pb.ModerationResponse{}, nil
+}
+
+func (s *Service) SetRole(ctx context.Context, roleReq *pb.RoleRequest) (*pb.ModerationResponse, error) {
+	s.log.WithContext(ctx).Debug("set role request", "target", roleReq.Nick, "role", roleReq.Role)
+	admin, err := s.moderator(roleReq.GetCreds(), pb.User_ADMIN)
+	if err != nil {
+		return nil, err
+	}
+	if _, valid := pb.User_Role_name[int32(roleReq.Role)]; !valid {
+		return nil, errors.New("invalid role")
+	}
+	err = s.updateTarget(admin, roleReq.Nick, func(user *storage.User) error {
+		if roleReq.Role > pb.User_USER && !user.Registered() {
+			return errGuestRole
+		}
+		user.Role = roleReq.Role
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.log.WithContext(ctx).Info("set role", "target", roleReq.Nick, "role", roleReq.Role)
+
+	return &pb.ModerationResponse{}, nil
+}
+
+// moderator checks creds and that the user has at least role.
+func (s *Service) moderator(creds *pb.Credentials, role pb.User_Role) (storage.User, error) {
+	user, err := s.storage.CheckCredentials(creds)
+	if err != nil {
+		return user, err
+	}
+	if user.Role < role {
+		return user, errNotPermitted
+	}
+	return user, nil
+}
+
+// target returns the user nick if mod may act on it.
+func (s *Service) target(mod storage.User, nick string) (storage.User, error) {
+	user, found := s.storage.GetUser(nick)
+	if !found {
+		return user, errUserNotFound
+	}
+	if user.Role >= mod.Role {
+		return user, errNotPermitted
+	}
+	return user, nil
+}
+
+// updateTarget applies update to the user nick, atomically with checking
+// that mod may moderate it. Errors of update are returned as they are.
+func (s *Service) updateTarget(mod storage.User, nick string, update func(user *storage.User) error) error {
+	var updateErr error
+	_, err := s.storage.UpdateUser(nick, func(user *storage.User) error {
+		if user.Role >= mod.Role {
+			updateErr = errNotPermitted
+		} else {
+			updateErr = update(user)
+		}
+		return updateErr
+	})
+	switch {
+	case updateErr != nil:
+		return updateErr
+	case err == storage.ErrUserNotFound:
+		return errUserNotFound
+	case err != nil:
+		return c.InternalServerError("storage error")
+	}
+	return nil
+}
+
+// banAddr checks that exactly one of nick and addr is set and returns addr
+// in canonical form.
+func banAddr(nick, addr string) (string, error) {
+	if (nick == "") == (addr == "") {
+		return "", errBanTarget
+	}
+	if addr == "" {
+		return "", nil
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return "", errBadAddr
+	}
+	return ip.String(), nil
+}
+
+// action describes a moderator action for the affected user.
+func action(what, by, reason string) string {
+	if reason == "" {
+		return fmt.Sprintf("%s by %s", what, by)
+	}
+	return fmt.Sprintf("%s by %s: %s", what, by, reason)
+}
+
+type byTarget []*pb.Ban
+
+func (s byTarget) Len() int      { return len(s) }
+func (s byTarget) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byTarget) Less(i, j int) bool {
+	if s[i].Nick != s[j].Nick {
+		return s[i].Nick < s[j].Nick
+	}
+	return s[i].Addr < s[j].Addr
+}
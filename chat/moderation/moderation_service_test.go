@@ -0,0 +1,227 @@
+package moderation_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/logging"
+	"github.com/tormoder/chat/moderation"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs chan *pb.ChatServerMsg
+}
+
+func (s *fakeStream) Send(msg *pb.ChatServerMsg) error {
+	s.msgs <- msg
+	return nil
+}
+
+func (s *fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+type testServer struct {
+	t     *testing.T
+	users *user.Service
+	chat  *chat.Service
+	mod   *moderation.Service
+}
+
+const password = "secret password"
+
+func newTestServer(t *testing.T) *testServer {
+	us := storage.NewInMemoryUserStorage()
+	if err := moderation.GrantAdmin(us, "admin"); err == nil {
+		t.Error("unregistered nick made admin")
+	}
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, chat.DefaultSessionTimeout, logging.Discard())
+	ts := &testServer{
+		t:     t,
+		users: user.NewService(cs, us, true, logging.Discard()),
+		chat:  cs,
+		mod:   moderation.NewService(cs, us, logging.Discard()),
+	}
+	ts.register("admin")
+	if err := moderation.GrantAdmin(us, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func (ts *testServer) register(nick string) {
+	_, err := ts.users.Register(context.Background(), &pb.RegisterRequest{Nick: nick, Password: password})
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+}
+
+func fromAddr(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000},
+	})
+}
+
+// listen logs in nick from ip, with the password if pass is set, and returns
+// its listening stream once established, and a channel receiving the result
+// of ListenForMessages.
+func (ts *testServer) listen(nick string, pass bool, ip string) (*pb.Credentials, chan error) {
+	ctx := fromAddr(ip)
+	loginReq := &pb.LoginRequest{Nick: nick}
+	if pass {
+		loginReq.Password = password
+	}
+	creds, err := ts.users.Login(ctx, loginReq)
+	if err != nil {
+		ts.t.Fatal(err)
+	}
+	stream := &fakeStream{ctx: ctx, msgs: make(chan *pb.ChatServerMsg, 64)}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- ts.chat.ListenForMessages(creds, stream)
+	}()
+	select {
+	case <-stream.msgs:
+	case <-time.After(5 * time.Second):
+		ts.t.Fatal("no message on listening stream")
+	}
+	return creds, errc
+}
+
+func wantCode(t *testing.T, desc string, err error, code codes.Code) {
+	if status.Code(err) != code {
+		t.Errorf("%s: got %v, want %v", desc, err, code)
+	}
+}
+
+func streamEnded(t *testing.T, errc chan error) error {
+	select {
+	case err := <-errc:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("listening stream not ended")
+	}
+	return nil
+}
+
+func TestModeration(t *testing.T) {
+	ts := newTestServer(t)
+	ctx := context.Background()
+	ts.register("carol")
+	admin, _ := ts.listen("admin", true, "10.0.0.1")
+	carol, _ := ts.listen("carol", true, "10.0.0.2")
+	bob, bobErrc := ts.listen("bob", false, "10.0.0.3")
+
+	_, err := ts.mod.Kick(ctx, &pb.ModerationRequest{Creds: carol, Nick: "bob"})
+	wantCode(t, "kick by user", err, codes.PermissionDenied)
+	_, err = ts.mod.SetRole(ctx, &pb.RoleRequest{Creds: admin, Nick: "bob", Role: pb.User_MODERATOR})
+	wantCode(t, "role for guest", err, codes.FailedPrecondition)
+	_, err = ts.mod.SetRole(ctx, &pb.RoleRequest{Creds: admin, Nick: "carol", Role: pb.User_MODERATOR})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.mod.Kick(ctx, &pb.ModerationRequest{Creds: carol, Nick: "admin"})
+	wantCode(t, "kick of admin by moderator", err, codes.PermissionDenied)
+	_, err = ts.mod.SetRole(ctx, &pb.RoleRequest{Creds: carol, Nick: "bob", Role: pb.User_MODERATOR})
+	wantCode(t, "set role by moderator", err, codes.PermissionDenied)
+
+	_, err = ts.mod.Mute(ctx, &pb.ModerationRequest{Creds: carol, Nick: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ts.chat.SendPublic(ctx, &pb.PublicMsgRequest{Creds: bob, Msg: "spam"})
+	wantCode(t, "public message while muted", err, codes.PermissionDenied)
+	_, err = ts.chat.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: bob, To: "carol", Msg: "spam"})
+	wantCode(t, "private message while muted", err, codes.PermissionDenied)
+	_, err = ts.mod.Unmute(ctx, &pb.ModerationRequest{Creds: carol, Nick: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.chat.SendPublic(ctx, &pb.PublicMsgRequest{Creds: bob, Msg: "sorry"}); err != nil {
+		t.Errorf("public message after unmute: %v", err)
+	}
+
+	_, err = ts.mod.Kick(ctx, &pb.ModerationRequest{Creds: carol, Nick: "bob", Reason: "spam"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = streamEnded(t, bobErrc)
+	wantCode(t, "kicked stream", err, codes.PermissionDenied)
+	if _, err = ts.chat.GetStats(ctx, bob); err == nil {
+		t.Error("kicked user still logged in")
+	}
+
+	_, err = ts.mod.Ban(ctx, &pb.BanRequest{Creds: carol, Nick: "bob", Duration: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.users.Login(ctx, &pb.LoginRequest{Nick: "bob"}); err == nil {
+		t.Error("banned user logged in")
+	}
+	bans, err := ts.mod.ListBans(ctx, carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bans.Bans) != 1 || bans.Bans[0].Nick != "bob" || bans.Bans[0].By != "carol" || bans.Bans[0].Expiry == 0 {
+		t.Errorf("bans: got %v", bans.Bans)
+	}
+	_, err = ts.mod.Unban(ctx, &pb.BanRequest{Creds: carol, Nick: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errc := ts.listen("bob", false, "10.0.0.3")
+	// Not listening, so only the address logged in from is known
+	erin, err := ts.users.Login(fromAddr("10.0.0.3"), &pb.LoginRequest{Nick: "erin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ts.mod.Ban(ctx, &pb.BanRequest{Creds: admin, Addr: "10.0.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantCode(t, "stream from banned address", streamEnded(t, errc), codes.PermissionDenied)
+	if _, err = ts.chat.GetStats(ctx, erin); err == nil {
+		t.Error("session from banned address still valid")
+	}
+	if _, err = ts.users.Login(fromAddr("10.0.0.3"), &pb.LoginRequest{Nick: "dave"}); err == nil {
+		t.Error("user logged in from banned address")
+	}
+	if _, err = ts.users.Login(fromAddr("10.0.0.4"), &pb.LoginRequest{Nick: "dave"}); err != nil {
+		t.Errorf("login from other address: %v", err)
+	}
+	if _, err = ts.users.Login(fromAddr("10.0.0.3"), &pb.LoginRequest{Nick: "carol", Password: password}); err == nil {
+		t.Error("moderator logged in from address banned by admin")
+	}
+
+	// Address bans spare users with at least the banner's role
+	_, err = ts.mod.Ban(ctx, &pb.BanRequest{Creds: carol, Addr: "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.chat.GetStats(ctx, carol); err != nil {
+		t.Errorf("moderator's session from own banned address: %v", err)
+	}
+	if _, err = ts.users.Login(fromAddr("10.0.0.2"), &pb.LoginRequest{Nick: "carol", Password: password}); err != nil {
+		t.Errorf("moderator login from address banned by moderator: %v", err)
+	}
+	if _, err = ts.users.Login(fromAddr("10.0.0.2"), &pb.LoginRequest{Nick: "admin", Password: password}); err != nil {
+		t.Errorf("admin login from address banned by moderator: %v", err)
+	}
+	if _, err = ts.users.Login(fromAddr("10.0.0.2"), &pb.LoginRequest{Nick: "frank"}); err == nil {
+		t.Error("guest logged in from address banned by moderator")
+	}
+}
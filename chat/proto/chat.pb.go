@@ -1,31 +1,9 @@
-// Code generated by protoc-gen-go.
+// Code generated by protoc-gen-go. DO NOT EDIT.
 // source: chat.proto
-// DO NOT EDIT!
-
-/*
-Package proto is a generated protocol buffer package.
-
-It is generated from these files:
-	chat.proto
-
-It has these top-level messages:
-	LoginRequest
-	LogoutResponse
-	User
-	Credentials
-	ListUsersResponse
-	PrivateMsgRequest
-	PublicMsgRequest
-	SendMsgResponse
-	ChatServerMsg
-	PrivateMsg
-	PublicMsg
-	UserEvent
-	Heartbeat
-*/
+
 package proto
 
-import proto1 "github.com/golang/protobuf/proto"
+import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
 
@@ -35,509 +13,4248 @@ import (
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
-var _ = proto1.Marshal
+var _ = proto.Marshal
 var _ = fmt.Errorf
 var _ = math.Inf
 
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type User_Role int32
+
+const (
+	User_USER      User_Role = 0
+	User_MODERATOR User_Role = 1
+	User_ADMIN     User_Role = 2
+)
+
+var User_Role_name = map[int32]string{
+	0: "USER",
+	1: "MODERATOR",
+	2: "ADMIN",
+}
+var User_Role_value = map[string]int32{
+	"USER":      0,
+	"MODERATOR": 1,
+	"ADMIN":     2,
+}
+
+func (x User_Role) String() string {
+	return proto.EnumName(User_Role_name, int32(x))
+}
+func (User_Role) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{5, 0}
+}
+
+type Presence_Status int32
+
+const (
+	Presence_OFFLINE   Presence_Status = 0
+	Presence_ONLINE    Presence_Status = 1
+	Presence_AWAY      Presence_Status = 2
+	Presence_BUSY      Presence_Status = 3
+	Presence_INVISIBLE Presence_Status = 4
+)
+
+var Presence_Status_name = map[int32]string{
+	0: "OFFLINE",
+	1: "ONLINE",
+	2: "AWAY",
+	3: "BUSY",
+	4: "INVISIBLE",
+}
+var Presence_Status_value = map[string]int32{
+	"OFFLINE":   0,
+	"ONLINE":    1,
+	"AWAY":      2,
+	"BUSY":      3,
+	"INVISIBLE": 4,
+}
+
+func (x Presence_Status) String() string {
+	return proto.EnumName(Presence_Status_name, int32(x))
+}
+func (Presence_Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{6, 0}
+}
+
+type SendMsgResponse_Status int32
+
+const (
+	SendMsgResponse_UNKNOWN   SendMsgResponse_Status = 0
+	SendMsgResponse_DELIVERED SendMsgResponse_Status = 1
+	SendMsgResponse_QUEUED    SendMsgResponse_Status = 2
+	SendMsgResponse_DROPPED   SendMsgResponse_Status = 3
+	SendMsgResponse_RELAYED   SendMsgResponse_Status = 4
+)
+
+var SendMsgResponse_Status_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "DELIVERED",
+	2: "QUEUED",
+	3: "DROPPED",
+	4: "RELAYED",
+}
+var SendMsgResponse_Status_value = map[string]int32{
+	"UNKNOWN":   0,
+	"DELIVERED": 1,
+	"QUEUED":    2,
+	"DROPPED":   3,
+	"RELAYED":   4,
+}
+
+func (x SendMsgResponse_Status) String() string {
+	return proto.EnumName(SendMsgResponse_Status_name, int32(x))
+}
+func (SendMsgResponse_Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{28, 0}
+}
+
 type UserEvent_EventType int32
 
 const (
-	UserEvent_UNKNOWN UserEvent_EventType = 0
-	UserEvent_LOGIN   UserEvent_EventType = 1
-	UserEvent_LOGOUT  UserEvent_EventType = 2
+	UserEvent_UNKNOWN  UserEvent_EventType = 0
+	UserEvent_LOGIN    UserEvent_EventType = 1
+	UserEvent_LOGOUT   UserEvent_EventType = 2
+	UserEvent_JOIN     UserEvent_EventType = 3
+	UserEvent_LEAVE    UserEvent_EventType = 4
+	UserEvent_PRESENCE UserEvent_EventType = 5
+	UserEvent_KICK     UserEvent_EventType = 6
 )
 
 var UserEvent_EventType_name = map[int32]string{
 	0: "UNKNOWN",
 	1: "LOGIN",
 	2: "LOGOUT",
+	3: "JOIN",
+	4: "LEAVE",
+	5: "PRESENCE",
+	6: "KICK",
 }
 var UserEvent_EventType_value = map[string]int32{
-	"UNKNOWN": 0,
-	"LOGIN":   1,
-	"LOGOUT":  2,
+	"UNKNOWN":  0,
+	"LOGIN":    1,
+	"LOGOUT":   2,
+	"JOIN":     3,
+	"LEAVE":    4,
+	"PRESENCE": 5,
+	"KICK":     6,
 }
 
 func (x UserEvent_EventType) String() string {
-	return proto1.EnumName(UserEvent_EventType_name, int32(x))
+	return proto.EnumName(UserEvent_EventType_name, int32(x))
 }
-
-type LoginRequest struct {
-	Nick string `protobuf:"bytes,1,opt,name=nick" json:"nick,omitempty"`
+func (UserEvent_EventType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{43, 0}
 }
 
-func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
-func (m *LoginRequest) String() string { return proto1.CompactTextString(m) }
-func (*LoginRequest) ProtoMessage()    {}
-
-type LogoutResponse struct {
-}
+type Receipt_Type int32
 
-func (m *LogoutResponse) Reset()         { *m = LogoutResponse{} }
-func (m *LogoutResponse) String() string { return proto1.CompactTextString(m) }
-func (*LogoutResponse) ProtoMessage()    {}
+const (
+	Receipt_UNKNOWN   Receipt_Type = 0
+	Receipt_DELIVERED Receipt_Type = 1
+	Receipt_READ      Receipt_Type = 2
+)
 
-type User struct {
-	Nick         string `protobuf:"bytes,1,opt,name=nick" json:"nick,omitempty"`
-	TimeLastSeen int64  `protobuf:"varint,3,opt,name=time_last_seen" json:"time_last_seen,omitempty"`
+var Receipt_Type_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "DELIVERED",
+	2: "READ",
+}
+var Receipt_Type_value = map[string]int32{
+	"UNKNOWN":   0,
+	"DELIVERED": 1,
+	"READ":      2,
 }
 
-func (m *User) Reset()         { *m = User{} }
-func (m *User) String() string { return proto1.CompactTextString(m) }
-func (*User) ProtoMessage()    {}
-
-type Credentials struct {
-	Nick  string `protobuf:"bytes,1,opt,name=nick" json:"nick,omitempty"`
-	Token []byte `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+func (x Receipt_Type) String() string {
+	return proto.EnumName(Receipt_Type_name, int32(x))
+}
+func (Receipt_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{45, 0}
 }
 
-func (m *Credentials) Reset()         { *m = Credentials{} }
-func (m *Credentials) String() string { return proto1.CompactTextString(m) }
-func (*Credentials) ProtoMessage()    {}
+type LoginRequest struct {
+	Nick                 string   `protobuf:"bytes,1,opt,name=nick,proto3" json:"nick,omitempty"`
+	Password             string   `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	PublicKey            []byte   `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
 
-type ListUsersResponse struct {
-	Users []*User `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+func (m *LoginRequest) Reset()         { *m = LoginRequest{} }
+func (m *LoginRequest) String() string { return proto.CompactTextString(m) }
+func (*LoginRequest) ProtoMessage()    {}
+func (*LoginRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{0}
+}
+func (m *LoginRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LoginRequest.Unmarshal(m, b)
+}
+func (m *LoginRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LoginRequest.Marshal(b, m, deterministic)
+}
+func (dst *LoginRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LoginRequest.Merge(dst, src)
+}
+func (m *LoginRequest) XXX_Size() int {
+	return xxx_messageInfo_LoginRequest.Size(m)
+}
+func (m *LoginRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LoginRequest.DiscardUnknown(m)
 }
 
-func (m *ListUsersResponse) Reset()         { *m = ListUsersResponse{} }
-func (m *ListUsersResponse) String() string { return proto1.CompactTextString(m) }
-func (*ListUsersResponse) ProtoMessage()    {}
+var xxx_messageInfo_LoginRequest proto.InternalMessageInfo
 
-func (m *ListUsersResponse) GetUsers() []*User {
+func (m *LoginRequest) GetNick() string {
 	if m != nil {
-		return m.Users
+		return m.Nick
 	}
-	return nil
+	return ""
 }
 
-type PrivateMsgRequest struct {
-	Creds *Credentials `protobuf:"bytes,1,opt,name=creds" json:"creds,omitempty"`
-	To    string       `protobuf:"bytes,2,opt,name=to" json:"to,omitempty"`
-	Msg   string       `protobuf:"bytes,3,opt,name=msg" json:"msg,omitempty"`
+func (m *LoginRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
 }
 
-func (m *PrivateMsgRequest) Reset()         { *m = PrivateMsgRequest{} }
-func (m *PrivateMsgRequest) String() string { return proto1.CompactTextString(m) }
-func (*PrivateMsgRequest) ProtoMessage()    {}
-
-func (m *PrivateMsgRequest) GetCreds() *Credentials {
+func (m *LoginRequest) GetPublicKey() []byte {
 	if m != nil {
-		return m.Creds
+		return m.PublicKey
 	}
 	return nil
 }
 
-type PublicMsgRequest struct {
-	Creds *Credentials `protobuf:"bytes,1,opt,name=creds" json:"creds,omitempty"`
-	Msg   string       `protobuf:"bytes,2,opt,name=msg" json:"msg,omitempty"`
+type RegisterRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Nick                 string       `protobuf:"bytes,2,opt,name=nick,proto3" json:"nick,omitempty"`
+	Password             string       `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
-func (m *PublicMsgRequest) Reset()         { *m = PublicMsgRequest{} }
-func (m *PublicMsgRequest) String() string { return proto1.CompactTextString(m) }
-func (*PublicMsgRequest) ProtoMessage()    {}
+func (m *RegisterRequest) Reset()         { *m = RegisterRequest{} }
+func (m *RegisterRequest) String() string { return proto.CompactTextString(m) }
+func (*RegisterRequest) ProtoMessage()    {}
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{1}
+}
+func (m *RegisterRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RegisterRequest.Unmarshal(m, b)
+}
+func (m *RegisterRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RegisterRequest.Marshal(b, m, deterministic)
+}
+func (dst *RegisterRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RegisterRequest.Merge(dst, src)
+}
+func (m *RegisterRequest) XXX_Size() int {
+	return xxx_messageInfo_RegisterRequest.Size(m)
+}
+func (m *RegisterRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RegisterRequest.DiscardUnknown(m)
+}
 
-func (m *PublicMsgRequest) GetCreds() *Credentials {
+var xxx_messageInfo_RegisterRequest proto.InternalMessageInfo
+
+func (m *RegisterRequest) GetCreds() *Credentials {
 	if m != nil {
 		return m.Creds
 	}
 	return nil
 }
 
-type SendMsgResponse struct {
+func (m *RegisterRequest) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
 }
 
-func (m *SendMsgResponse) Reset()         { *m = SendMsgResponse{} }
-func (m *SendMsgResponse) String() string { return proto1.CompactTextString(m) }
-func (*SendMsgResponse) ProtoMessage()    {}
-
-type ChatServerMsg struct {
-	// Types that are valid to be assigned to Msg:
-	//	*ChatServerMsg_PublicMsg
-	//	*ChatServerMsg_PrivateMsg
-	//	*ChatServerMsg_UserEvent
-	//	*ChatServerMsg_Heartbeat
-	Msg isChatServerMsg_Msg `protobuf_oneof:"msg"`
+func (m *RegisterRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
 }
 
-func (m *ChatServerMsg) Reset()         { *m = ChatServerMsg{} }
-func (m *ChatServerMsg) String() string { return proto1.CompactTextString(m) }
-func (*ChatServerMsg) ProtoMessage()    {}
-
-type isChatServerMsg_Msg interface {
-	isChatServerMsg_Msg()
+type AccountRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Password             string       `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	NewPassword          string       `protobuf:"bytes,3,opt,name=new_password,json=newPassword,proto3" json:"new_password,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
 }
 
-type ChatServerMsg_PublicMsg struct {
-	PublicMsg *PublicMsg `protobuf:"bytes,1,opt,name=public_msg"`
+func (m *AccountRequest) Reset()         { *m = AccountRequest{} }
+func (m *AccountRequest) String() string { return proto.CompactTextString(m) }
+func (*AccountRequest) ProtoMessage()    {}
+func (*AccountRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{2}
 }
-type ChatServerMsg_PrivateMsg struct {
-	PrivateMsg *PrivateMsg `protobuf:"bytes,2,opt,name=private_msg"`
+func (m *AccountRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountRequest.Unmarshal(m, b)
 }
-type ChatServerMsg_UserEvent struct {
-	UserEvent *UserEvent `protobuf:"bytes,3,opt,name=user_event"`
+func (m *AccountRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountRequest.Marshal(b, m, deterministic)
 }
-type ChatServerMsg_Heartbeat struct {
-	Heartbeat *Heartbeat `protobuf:"bytes,4,opt,name=heartbeat"`
+func (dst *AccountRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountRequest.Merge(dst, src)
+}
+func (m *AccountRequest) XXX_Size() int {
+	return xxx_messageInfo_AccountRequest.Size(m)
+}
+func (m *AccountRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountRequest.DiscardUnknown(m)
 }
 
-func (*ChatServerMsg_PublicMsg) isChatServerMsg_Msg()  {}
-func (*ChatServerMsg_PrivateMsg) isChatServerMsg_Msg() {}
-func (*ChatServerMsg_UserEvent) isChatServerMsg_Msg()  {}
-func (*ChatServerMsg_Heartbeat) isChatServerMsg_Msg()  {}
+var xxx_messageInfo_AccountRequest proto.InternalMessageInfo
 
-func (m *ChatServerMsg) GetMsg() isChatServerMsg_Msg {
+func (m *AccountRequest) GetCreds() *Credentials {
 	if m != nil {
-		return m.Msg
+		return m.Creds
 	}
 	return nil
 }
 
-func (m *ChatServerMsg) GetPublicMsg() *PublicMsg {
-	if x, ok := m.GetMsg().(*ChatServerMsg_PublicMsg); ok {
-		return x.PublicMsg
+func (m *AccountRequest) GetPassword() string {
+	if m != nil {
+		return m.Password
 	}
-	return nil
+	return ""
 }
 
-func (m *ChatServerMsg) GetPrivateMsg() *PrivateMsg {
-	if x, ok := m.GetMsg().(*ChatServerMsg_PrivateMsg); ok {
-		return x.PrivateMsg
+func (m *AccountRequest) GetNewPassword() string {
+	if m != nil {
+		return m.NewPassword
 	}
-	return nil
+	return ""
 }
 
-func (m *ChatServerMsg) GetUserEvent() *UserEvent {
-	if x, ok := m.GetMsg().(*ChatServerMsg_UserEvent); ok {
-		return x.UserEvent
-	}
-	return nil
+type AccountResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *ChatServerMsg) GetHeartbeat() *Heartbeat {
-	if x, ok := m.GetMsg().(*ChatServerMsg_Heartbeat); ok {
-		return x.Heartbeat
-	}
-	return nil
+func (m *AccountResponse) Reset()         { *m = AccountResponse{} }
+func (m *AccountResponse) String() string { return proto.CompactTextString(m) }
+func (*AccountResponse) ProtoMessage()    {}
+func (*AccountResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{3}
+}
+func (m *AccountResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AccountResponse.Unmarshal(m, b)
+}
+func (m *AccountResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AccountResponse.Marshal(b, m, deterministic)
+}
+func (dst *AccountResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AccountResponse.Merge(dst, src)
+}
+func (m *AccountResponse) XXX_Size() int {
+	return xxx_messageInfo_AccountResponse.Size(m)
+}
+func (m *AccountResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AccountResponse.DiscardUnknown(m)
 }
 
-// XXX_OneofFuncs is for the internal use of the proto package.
-func (*ChatServerMsg) XXX_OneofFuncs() (func(msg proto1.Message, b *proto1.Buffer) error, func(msg proto1.Message, tag, wire int, b *proto1.Buffer) (bool, error), []interface{}) {
-	return _ChatServerMsg_OneofMarshaler, _ChatServerMsg_OneofUnmarshaler, []interface{}{
-		(*ChatServerMsg_PublicMsg)(nil),
-		(*ChatServerMsg_PrivateMsg)(nil),
-		(*ChatServerMsg_UserEvent)(nil),
-		(*ChatServerMsg_Heartbeat)(nil),
-	}
+var xxx_messageInfo_AccountResponse proto.InternalMessageInfo
+
+type LogoutResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func _ChatServerMsg_OneofMarshaler(msg proto1.Message, b *proto1.Buffer) error {
-	m := msg.(*ChatServerMsg)
-	// msg
-	switch x := m.Msg.(type) {
-	case *ChatServerMsg_PublicMsg:
-		b.EncodeVarint(1<<3 | proto1.WireBytes)
-		if err := b.EncodeMessage(x.PublicMsg); err != nil {
-			return err
-		}
-	case *ChatServerMsg_PrivateMsg:
-		b.EncodeVarint(2<<3 | proto1.WireBytes)
-		if err := b.EncodeMessage(x.PrivateMsg); err != nil {
-			return err
-		}
-	case *ChatServerMsg_UserEvent:
-		b.EncodeVarint(3<<3 | proto1.WireBytes)
-		if err := b.EncodeMessage(x.UserEvent); err != nil {
-			return err
-		}
-	case *ChatServerMsg_Heartbeat:
-		b.EncodeVarint(4<<3 | proto1.WireBytes)
-		if err := b.EncodeMessage(x.Heartbeat); err != nil {
-			return err
-		}
-	case nil:
-	default:
-		return fmt.Errorf("ChatServerMsg.Msg has unexpected type %T", x)
-	}
-	return nil
+func (m *LogoutResponse) Reset()         { *m = LogoutResponse{} }
+func (m *LogoutResponse) String() string { return proto.CompactTextString(m) }
+func (*LogoutResponse) ProtoMessage()    {}
+func (*LogoutResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{4}
+}
+func (m *LogoutResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LogoutResponse.Unmarshal(m, b)
+}
+func (m *LogoutResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LogoutResponse.Marshal(b, m, deterministic)
+}
+func (dst *LogoutResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LogoutResponse.Merge(dst, src)
+}
+func (m *LogoutResponse) XXX_Size() int {
+	return xxx_messageInfo_LogoutResponse.Size(m)
+}
+func (m *LogoutResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LogoutResponse.DiscardUnknown(m)
 }
 
-func _ChatServerMsg_OneofUnmarshaler(msg proto1.Message, tag, wire int, b *proto1.Buffer) (bool, error) {
-	m := msg.(*ChatServerMsg)
-	switch tag {
-	case 1: // msg.public_msg
-		if wire != proto1.WireBytes {
-			return true, proto1.ErrInternalBadWireType
-		}
-		msg := new(PublicMsg)
-		err := b.DecodeMessage(msg)
-		m.Msg = &ChatServerMsg_PublicMsg{msg}
-		return true, err
-	case 2: // msg.private_msg
-		if wire != proto1.WireBytes {
-			return true, proto1.ErrInternalBadWireType
-		}
-		msg := new(PrivateMsg)
-		err := b.DecodeMessage(msg)
-		m.Msg = &ChatServerMsg_PrivateMsg{msg}
-		return true, err
-	case 3: // msg.user_event
-		if wire != proto1.WireBytes {
-			return true, proto1.ErrInternalBadWireType
-		}
-		msg := new(UserEvent)
-		err := b.DecodeMessage(msg)
-		m.Msg = &ChatServerMsg_UserEvent{msg}
-		return true, err
-	case 4: // msg.heartbeat
-		if wire != proto1.WireBytes {
-			return true, proto1.ErrInternalBadWireType
-		}
-		msg := new(Heartbeat)
-		err := b.DecodeMessage(msg)
-		m.Msg = &ChatServerMsg_Heartbeat{msg}
-		return true, err
-	default:
-		return false, nil
-	}
+var xxx_messageInfo_LogoutResponse proto.InternalMessageInfo
+
+type User struct {
+	Nick                 string    `protobuf:"bytes,1,opt,name=nick,proto3" json:"nick,omitempty"`
+	TimeLastSeen         int64     `protobuf:"varint,3,opt,name=time_last_seen,json=timeLastSeen,proto3" json:"time_last_seen,omitempty"`
+	Presence             *Presence `protobuf:"bytes,4,opt,name=presence,proto3" json:"presence,omitempty"`
+	Role                 User_Role `protobuf:"varint,5,opt,name=role,proto3,enum=proto.User_Role" json:"role,omitempty"`
+	PublicKey            []byte    `protobuf:"bytes,6,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
-type PrivateMsg struct {
-	To       string `protobuf:"bytes,1,opt,name=to" json:"to,omitempty"`
-	From     *User  `protobuf:"bytes,2,opt,name=from" json:"from,omitempty"`
-	Msg      string `protobuf:"bytes,3,opt,name=msg" json:"msg,omitempty"`
-	TimeSent int64  `protobuf:"varint,4,opt,name=time_sent" json:"time_sent,omitempty"`
+func (m *User) Reset()         { *m = User{} }
+func (m *User) String() string { return proto.CompactTextString(m) }
+func (*User) ProtoMessage()    {}
+func (*User) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{5}
+}
+func (m *User) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_User.Unmarshal(m, b)
+}
+func (m *User) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_User.Marshal(b, m, deterministic)
+}
+func (dst *User) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_User.Merge(dst, src)
+}
+func (m *User) XXX_Size() int {
+	return xxx_messageInfo_User.Size(m)
+}
+func (m *User) XXX_DiscardUnknown() {
+	xxx_messageInfo_User.DiscardUnknown(m)
 }
 
-func (m *PrivateMsg) Reset()         { *m = PrivateMsg{} }
-func (m *PrivateMsg) String() string { return proto1.CompactTextString(m) }
-func (*PrivateMsg) ProtoMessage()    {}
+var xxx_messageInfo_User proto.InternalMessageInfo
 
-func (m *PrivateMsg) GetFrom() *User {
+func (m *User) GetNick() string {
 	if m != nil {
-		return m.From
+		return m.Nick
 	}
-	return nil
+	return ""
 }
 
-type PublicMsg struct {
-	From     *User  `protobuf:"bytes,1,opt,name=from" json:"from,omitempty"`
-	Msg      string `protobuf:"bytes,2,opt,name=msg" json:"msg,omitempty"`
-	TimeSent int64  `protobuf:"varint,3,opt,name=time_sent" json:"time_sent,omitempty"`
+func (m *User) GetTimeLastSeen() int64 {
+	if m != nil {
+		return m.TimeLastSeen
+	}
+	return 0
 }
 
-func (m *PublicMsg) Reset()         { *m = PublicMsg{} }
-func (m *PublicMsg) String() string { return proto1.CompactTextString(m) }
-func (*PublicMsg) ProtoMessage()    {}
-
-func (m *PublicMsg) GetFrom() *User {
+func (m *User) GetPresence() *Presence {
 	if m != nil {
-		return m.From
+		return m.Presence
 	}
 	return nil
 }
 
-type UserEvent struct {
-	Event UserEvent_EventType `protobuf:"varint,1,opt,name=event,enum=proto.UserEvent_EventType" json:"event,omitempty"`
-	User  *User               `protobuf:"bytes,2,opt,name=user" json:"user,omitempty"`
-	Time  int64               `protobuf:"varint,3,opt,name=time" json:"time,omitempty"`
+func (m *User) GetRole() User_Role {
+	if m != nil {
+		return m.Role
+	}
+	return User_USER
 }
 
-func (m *UserEvent) Reset()         { *m = UserEvent{} }
-func (m *UserEvent) String() string { return proto1.CompactTextString(m) }
-func (*UserEvent) ProtoMessage()    {}
-
-func (m *UserEvent) GetUser() *User {
+func (m *User) GetPublicKey() []byte {
 	if m != nil {
-		return m.User
+		return m.PublicKey
 	}
 	return nil
 }
 
-type Heartbeat struct {
+type Presence struct {
+	Status               Presence_Status `protobuf:"varint,1,opt,name=status,proto3,enum=proto.Presence_Status" json:"status,omitempty"`
+	Text                 string          `protobuf:"bytes,2,opt,name=text,proto3" json:"text,omitempty"`
+	Idle                 bool            `protobuf:"varint,3,opt,name=idle,proto3" json:"idle,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
-func (m *Heartbeat) Reset()         { *m = Heartbeat{} }
-func (m *Heartbeat) String() string { return proto1.CompactTextString(m) }
-func (*Heartbeat) ProtoMessage()    {}
-
-func init() {
-	proto1.RegisterEnum("proto.UserEvent_EventType", UserEvent_EventType_name, UserEvent_EventType_value)
+func (m *Presence) Reset()         { *m = Presence{} }
+func (m *Presence) String() string { return proto.CompactTextString(m) }
+func (*Presence) ProtoMessage()    {}
+func (*Presence) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{6}
+}
+func (m *Presence) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Presence.Unmarshal(m, b)
+}
+func (m *Presence) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Presence.Marshal(b, m, deterministic)
+}
+func (dst *Presence) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Presence.Merge(dst, src)
+}
+func (m *Presence) XXX_Size() int {
+	return xxx_messageInfo_Presence.Size(m)
+}
+func (m *Presence) XXX_DiscardUnknown() {
+	xxx_messageInfo_Presence.DiscardUnknown(m)
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// Client API for UserService service
+var xxx_messageInfo_Presence proto.InternalMessageInfo
 
-type UserServiceClient interface {
+func (m *Presence) GetStatus() Presence_Status {
+	if m != nil {
+		return m.Status
+	}
+	return Presence_OFFLINE
+}
+
+func (m *Presence) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+func (m *Presence) GetIdle() bool {
+	if m != nil {
+		return m.Idle
+	}
+	return false
+}
+
+type Credentials struct {
+	Nick                 string   `protobuf:"bytes,1,opt,name=nick,proto3" json:"nick,omitempty"`
+	Token                []byte   `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Session              string   `protobuf:"bytes,3,opt,name=session,proto3" json:"session,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Credentials) Reset()         { *m = Credentials{} }
+func (m *Credentials) String() string { return proto.CompactTextString(m) }
+func (*Credentials) ProtoMessage()    {}
+func (*Credentials) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{7}
+}
+func (m *Credentials) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Credentials.Unmarshal(m, b)
+}
+func (m *Credentials) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Credentials.Marshal(b, m, deterministic)
+}
+func (dst *Credentials) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Credentials.Merge(dst, src)
+}
+func (m *Credentials) XXX_Size() int {
+	return xxx_messageInfo_Credentials.Size(m)
+}
+func (m *Credentials) XXX_DiscardUnknown() {
+	xxx_messageInfo_Credentials.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Credentials proto.InternalMessageInfo
+
+func (m *Credentials) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *Credentials) GetToken() []byte {
+	if m != nil {
+		return m.Token
+	}
+	return nil
+}
+
+func (m *Credentials) GetSession() string {
+	if m != nil {
+		return m.Session
+	}
+	return ""
+}
+
+type ListUsersResponse struct {
+	Users                []*User  `protobuf:"bytes,1,rep,name=users,proto3" json:"users,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListUsersResponse) Reset()         { *m = ListUsersResponse{} }
+func (m *ListUsersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListUsersResponse) ProtoMessage()    {}
+func (*ListUsersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{8}
+}
+func (m *ListUsersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListUsersResponse.Unmarshal(m, b)
+}
+func (m *ListUsersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListUsersResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListUsersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListUsersResponse.Merge(dst, src)
+}
+func (m *ListUsersResponse) XXX_Size() int {
+	return xxx_messageInfo_ListUsersResponse.Size(m)
+}
+func (m *ListUsersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListUsersResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListUsersResponse proto.InternalMessageInfo
+
+func (m *ListUsersResponse) GetUsers() []*User {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+type ModerationRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Nick                 string       `protobuf:"bytes,2,opt,name=nick,proto3" json:"nick,omitempty"`
+	Reason               string       `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Duration             int64        `protobuf:"varint,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ModerationRequest) Reset()         { *m = ModerationRequest{} }
+func (m *ModerationRequest) String() string { return proto.CompactTextString(m) }
+func (*ModerationRequest) ProtoMessage()    {}
+func (*ModerationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{9}
+}
+func (m *ModerationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModerationRequest.Unmarshal(m, b)
+}
+func (m *ModerationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModerationRequest.Marshal(b, m, deterministic)
+}
+func (dst *ModerationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModerationRequest.Merge(dst, src)
+}
+func (m *ModerationRequest) XXX_Size() int {
+	return xxx_messageInfo_ModerationRequest.Size(m)
+}
+func (m *ModerationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModerationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModerationRequest proto.InternalMessageInfo
+
+func (m *ModerationRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *ModerationRequest) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *ModerationRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *ModerationRequest) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type ModerationResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ModerationResponse) Reset()         { *m = ModerationResponse{} }
+func (m *ModerationResponse) String() string { return proto.CompactTextString(m) }
+func (*ModerationResponse) ProtoMessage()    {}
+func (*ModerationResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{10}
+}
+func (m *ModerationResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ModerationResponse.Unmarshal(m, b)
+}
+func (m *ModerationResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ModerationResponse.Marshal(b, m, deterministic)
+}
+func (dst *ModerationResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ModerationResponse.Merge(dst, src)
+}
+func (m *ModerationResponse) XXX_Size() int {
+	return xxx_messageInfo_ModerationResponse.Size(m)
+}
+func (m *ModerationResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ModerationResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ModerationResponse proto.InternalMessageInfo
+
+type BanRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Nick                 string       `protobuf:"bytes,2,opt,name=nick,proto3" json:"nick,omitempty"`
+	Addr                 string       `protobuf:"bytes,3,opt,name=addr,proto3" json:"addr,omitempty"`
+	Reason               string       `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Duration             int64        `protobuf:"varint,5,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *BanRequest) Reset()         { *m = BanRequest{} }
+func (m *BanRequest) String() string { return proto.CompactTextString(m) }
+func (*BanRequest) ProtoMessage()    {}
+func (*BanRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{11}
+}
+func (m *BanRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BanRequest.Unmarshal(m, b)
+}
+func (m *BanRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BanRequest.Marshal(b, m, deterministic)
+}
+func (dst *BanRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BanRequest.Merge(dst, src)
+}
+func (m *BanRequest) XXX_Size() int {
+	return xxx_messageInfo_BanRequest.Size(m)
+}
+func (m *BanRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BanRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BanRequest proto.InternalMessageInfo
+
+func (m *BanRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *BanRequest) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *BanRequest) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *BanRequest) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *BanRequest) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type Ban struct {
+	Nick                 string    `protobuf:"bytes,1,opt,name=nick,proto3" json:"nick,omitempty"`
+	Addr                 string    `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	Reason               string    `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	By                   string    `protobuf:"bytes,4,opt,name=by,proto3" json:"by,omitempty"`
+	Expiry               int64     `protobuf:"varint,5,opt,name=expiry,proto3" json:"expiry,omitempty"`
+	ByRole               User_Role `protobuf:"varint,6,opt,name=by_role,json=byRole,proto3,enum=proto.User_Role" json:"by_role,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Ban) Reset()         { *m = Ban{} }
+func (m *Ban) String() string { return proto.CompactTextString(m) }
+func (*Ban) ProtoMessage()    {}
+func (*Ban) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{12}
+}
+func (m *Ban) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ban.Unmarshal(m, b)
+}
+func (m *Ban) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ban.Marshal(b, m, deterministic)
+}
+func (dst *Ban) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ban.Merge(dst, src)
+}
+func (m *Ban) XXX_Size() int {
+	return xxx_messageInfo_Ban.Size(m)
+}
+func (m *Ban) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ban.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ban proto.InternalMessageInfo
+
+func (m *Ban) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *Ban) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *Ban) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *Ban) GetBy() string {
+	if m != nil {
+		return m.By
+	}
+	return ""
+}
+
+func (m *Ban) GetExpiry() int64 {
+	if m != nil {
+		return m.Expiry
+	}
+	return 0
+}
+
+func (m *Ban) GetByRole() User_Role {
+	if m != nil {
+		return m.ByRole
+	}
+	return User_USER
+}
+
+type ListBansResponse struct {
+	Bans                 []*Ban   `protobuf:"bytes,1,rep,name=bans,proto3" json:"bans,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListBansResponse) Reset()         { *m = ListBansResponse{} }
+func (m *ListBansResponse) String() string { return proto.CompactTextString(m) }
+func (*ListBansResponse) ProtoMessage()    {}
+func (*ListBansResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{13}
+}
+func (m *ListBansResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListBansResponse.Unmarshal(m, b)
+}
+func (m *ListBansResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListBansResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListBansResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListBansResponse.Merge(dst, src)
+}
+func (m *ListBansResponse) XXX_Size() int {
+	return xxx_messageInfo_ListBansResponse.Size(m)
+}
+func (m *ListBansResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListBansResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListBansResponse proto.InternalMessageInfo
+
+func (m *ListBansResponse) GetBans() []*Ban {
+	if m != nil {
+		return m.Bans
+	}
+	return nil
+}
+
+type RoleRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Nick                 string       `protobuf:"bytes,2,opt,name=nick,proto3" json:"nick,omitempty"`
+	Role                 User_Role    `protobuf:"varint,3,opt,name=role,proto3,enum=proto.User_Role" json:"role,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *RoleRequest) Reset()         { *m = RoleRequest{} }
+func (m *RoleRequest) String() string { return proto.CompactTextString(m) }
+func (*RoleRequest) ProtoMessage()    {}
+func (*RoleRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{14}
+}
+func (m *RoleRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoleRequest.Unmarshal(m, b)
+}
+func (m *RoleRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoleRequest.Marshal(b, m, deterministic)
+}
+func (dst *RoleRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoleRequest.Merge(dst, src)
+}
+func (m *RoleRequest) XXX_Size() int {
+	return xxx_messageInfo_RoleRequest.Size(m)
+}
+func (m *RoleRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoleRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoleRequest proto.InternalMessageInfo
+
+func (m *RoleRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *RoleRequest) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *RoleRequest) GetRole() User_Role {
+	if m != nil {
+		return m.Role
+	}
+	return User_USER
+}
+
+type ChatClientMsg struct {
+	// Types that are valid to be assigned to Msg:
+	//	*ChatClientMsg_Open
+	//	*ChatClientMsg_Public
+	//	*ChatClientMsg_Private
+	//	*ChatClientMsg_Room
+	//	*ChatClientMsg_CreateRoom
+	//	*ChatClientMsg_JoinRoom
+	//	*ChatClientMsg_LeaveRoom
+	//	*ChatClientMsg_Ack
+	//	*ChatClientMsg_MarkRead
+	//	*ChatClientMsg_Presence
+	//	*ChatClientMsg_Typing
+	//	*ChatClientMsg_Ping
+	Msg                  isChatClientMsg_Msg `protobuf_oneof:"msg"`
+	Id                   uint64              `protobuf:"varint,16,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *ChatClientMsg) Reset()         { *m = ChatClientMsg{} }
+func (m *ChatClientMsg) String() string { return proto.CompactTextString(m) }
+func (*ChatClientMsg) ProtoMessage()    {}
+func (*ChatClientMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{15}
+}
+func (m *ChatClientMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChatClientMsg.Unmarshal(m, b)
+}
+func (m *ChatClientMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChatClientMsg.Marshal(b, m, deterministic)
+}
+func (dst *ChatClientMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChatClientMsg.Merge(dst, src)
+}
+func (m *ChatClientMsg) XXX_Size() int {
+	return xxx_messageInfo_ChatClientMsg.Size(m)
+}
+func (m *ChatClientMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChatClientMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChatClientMsg proto.InternalMessageInfo
+
+type isChatClientMsg_Msg interface {
+	isChatClientMsg_Msg()
+}
+
+type ChatClientMsg_Open struct {
+	Open *ChatOpen `protobuf:"bytes,1,opt,name=open,proto3,oneof"`
+}
+
+type ChatClientMsg_Public struct {
+	Public *PublicMsgRequest `protobuf:"bytes,2,opt,name=public,proto3,oneof"`
+}
+
+type ChatClientMsg_Private struct {
+	Private *PrivateMsgRequest `protobuf:"bytes,3,opt,name=private,proto3,oneof"`
+}
+
+type ChatClientMsg_Room struct {
+	Room *RoomMsgRequest `protobuf:"bytes,4,opt,name=room,proto3,oneof"`
+}
+
+type ChatClientMsg_CreateRoom struct {
+	CreateRoom *RoomRequest `protobuf:"bytes,5,opt,name=create_room,json=createRoom,proto3,oneof"`
+}
+
+type ChatClientMsg_JoinRoom struct {
+	JoinRoom *RoomRequest `protobuf:"bytes,6,opt,name=join_room,json=joinRoom,proto3,oneof"`
+}
+
+type ChatClientMsg_LeaveRoom struct {
+	LeaveRoom *RoomRequest `protobuf:"bytes,7,opt,name=leave_room,json=leaveRoom,proto3,oneof"`
+}
+
+type ChatClientMsg_Ack struct {
+	Ack *AckRequest `protobuf:"bytes,8,opt,name=ack,proto3,oneof"`
+}
+
+type ChatClientMsg_MarkRead struct {
+	MarkRead *AckRequest `protobuf:"bytes,9,opt,name=mark_read,json=markRead,proto3,oneof"`
+}
+
+type ChatClientMsg_Presence struct {
+	Presence *PresenceRequest `protobuf:"bytes,10,opt,name=presence,proto3,oneof"`
+}
+
+type ChatClientMsg_Typing struct {
+	Typing *TypingRequest `protobuf:"bytes,11,opt,name=typing,proto3,oneof"`
+}
+
+type ChatClientMsg_Ping struct {
+	Ping *PingRequest `protobuf:"bytes,12,opt,name=ping,proto3,oneof"`
+}
+
+func (*ChatClientMsg_Open) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Public) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Private) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Room) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_CreateRoom) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_JoinRoom) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_LeaveRoom) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Ack) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_MarkRead) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Presence) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Typing) isChatClientMsg_Msg() {}
+
+func (*ChatClientMsg_Ping) isChatClientMsg_Msg() {}
+
+func (m *ChatClientMsg) GetMsg() isChatClientMsg_Msg {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetOpen() *ChatOpen {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Open); ok {
+		return x.Open
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetPublic() *PublicMsgRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Public); ok {
+		return x.Public
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetPrivate() *PrivateMsgRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Private); ok {
+		return x.Private
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetRoom() *RoomMsgRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Room); ok {
+		return x.Room
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetCreateRoom() *RoomRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_CreateRoom); ok {
+		return x.CreateRoom
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetJoinRoom() *RoomRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_JoinRoom); ok {
+		return x.JoinRoom
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetLeaveRoom() *RoomRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_LeaveRoom); ok {
+		return x.LeaveRoom
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetAck() *AckRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Ack); ok {
+		return x.Ack
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetMarkRead() *AckRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_MarkRead); ok {
+		return x.MarkRead
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetPresence() *PresenceRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Presence); ok {
+		return x.Presence
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetTyping() *TypingRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Typing); ok {
+		return x.Typing
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetPing() *PingRequest {
+	if x, ok := m.GetMsg().(*ChatClientMsg_Ping); ok {
+		return x.Ping
+	}
+	return nil
+}
+
+func (m *ChatClientMsg) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*ChatClientMsg) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _ChatClientMsg_OneofMarshaler, _ChatClientMsg_OneofUnmarshaler, _ChatClientMsg_OneofSizer, []interface{}{
+		(*ChatClientMsg_Open)(nil),
+		(*ChatClientMsg_Public)(nil),
+		(*ChatClientMsg_Private)(nil),
+		(*ChatClientMsg_Room)(nil),
+		(*ChatClientMsg_CreateRoom)(nil),
+		(*ChatClientMsg_JoinRoom)(nil),
+		(*ChatClientMsg_LeaveRoom)(nil),
+		(*ChatClientMsg_Ack)(nil),
+		(*ChatClientMsg_MarkRead)(nil),
+		(*ChatClientMsg_Presence)(nil),
+		(*ChatClientMsg_Typing)(nil),
+		(*ChatClientMsg_Ping)(nil),
+	}
+}
+
+func _ChatClientMsg_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*ChatClientMsg)
+	// msg
+	switch x := m.Msg.(type) {
+	case *ChatClientMsg_Open:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Open); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Public:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Public); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Private:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Private); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Room:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Room); err != nil {
+			return err
+		}
+	case *ChatClientMsg_CreateRoom:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.CreateRoom); err != nil {
+			return err
+		}
+	case *ChatClientMsg_JoinRoom:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.JoinRoom); err != nil {
+			return err
+		}
+	case *ChatClientMsg_LeaveRoom:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.LeaveRoom); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Ack:
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Ack); err != nil {
+			return err
+		}
+	case *ChatClientMsg_MarkRead:
+		b.EncodeVarint(9<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.MarkRead); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Presence:
+		b.EncodeVarint(10<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Presence); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Typing:
+		b.EncodeVarint(11<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Typing); err != nil {
+			return err
+		}
+	case *ChatClientMsg_Ping:
+		b.EncodeVarint(12<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Ping); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("ChatClientMsg.Msg has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _ChatClientMsg_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*ChatClientMsg)
+	switch tag {
+	case 1: // msg.open
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ChatOpen)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Open{msg}
+		return true, err
+	case 2: // msg.public
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PublicMsgRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Public{msg}
+		return true, err
+	case 3: // msg.private
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PrivateMsgRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Private{msg}
+		return true, err
+	case 4: // msg.room
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(RoomMsgRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Room{msg}
+		return true, err
+	case 5: // msg.create_room
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(RoomRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_CreateRoom{msg}
+		return true, err
+	case 6: // msg.join_room
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(RoomRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_JoinRoom{msg}
+		return true, err
+	case 7: // msg.leave_room
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(RoomRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_LeaveRoom{msg}
+		return true, err
+	case 8: // msg.ack
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(AckRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Ack{msg}
+		return true, err
+	case 9: // msg.mark_read
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(AckRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_MarkRead{msg}
+		return true, err
+	case 10: // msg.presence
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PresenceRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Presence{msg}
+		return true, err
+	case 11: // msg.typing
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(TypingRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Typing{msg}
+		return true, err
+	case 12: // msg.ping
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PingRequest)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatClientMsg_Ping{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _ChatClientMsg_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*ChatClientMsg)
+	// msg
+	switch x := m.Msg.(type) {
+	case *ChatClientMsg_Open:
+		s := proto.Size(x.Open)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Public:
+		s := proto.Size(x.Public)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Private:
+		s := proto.Size(x.Private)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Room:
+		s := proto.Size(x.Room)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_CreateRoom:
+		s := proto.Size(x.CreateRoom)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_JoinRoom:
+		s := proto.Size(x.JoinRoom)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_LeaveRoom:
+		s := proto.Size(x.LeaveRoom)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Ack:
+		s := proto.Size(x.Ack)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_MarkRead:
+		s := proto.Size(x.MarkRead)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Presence:
+		s := proto.Size(x.Presence)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Typing:
+		s := proto.Size(x.Typing)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatClientMsg_Ping:
+		s := proto.Size(x.Ping)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type ChatOpen struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Resume               bool         `protobuf:"varint,2,opt,name=resume,proto3" json:"resume,omitempty"`
+	Cursor               uint64       `protobuf:"varint,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ChatOpen) Reset()         { *m = ChatOpen{} }
+func (m *ChatOpen) String() string { return proto.CompactTextString(m) }
+func (*ChatOpen) ProtoMessage()    {}
+func (*ChatOpen) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{16}
+}
+func (m *ChatOpen) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChatOpen.Unmarshal(m, b)
+}
+func (m *ChatOpen) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChatOpen.Marshal(b, m, deterministic)
+}
+func (dst *ChatOpen) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChatOpen.Merge(dst, src)
+}
+func (m *ChatOpen) XXX_Size() int {
+	return xxx_messageInfo_ChatOpen.Size(m)
+}
+func (m *ChatOpen) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChatOpen.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChatOpen proto.InternalMessageInfo
+
+func (m *ChatOpen) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *ChatOpen) GetResume() bool {
+	if m != nil {
+		return m.Resume
+	}
+	return false
+}
+
+func (m *ChatOpen) GetCursor() uint64 {
+	if m != nil {
+		return m.Cursor
+	}
+	return 0
+}
+
+type Reply struct {
+	Id                   uint64           `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Code                 int32            `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Error                string           `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Sent                 *SendMsgResponse `protobuf:"bytes,4,opt,name=sent,proto3" json:"sent,omitempty"`
+	Presence             *Presence        `protobuf:"bytes,5,opt,name=presence,proto3" json:"presence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *Reply) Reset()         { *m = Reply{} }
+func (m *Reply) String() string { return proto.CompactTextString(m) }
+func (*Reply) ProtoMessage()    {}
+func (*Reply) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{17}
+}
+func (m *Reply) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Reply.Unmarshal(m, b)
+}
+func (m *Reply) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Reply.Marshal(b, m, deterministic)
+}
+func (dst *Reply) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Reply.Merge(dst, src)
+}
+func (m *Reply) XXX_Size() int {
+	return xxx_messageInfo_Reply.Size(m)
+}
+func (m *Reply) XXX_DiscardUnknown() {
+	xxx_messageInfo_Reply.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Reply proto.InternalMessageInfo
+
+func (m *Reply) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Reply) GetCode() int32 {
+	if m != nil {
+		return m.Code
+	}
+	return 0
+}
+
+func (m *Reply) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *Reply) GetSent() *SendMsgResponse {
+	if m != nil {
+		return m.Sent
+	}
+	return nil
+}
+
+func (m *Reply) GetPresence() *Presence {
+	if m != nil {
+		return m.Presence
+	}
+	return nil
+}
+
+type PresenceRequest struct {
+	Creds                *Credentials    `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Status               Presence_Status `protobuf:"varint,2,opt,name=status,proto3,enum=proto.Presence_Status" json:"status,omitempty"`
+	Text                 string          `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *PresenceRequest) Reset()         { *m = PresenceRequest{} }
+func (m *PresenceRequest) String() string { return proto.CompactTextString(m) }
+func (*PresenceRequest) ProtoMessage()    {}
+func (*PresenceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{18}
+}
+func (m *PresenceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PresenceRequest.Unmarshal(m, b)
+}
+func (m *PresenceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PresenceRequest.Marshal(b, m, deterministic)
+}
+func (dst *PresenceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PresenceRequest.Merge(dst, src)
+}
+func (m *PresenceRequest) XXX_Size() int {
+	return xxx_messageInfo_PresenceRequest.Size(m)
+}
+func (m *PresenceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PresenceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PresenceRequest proto.InternalMessageInfo
+
+func (m *PresenceRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *PresenceRequest) GetStatus() Presence_Status {
+	if m != nil {
+		return m.Status
+	}
+	return Presence_OFFLINE
+}
+
+func (m *PresenceRequest) GetText() string {
+	if m != nil {
+		return m.Text
+	}
+	return ""
+}
+
+type TypingRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	To                   string       `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Room                 string       `protobuf:"bytes,3,opt,name=room,proto3" json:"room,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *TypingRequest) Reset()         { *m = TypingRequest{} }
+func (m *TypingRequest) String() string { return proto.CompactTextString(m) }
+func (*TypingRequest) ProtoMessage()    {}
+func (*TypingRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{19}
+}
+func (m *TypingRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TypingRequest.Unmarshal(m, b)
+}
+func (m *TypingRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TypingRequest.Marshal(b, m, deterministic)
+}
+func (dst *TypingRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TypingRequest.Merge(dst, src)
+}
+func (m *TypingRequest) XXX_Size() int {
+	return xxx_messageInfo_TypingRequest.Size(m)
+}
+func (m *TypingRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TypingRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TypingRequest proto.InternalMessageInfo
+
+func (m *TypingRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *TypingRequest) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *TypingRequest) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+type TypingResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TypingResponse) Reset()         { *m = TypingResponse{} }
+func (m *TypingResponse) String() string { return proto.CompactTextString(m) }
+func (*TypingResponse) ProtoMessage()    {}
+func (*TypingResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{20}
+}
+func (m *TypingResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TypingResponse.Unmarshal(m, b)
+}
+func (m *TypingResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TypingResponse.Marshal(b, m, deterministic)
+}
+func (dst *TypingResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TypingResponse.Merge(dst, src)
+}
+func (m *TypingResponse) XXX_Size() int {
+	return xxx_messageInfo_TypingResponse.Size(m)
+}
+func (m *TypingResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TypingResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TypingResponse proto.InternalMessageInfo
+
+type PingRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Seq                  uint64       `protobuf:"varint,2,opt,name=seq,proto3" json:"seq,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *PingRequest) Reset()         { *m = PingRequest{} }
+func (m *PingRequest) String() string { return proto.CompactTextString(m) }
+func (*PingRequest) ProtoMessage()    {}
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{21}
+}
+func (m *PingRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingRequest.Unmarshal(m, b)
+}
+func (m *PingRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingRequest.Marshal(b, m, deterministic)
+}
+func (dst *PingRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingRequest.Merge(dst, src)
+}
+func (m *PingRequest) XXX_Size() int {
+	return xxx_messageInfo_PingRequest.Size(m)
+}
+func (m *PingRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingRequest proto.InternalMessageInfo
+
+func (m *PingRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *PingRequest) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+type PingResponse struct {
+	Seq                  uint64   `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PingResponse) Reset()         { *m = PingResponse{} }
+func (m *PingResponse) String() string { return proto.CompactTextString(m) }
+func (*PingResponse) ProtoMessage()    {}
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{22}
+}
+func (m *PingResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingResponse.Unmarshal(m, b)
+}
+func (m *PingResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingResponse.Marshal(b, m, deterministic)
+}
+func (dst *PingResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingResponse.Merge(dst, src)
+}
+func (m *PingResponse) XXX_Size() int {
+	return xxx_messageInfo_PingResponse.Size(m)
+}
+func (m *PingResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingResponse proto.InternalMessageInfo
+
+func (m *PingResponse) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+type PrivateMsgRequest struct {
+	Creds                *Credentials  `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	To                   string        `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Msg                  string        `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	WantReceipts         bool          `protobuf:"varint,4,opt,name=want_receipts,json=wantReceipts,proto3" json:"want_receipts,omitempty"`
+	Encrypted            *EncryptedMsg `protobuf:"bytes,5,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *PrivateMsgRequest) Reset()         { *m = PrivateMsgRequest{} }
+func (m *PrivateMsgRequest) String() string { return proto.CompactTextString(m) }
+func (*PrivateMsgRequest) ProtoMessage()    {}
+func (*PrivateMsgRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{23}
+}
+func (m *PrivateMsgRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrivateMsgRequest.Unmarshal(m, b)
+}
+func (m *PrivateMsgRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrivateMsgRequest.Marshal(b, m, deterministic)
+}
+func (dst *PrivateMsgRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrivateMsgRequest.Merge(dst, src)
+}
+func (m *PrivateMsgRequest) XXX_Size() int {
+	return xxx_messageInfo_PrivateMsgRequest.Size(m)
+}
+func (m *PrivateMsgRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrivateMsgRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrivateMsgRequest proto.InternalMessageInfo
+
+func (m *PrivateMsgRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *PrivateMsgRequest) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *PrivateMsgRequest) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func (m *PrivateMsgRequest) GetWantReceipts() bool {
+	if m != nil {
+		return m.WantReceipts
+	}
+	return false
+}
+
+func (m *PrivateMsgRequest) GetEncrypted() *EncryptedMsg {
+	if m != nil {
+		return m.Encrypted
+	}
+	return nil
+}
+
+type EncryptedMsg struct {
+	SenderKey            []byte   `protobuf:"bytes,1,opt,name=sender_key,json=senderKey,proto3" json:"sender_key,omitempty"`
+	RecipientKey         []byte   `protobuf:"bytes,2,opt,name=recipient_key,json=recipientKey,proto3" json:"recipient_key,omitempty"`
+	Nonce                []byte   `protobuf:"bytes,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Box                  []byte   `protobuf:"bytes,4,opt,name=box,proto3" json:"box,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EncryptedMsg) Reset()         { *m = EncryptedMsg{} }
+func (m *EncryptedMsg) String() string { return proto.CompactTextString(m) }
+func (*EncryptedMsg) ProtoMessage()    {}
+func (*EncryptedMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{24}
+}
+func (m *EncryptedMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EncryptedMsg.Unmarshal(m, b)
+}
+func (m *EncryptedMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EncryptedMsg.Marshal(b, m, deterministic)
+}
+func (dst *EncryptedMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EncryptedMsg.Merge(dst, src)
+}
+func (m *EncryptedMsg) XXX_Size() int {
+	return xxx_messageInfo_EncryptedMsg.Size(m)
+}
+func (m *EncryptedMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_EncryptedMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EncryptedMsg proto.InternalMessageInfo
+
+func (m *EncryptedMsg) GetSenderKey() []byte {
+	if m != nil {
+		return m.SenderKey
+	}
+	return nil
+}
+
+func (m *EncryptedMsg) GetRecipientKey() []byte {
+	if m != nil {
+		return m.RecipientKey
+	}
+	return nil
+}
+
+func (m *EncryptedMsg) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *EncryptedMsg) GetBox() []byte {
+	if m != nil {
+		return m.Box
+	}
+	return nil
+}
+
+type PublicKeyRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Nick                 string       `protobuf:"bytes,2,opt,name=nick,proto3" json:"nick,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *PublicKeyRequest) Reset()         { *m = PublicKeyRequest{} }
+func (m *PublicKeyRequest) String() string { return proto.CompactTextString(m) }
+func (*PublicKeyRequest) ProtoMessage()    {}
+func (*PublicKeyRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{25}
+}
+func (m *PublicKeyRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicKeyRequest.Unmarshal(m, b)
+}
+func (m *PublicKeyRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicKeyRequest.Marshal(b, m, deterministic)
+}
+func (dst *PublicKeyRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicKeyRequest.Merge(dst, src)
+}
+func (m *PublicKeyRequest) XXX_Size() int {
+	return xxx_messageInfo_PublicKeyRequest.Size(m)
+}
+func (m *PublicKeyRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicKeyRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicKeyRequest proto.InternalMessageInfo
+
+func (m *PublicKeyRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *PublicKeyRequest) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+type PublicKeyResponse struct {
+	Nick                 string   `protobuf:"bytes,1,opt,name=nick,proto3" json:"nick,omitempty"`
+	PublicKey            []byte   `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PublicKeyResponse) Reset()         { *m = PublicKeyResponse{} }
+func (m *PublicKeyResponse) String() string { return proto.CompactTextString(m) }
+func (*PublicKeyResponse) ProtoMessage()    {}
+func (*PublicKeyResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{26}
+}
+func (m *PublicKeyResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicKeyResponse.Unmarshal(m, b)
+}
+func (m *PublicKeyResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicKeyResponse.Marshal(b, m, deterministic)
+}
+func (dst *PublicKeyResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicKeyResponse.Merge(dst, src)
+}
+func (m *PublicKeyResponse) XXX_Size() int {
+	return xxx_messageInfo_PublicKeyResponse.Size(m)
+}
+func (m *PublicKeyResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicKeyResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicKeyResponse proto.InternalMessageInfo
+
+func (m *PublicKeyResponse) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *PublicKeyResponse) GetPublicKey() []byte {
+	if m != nil {
+		return m.PublicKey
+	}
+	return nil
+}
+
+type PublicMsgRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Msg                  string       `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *PublicMsgRequest) Reset()         { *m = PublicMsgRequest{} }
+func (m *PublicMsgRequest) String() string { return proto.CompactTextString(m) }
+func (*PublicMsgRequest) ProtoMessage()    {}
+func (*PublicMsgRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{27}
+}
+func (m *PublicMsgRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicMsgRequest.Unmarshal(m, b)
+}
+func (m *PublicMsgRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicMsgRequest.Marshal(b, m, deterministic)
+}
+func (dst *PublicMsgRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicMsgRequest.Merge(dst, src)
+}
+func (m *PublicMsgRequest) XXX_Size() int {
+	return xxx_messageInfo_PublicMsgRequest.Size(m)
+}
+func (m *PublicMsgRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicMsgRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicMsgRequest proto.InternalMessageInfo
+
+func (m *PublicMsgRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *PublicMsgRequest) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+type SendMsgResponse struct {
+	Status               SendMsgResponse_Status `protobuf:"varint,1,opt,name=status,proto3,enum=proto.SendMsgResponse_Status" json:"status,omitempty"`
+	Reason               string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	Delivered            uint32                 `protobuf:"varint,3,opt,name=delivered,proto3" json:"delivered,omitempty"`
+	Dropped              uint32                 `protobuf:"varint,4,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	Id                   uint64                 `protobuf:"varint,5,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *SendMsgResponse) Reset()         { *m = SendMsgResponse{} }
+func (m *SendMsgResponse) String() string { return proto.CompactTextString(m) }
+func (*SendMsgResponse) ProtoMessage()    {}
+func (*SendMsgResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{28}
+}
+func (m *SendMsgResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SendMsgResponse.Unmarshal(m, b)
+}
+func (m *SendMsgResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SendMsgResponse.Marshal(b, m, deterministic)
+}
+func (dst *SendMsgResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SendMsgResponse.Merge(dst, src)
+}
+func (m *SendMsgResponse) XXX_Size() int {
+	return xxx_messageInfo_SendMsgResponse.Size(m)
+}
+func (m *SendMsgResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendMsgResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendMsgResponse proto.InternalMessageInfo
+
+func (m *SendMsgResponse) GetStatus() SendMsgResponse_Status {
+	if m != nil {
+		return m.Status
+	}
+	return SendMsgResponse_UNKNOWN
+}
+
+func (m *SendMsgResponse) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *SendMsgResponse) GetDelivered() uint32 {
+	if m != nil {
+		return m.Delivered
+	}
+	return 0
+}
+
+func (m *SendMsgResponse) GetDropped() uint32 {
+	if m != nil {
+		return m.Dropped
+	}
+	return 0
+}
+
+func (m *SendMsgResponse) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	DroppedMsgs          uint64   `protobuf:"varint,1,opt,name=dropped_msgs,json=droppedMsgs,proto3" json:"dropped_msgs,omitempty"`
+	QueueLen             uint32   `protobuf:"varint,2,opt,name=queue_len,json=queueLen,proto3" json:"queue_len,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatsResponse) Reset()         { *m = StatsResponse{} }
+func (m *StatsResponse) String() string { return proto.CompactTextString(m) }
+func (*StatsResponse) ProtoMessage()    {}
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{29}
+}
+func (m *StatsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatsResponse.Unmarshal(m, b)
+}
+func (m *StatsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatsResponse.Marshal(b, m, deterministic)
+}
+func (dst *StatsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatsResponse.Merge(dst, src)
+}
+func (m *StatsResponse) XXX_Size() int {
+	return xxx_messageInfo_StatsResponse.Size(m)
+}
+func (m *StatsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatsResponse proto.InternalMessageInfo
+
+func (m *StatsResponse) GetDroppedMsgs() uint64 {
+	if m != nil {
+		return m.DroppedMsgs
+	}
+	return 0
+}
+
+func (m *StatsResponse) GetQueueLen() uint32 {
+	if m != nil {
+		return m.QueueLen
+	}
+	return 0
+}
+
+type ResumeRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Cursor               uint64       `protobuf:"varint,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *ResumeRequest) Reset()         { *m = ResumeRequest{} }
+func (m *ResumeRequest) String() string { return proto.CompactTextString(m) }
+func (*ResumeRequest) ProtoMessage()    {}
+func (*ResumeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{30}
+}
+func (m *ResumeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResumeRequest.Unmarshal(m, b)
+}
+func (m *ResumeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResumeRequest.Marshal(b, m, deterministic)
+}
+func (dst *ResumeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResumeRequest.Merge(dst, src)
+}
+func (m *ResumeRequest) XXX_Size() int {
+	return xxx_messageInfo_ResumeRequest.Size(m)
+}
+func (m *ResumeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResumeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResumeRequest proto.InternalMessageInfo
+
+func (m *ResumeRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *ResumeRequest) GetCursor() uint64 {
+	if m != nil {
+		return m.Cursor
+	}
+	return 0
+}
+
+type HistoryRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Limit                int32        `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	AfterId              uint64       `protobuf:"varint,5,opt,name=after_id,json=afterId,proto3" json:"after_id,omitempty"`
+	BeforeId             uint64       `protobuf:"varint,6,opt,name=before_id,json=beforeId,proto3" json:"before_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *HistoryRequest) Reset()         { *m = HistoryRequest{} }
+func (m *HistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*HistoryRequest) ProtoMessage()    {}
+func (*HistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{31}
+}
+func (m *HistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryRequest.Unmarshal(m, b)
+}
+func (m *HistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryRequest.Marshal(b, m, deterministic)
+}
+func (dst *HistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryRequest.Merge(dst, src)
+}
+func (m *HistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_HistoryRequest.Size(m)
+}
+func (m *HistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryRequest proto.InternalMessageInfo
+
+func (m *HistoryRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *HistoryRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *HistoryRequest) GetAfterId() uint64 {
+	if m != nil {
+		return m.AfterId
+	}
+	return 0
+}
+
+func (m *HistoryRequest) GetBeforeId() uint64 {
+	if m != nil {
+		return m.BeforeId
+	}
+	return 0
+}
+
+type HistoryResponse struct {
+	Msgs                 []*ChatServerMsg `protobuf:"bytes,1,rep,name=msgs,proto3" json:"msgs,omitempty"`
+	Ids                  []uint64         `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *HistoryResponse) Reset()         { *m = HistoryResponse{} }
+func (m *HistoryResponse) String() string { return proto.CompactTextString(m) }
+func (*HistoryResponse) ProtoMessage()    {}
+func (*HistoryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{32}
+}
+func (m *HistoryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HistoryResponse.Unmarshal(m, b)
+}
+func (m *HistoryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HistoryResponse.Marshal(b, m, deterministic)
+}
+func (dst *HistoryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HistoryResponse.Merge(dst, src)
+}
+func (m *HistoryResponse) XXX_Size() int {
+	return xxx_messageInfo_HistoryResponse.Size(m)
+}
+func (m *HistoryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_HistoryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HistoryResponse proto.InternalMessageInfo
+
+func (m *HistoryResponse) GetMsgs() []*ChatServerMsg {
+	if m != nil {
+		return m.Msgs
+	}
+	return nil
+}
+
+func (m *HistoryResponse) GetIds() []uint64 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type RoomRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Room                 string       `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *RoomRequest) Reset()         { *m = RoomRequest{} }
+func (m *RoomRequest) String() string { return proto.CompactTextString(m) }
+func (*RoomRequest) ProtoMessage()    {}
+func (*RoomRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{33}
+}
+func (m *RoomRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoomRequest.Unmarshal(m, b)
+}
+func (m *RoomRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoomRequest.Marshal(b, m, deterministic)
+}
+func (dst *RoomRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoomRequest.Merge(dst, src)
+}
+func (m *RoomRequest) XXX_Size() int {
+	return xxx_messageInfo_RoomRequest.Size(m)
+}
+func (m *RoomRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoomRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoomRequest proto.InternalMessageInfo
+
+func (m *RoomRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *RoomRequest) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+type RoomResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RoomResponse) Reset()         { *m = RoomResponse{} }
+func (m *RoomResponse) String() string { return proto.CompactTextString(m) }
+func (*RoomResponse) ProtoMessage()    {}
+func (*RoomResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{34}
+}
+func (m *RoomResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoomResponse.Unmarshal(m, b)
+}
+func (m *RoomResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoomResponse.Marshal(b, m, deterministic)
+}
+func (dst *RoomResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoomResponse.Merge(dst, src)
+}
+func (m *RoomResponse) XXX_Size() int {
+	return xxx_messageInfo_RoomResponse.Size(m)
+}
+func (m *RoomResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoomResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoomResponse proto.InternalMessageInfo
+
+type Room struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Members              []string `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Room) Reset()         { *m = Room{} }
+func (m *Room) String() string { return proto.CompactTextString(m) }
+func (*Room) ProtoMessage()    {}
+func (*Room) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{35}
+}
+func (m *Room) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Room.Unmarshal(m, b)
+}
+func (m *Room) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Room.Marshal(b, m, deterministic)
+}
+func (dst *Room) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Room.Merge(dst, src)
+}
+func (m *Room) XXX_Size() int {
+	return xxx_messageInfo_Room.Size(m)
+}
+func (m *Room) XXX_DiscardUnknown() {
+	xxx_messageInfo_Room.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Room proto.InternalMessageInfo
+
+func (m *Room) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Room) GetMembers() []string {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+type ListRoomsResponse struct {
+	Rooms                []*Room  `protobuf:"bytes,1,rep,name=rooms,proto3" json:"rooms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListRoomsResponse) Reset()         { *m = ListRoomsResponse{} }
+func (m *ListRoomsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListRoomsResponse) ProtoMessage()    {}
+func (*ListRoomsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{36}
+}
+func (m *ListRoomsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListRoomsResponse.Unmarshal(m, b)
+}
+func (m *ListRoomsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListRoomsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListRoomsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListRoomsResponse.Merge(dst, src)
+}
+func (m *ListRoomsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListRoomsResponse.Size(m)
+}
+func (m *ListRoomsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListRoomsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListRoomsResponse proto.InternalMessageInfo
+
+func (m *ListRoomsResponse) GetRooms() []*Room {
+	if m != nil {
+		return m.Rooms
+	}
+	return nil
+}
+
+type RoomMsgRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	Room                 string       `protobuf:"bytes,2,opt,name=room,proto3" json:"room,omitempty"`
+	Msg                  string       `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *RoomMsgRequest) Reset()         { *m = RoomMsgRequest{} }
+func (m *RoomMsgRequest) String() string { return proto.CompactTextString(m) }
+func (*RoomMsgRequest) ProtoMessage()    {}
+func (*RoomMsgRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{37}
+}
+func (m *RoomMsgRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RoomMsgRequest.Unmarshal(m, b)
+}
+func (m *RoomMsgRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RoomMsgRequest.Marshal(b, m, deterministic)
+}
+func (dst *RoomMsgRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RoomMsgRequest.Merge(dst, src)
+}
+func (m *RoomMsgRequest) XXX_Size() int {
+	return xxx_messageInfo_RoomMsgRequest.Size(m)
+}
+func (m *RoomMsgRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RoomMsgRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RoomMsgRequest proto.InternalMessageInfo
+
+func (m *RoomMsgRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *RoomMsgRequest) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+func (m *RoomMsgRequest) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+type AckRequest struct {
+	Creds                *Credentials `protobuf:"bytes,1,opt,name=creds,proto3" json:"creds,omitempty"`
+	UpTo                 uint64       `protobuf:"varint,2,opt,name=up_to,json=upTo,proto3" json:"up_to,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *AckRequest) Reset()         { *m = AckRequest{} }
+func (m *AckRequest) String() string { return proto.CompactTextString(m) }
+func (*AckRequest) ProtoMessage()    {}
+func (*AckRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{38}
+}
+func (m *AckRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AckRequest.Unmarshal(m, b)
+}
+func (m *AckRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AckRequest.Marshal(b, m, deterministic)
+}
+func (dst *AckRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AckRequest.Merge(dst, src)
+}
+func (m *AckRequest) XXX_Size() int {
+	return xxx_messageInfo_AckRequest.Size(m)
+}
+func (m *AckRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AckRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AckRequest proto.InternalMessageInfo
+
+func (m *AckRequest) GetCreds() *Credentials {
+	if m != nil {
+		return m.Creds
+	}
+	return nil
+}
+
+func (m *AckRequest) GetUpTo() uint64 {
+	if m != nil {
+		return m.UpTo
+	}
+	return 0
+}
+
+type AckResponse struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AckResponse) Reset()         { *m = AckResponse{} }
+func (m *AckResponse) String() string { return proto.CompactTextString(m) }
+func (*AckResponse) ProtoMessage()    {}
+func (*AckResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{39}
+}
+func (m *AckResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AckResponse.Unmarshal(m, b)
+}
+func (m *AckResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AckResponse.Marshal(b, m, deterministic)
+}
+func (dst *AckResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AckResponse.Merge(dst, src)
+}
+func (m *AckResponse) XXX_Size() int {
+	return xxx_messageInfo_AckResponse.Size(m)
+}
+func (m *AckResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AckResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AckResponse proto.InternalMessageInfo
+
+type ChatServerMsg struct {
+	// Types that are valid to be assigned to Msg:
+	//	*ChatServerMsg_PublicMsg
+	//	*ChatServerMsg_PrivateMsg
+	//	*ChatServerMsg_UserEvent
+	//	*ChatServerMsg_Heartbeat
+	//	*ChatServerMsg_Receipt
+	//	*ChatServerMsg_ServerShutdown
+	//	*ChatServerMsg_Typing
+	//	*ChatServerMsg_Reply
+	Msg                  isChatServerMsg_Msg `protobuf_oneof:"msg"`
+	Room                 string              `protobuf:"bytes,16,opt,name=room,proto3" json:"room,omitempty"`
+	Cursor               uint64              `protobuf:"varint,17,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *ChatServerMsg) Reset()         { *m = ChatServerMsg{} }
+func (m *ChatServerMsg) String() string { return proto.CompactTextString(m) }
+func (*ChatServerMsg) ProtoMessage()    {}
+func (*ChatServerMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{40}
+}
+func (m *ChatServerMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ChatServerMsg.Unmarshal(m, b)
+}
+func (m *ChatServerMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ChatServerMsg.Marshal(b, m, deterministic)
+}
+func (dst *ChatServerMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ChatServerMsg.Merge(dst, src)
+}
+func (m *ChatServerMsg) XXX_Size() int {
+	return xxx_messageInfo_ChatServerMsg.Size(m)
+}
+func (m *ChatServerMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_ChatServerMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ChatServerMsg proto.InternalMessageInfo
+
+type isChatServerMsg_Msg interface {
+	isChatServerMsg_Msg()
+}
+
+type ChatServerMsg_PublicMsg struct {
+	PublicMsg *PublicMsg `protobuf:"bytes,1,opt,name=public_msg,json=publicMsg,proto3,oneof"`
+}
+
+type ChatServerMsg_PrivateMsg struct {
+	PrivateMsg *PrivateMsg `protobuf:"bytes,2,opt,name=private_msg,json=privateMsg,proto3,oneof"`
+}
+
+type ChatServerMsg_UserEvent struct {
+	UserEvent *UserEvent `protobuf:"bytes,3,opt,name=user_event,json=userEvent,proto3,oneof"`
+}
+
+type ChatServerMsg_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,4,opt,name=heartbeat,proto3,oneof"`
+}
+
+type ChatServerMsg_Receipt struct {
+	Receipt *Receipt `protobuf:"bytes,5,opt,name=receipt,proto3,oneof"`
+}
+
+type ChatServerMsg_ServerShutdown struct {
+	ServerShutdown *ServerShutdown `protobuf:"bytes,6,opt,name=server_shutdown,json=serverShutdown,proto3,oneof"`
+}
+
+type ChatServerMsg_Typing struct {
+	Typing *Typing `protobuf:"bytes,7,opt,name=typing,proto3,oneof"`
+}
+
+type ChatServerMsg_Reply struct {
+	Reply *Reply `protobuf:"bytes,8,opt,name=reply,proto3,oneof"`
+}
+
+func (*ChatServerMsg_PublicMsg) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_PrivateMsg) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_UserEvent) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_Heartbeat) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_Receipt) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_ServerShutdown) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_Typing) isChatServerMsg_Msg() {}
+
+func (*ChatServerMsg_Reply) isChatServerMsg_Msg() {}
+
+func (m *ChatServerMsg) GetMsg() isChatServerMsg_Msg {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetPublicMsg() *PublicMsg {
+	if x, ok := m.GetMsg().(*ChatServerMsg_PublicMsg); ok {
+		return x.PublicMsg
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetPrivateMsg() *PrivateMsg {
+	if x, ok := m.GetMsg().(*ChatServerMsg_PrivateMsg); ok {
+		return x.PrivateMsg
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetUserEvent() *UserEvent {
+	if x, ok := m.GetMsg().(*ChatServerMsg_UserEvent); ok {
+		return x.UserEvent
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetHeartbeat() *Heartbeat {
+	if x, ok := m.GetMsg().(*ChatServerMsg_Heartbeat); ok {
+		return x.Heartbeat
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetReceipt() *Receipt {
+	if x, ok := m.GetMsg().(*ChatServerMsg_Receipt); ok {
+		return x.Receipt
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetServerShutdown() *ServerShutdown {
+	if x, ok := m.GetMsg().(*ChatServerMsg_ServerShutdown); ok {
+		return x.ServerShutdown
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetTyping() *Typing {
+	if x, ok := m.GetMsg().(*ChatServerMsg_Typing); ok {
+		return x.Typing
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetReply() *Reply {
+	if x, ok := m.GetMsg().(*ChatServerMsg_Reply); ok {
+		return x.Reply
+	}
+	return nil
+}
+
+func (m *ChatServerMsg) GetRoom() string {
+	if m != nil {
+		return m.Room
+	}
+	return ""
+}
+
+func (m *ChatServerMsg) GetCursor() uint64 {
+	if m != nil {
+		return m.Cursor
+	}
+	return 0
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*ChatServerMsg) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _ChatServerMsg_OneofMarshaler, _ChatServerMsg_OneofUnmarshaler, _ChatServerMsg_OneofSizer, []interface{}{
+		(*ChatServerMsg_PublicMsg)(nil),
+		(*ChatServerMsg_PrivateMsg)(nil),
+		(*ChatServerMsg_UserEvent)(nil),
+		(*ChatServerMsg_Heartbeat)(nil),
+		(*ChatServerMsg_Receipt)(nil),
+		(*ChatServerMsg_ServerShutdown)(nil),
+		(*ChatServerMsg_Typing)(nil),
+		(*ChatServerMsg_Reply)(nil),
+	}
+}
+
+func _ChatServerMsg_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*ChatServerMsg)
+	// msg
+	switch x := m.Msg.(type) {
+	case *ChatServerMsg_PublicMsg:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.PublicMsg); err != nil {
+			return err
+		}
+	case *ChatServerMsg_PrivateMsg:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.PrivateMsg); err != nil {
+			return err
+		}
+	case *ChatServerMsg_UserEvent:
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.UserEvent); err != nil {
+			return err
+		}
+	case *ChatServerMsg_Heartbeat:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Heartbeat); err != nil {
+			return err
+		}
+	case *ChatServerMsg_Receipt:
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Receipt); err != nil {
+			return err
+		}
+	case *ChatServerMsg_ServerShutdown:
+		b.EncodeVarint(6<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.ServerShutdown); err != nil {
+			return err
+		}
+	case *ChatServerMsg_Typing:
+		b.EncodeVarint(7<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Typing); err != nil {
+			return err
+		}
+	case *ChatServerMsg_Reply:
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Reply); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("ChatServerMsg.Msg has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _ChatServerMsg_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*ChatServerMsg)
+	switch tag {
+	case 1: // msg.public_msg
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PublicMsg)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_PublicMsg{msg}
+		return true, err
+	case 2: // msg.private_msg
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(PrivateMsg)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_PrivateMsg{msg}
+		return true, err
+	case 3: // msg.user_event
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(UserEvent)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_UserEvent{msg}
+		return true, err
+	case 4: // msg.heartbeat
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Heartbeat)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_Heartbeat{msg}
+		return true, err
+	case 5: // msg.receipt
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Receipt)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_Receipt{msg}
+		return true, err
+	case 6: // msg.server_shutdown
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ServerShutdown)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_ServerShutdown{msg}
+		return true, err
+	case 7: // msg.typing
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Typing)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_Typing{msg}
+		return true, err
+	case 8: // msg.reply
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Reply)
+		err := b.DecodeMessage(msg)
+		m.Msg = &ChatServerMsg_Reply{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _ChatServerMsg_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*ChatServerMsg)
+	// msg
+	switch x := m.Msg.(type) {
+	case *ChatServerMsg_PublicMsg:
+		s := proto.Size(x.PublicMsg)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_PrivateMsg:
+		s := proto.Size(x.PrivateMsg)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_UserEvent:
+		s := proto.Size(x.UserEvent)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_Heartbeat:
+		s := proto.Size(x.Heartbeat)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_Receipt:
+		s := proto.Size(x.Receipt)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_ServerShutdown:
+		s := proto.Size(x.ServerShutdown)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_Typing:
+		s := proto.Size(x.Typing)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *ChatServerMsg_Reply:
+		s := proto.Size(x.Reply)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type PrivateMsg struct {
+	To                   string        `protobuf:"bytes,1,opt,name=to,proto3" json:"to,omitempty"`
+	From                 *User         `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	Msg                  string        `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	TimeSent             int64         `protobuf:"varint,4,opt,name=time_sent,json=timeSent,proto3" json:"time_sent,omitempty"`
+	Id                   uint64        `protobuf:"varint,5,opt,name=id,proto3" json:"id,omitempty"`
+	Encrypted            *EncryptedMsg `protobuf:"bytes,6,opt,name=encrypted,proto3" json:"encrypted,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *PrivateMsg) Reset()         { *m = PrivateMsg{} }
+func (m *PrivateMsg) String() string { return proto.CompactTextString(m) }
+func (*PrivateMsg) ProtoMessage()    {}
+func (*PrivateMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{41}
+}
+func (m *PrivateMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrivateMsg.Unmarshal(m, b)
+}
+func (m *PrivateMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrivateMsg.Marshal(b, m, deterministic)
+}
+func (dst *PrivateMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrivateMsg.Merge(dst, src)
+}
+func (m *PrivateMsg) XXX_Size() int {
+	return xxx_messageInfo_PrivateMsg.Size(m)
+}
+func (m *PrivateMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrivateMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrivateMsg proto.InternalMessageInfo
+
+func (m *PrivateMsg) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *PrivateMsg) GetFrom() *User {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *PrivateMsg) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func (m *PrivateMsg) GetTimeSent() int64 {
+	if m != nil {
+		return m.TimeSent
+	}
+	return 0
+}
+
+func (m *PrivateMsg) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *PrivateMsg) GetEncrypted() *EncryptedMsg {
+	if m != nil {
+		return m.Encrypted
+	}
+	return nil
+}
+
+type PublicMsg struct {
+	From                 *User    `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	Msg                  string   `protobuf:"bytes,2,opt,name=msg,proto3" json:"msg,omitempty"`
+	TimeSent             int64    `protobuf:"varint,3,opt,name=time_sent,json=timeSent,proto3" json:"time_sent,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PublicMsg) Reset()         { *m = PublicMsg{} }
+func (m *PublicMsg) String() string { return proto.CompactTextString(m) }
+func (*PublicMsg) ProtoMessage()    {}
+func (*PublicMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{42}
+}
+func (m *PublicMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublicMsg.Unmarshal(m, b)
+}
+func (m *PublicMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublicMsg.Marshal(b, m, deterministic)
+}
+func (dst *PublicMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublicMsg.Merge(dst, src)
+}
+func (m *PublicMsg) XXX_Size() int {
+	return xxx_messageInfo_PublicMsg.Size(m)
+}
+func (m *PublicMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublicMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublicMsg proto.InternalMessageInfo
+
+func (m *PublicMsg) GetFrom() *User {
+	if m != nil {
+		return m.From
+	}
+	return nil
+}
+
+func (m *PublicMsg) GetMsg() string {
+	if m != nil {
+		return m.Msg
+	}
+	return ""
+}
+
+func (m *PublicMsg) GetTimeSent() int64 {
+	if m != nil {
+		return m.TimeSent
+	}
+	return 0
+}
+
+type UserEvent struct {
+	Event                UserEvent_EventType `protobuf:"varint,1,opt,name=event,proto3,enum=proto.UserEvent_EventType" json:"event,omitempty"`
+	User                 *User               `protobuf:"bytes,2,opt,name=user,proto3" json:"user,omitempty"`
+	Time                 int64               `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *UserEvent) Reset()         { *m = UserEvent{} }
+func (m *UserEvent) String() string { return proto.CompactTextString(m) }
+func (*UserEvent) ProtoMessage()    {}
+func (*UserEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{43}
+}
+func (m *UserEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UserEvent.Unmarshal(m, b)
+}
+func (m *UserEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UserEvent.Marshal(b, m, deterministic)
+}
+func (dst *UserEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UserEvent.Merge(dst, src)
+}
+func (m *UserEvent) XXX_Size() int {
+	return xxx_messageInfo_UserEvent.Size(m)
+}
+func (m *UserEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_UserEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UserEvent proto.InternalMessageInfo
+
+func (m *UserEvent) GetEvent() UserEvent_EventType {
+	if m != nil {
+		return m.Event
+	}
+	return UserEvent_UNKNOWN
+}
+
+func (m *UserEvent) GetUser() *User {
+	if m != nil {
+		return m.User
+	}
+	return nil
+}
+
+func (m *UserEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type Heartbeat struct {
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Heartbeat) Reset()         { *m = Heartbeat{} }
+func (m *Heartbeat) String() string { return proto.CompactTextString(m) }
+func (*Heartbeat) ProtoMessage()    {}
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{44}
+}
+func (m *Heartbeat) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Heartbeat.Unmarshal(m, b)
+}
+func (m *Heartbeat) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Heartbeat.Marshal(b, m, deterministic)
+}
+func (dst *Heartbeat) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Heartbeat.Merge(dst, src)
+}
+func (m *Heartbeat) XXX_Size() int {
+	return xxx_messageInfo_Heartbeat.Size(m)
+}
+func (m *Heartbeat) XXX_DiscardUnknown() {
+	xxx_messageInfo_Heartbeat.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Heartbeat proto.InternalMessageInfo
+
+type Receipt struct {
+	Type                 Receipt_Type `protobuf:"varint,1,opt,name=type,proto3,enum=proto.Receipt_Type" json:"type,omitempty"`
+	To                   string       `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Id                   uint64       `protobuf:"varint,3,opt,name=id,proto3" json:"id,omitempty"`
+	Time                 int64        `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *Receipt) Reset()         { *m = Receipt{} }
+func (m *Receipt) String() string { return proto.CompactTextString(m) }
+func (*Receipt) ProtoMessage()    {}
+func (*Receipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{45}
+}
+func (m *Receipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Receipt.Unmarshal(m, b)
+}
+func (m *Receipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Receipt.Marshal(b, m, deterministic)
+}
+func (dst *Receipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Receipt.Merge(dst, src)
+}
+func (m *Receipt) XXX_Size() int {
+	return xxx_messageInfo_Receipt.Size(m)
+}
+func (m *Receipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_Receipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Receipt proto.InternalMessageInfo
+
+func (m *Receipt) GetType() Receipt_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Receipt_UNKNOWN
+}
+
+func (m *Receipt) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *Receipt) GetId() uint64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Receipt) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type Typing struct {
+	From                 string   `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To                   string   `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Time                 int64    `protobuf:"varint,3,opt,name=time,proto3" json:"time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Typing) Reset()         { *m = Typing{} }
+func (m *Typing) String() string { return proto.CompactTextString(m) }
+func (*Typing) ProtoMessage()    {}
+func (*Typing) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{46}
+}
+func (m *Typing) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Typing.Unmarshal(m, b)
+}
+func (m *Typing) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Typing.Marshal(b, m, deterministic)
+}
+func (dst *Typing) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Typing.Merge(dst, src)
+}
+func (m *Typing) XXX_Size() int {
+	return xxx_messageInfo_Typing.Size(m)
+}
+func (m *Typing) XXX_DiscardUnknown() {
+	xxx_messageInfo_Typing.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Typing proto.InternalMessageInfo
+
+func (m *Typing) GetFrom() string {
+	if m != nil {
+		return m.From
+	}
+	return ""
+}
+
+func (m *Typing) GetTo() string {
+	if m != nil {
+		return m.To
+	}
+	return ""
+}
+
+func (m *Typing) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+type ServerShutdown struct {
+	Reason               string   `protobuf:"bytes,1,opt,name=reason,proto3" json:"reason,omitempty"`
+	ReconnectAfter       int64    `protobuf:"varint,2,opt,name=reconnect_after,json=reconnectAfter,proto3" json:"reconnect_after,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ServerShutdown) Reset()         { *m = ServerShutdown{} }
+func (m *ServerShutdown) String() string { return proto.CompactTextString(m) }
+func (*ServerShutdown) ProtoMessage()    {}
+func (*ServerShutdown) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{47}
+}
+func (m *ServerShutdown) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ServerShutdown.Unmarshal(m, b)
+}
+func (m *ServerShutdown) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ServerShutdown.Marshal(b, m, deterministic)
+}
+func (dst *ServerShutdown) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ServerShutdown.Merge(dst, src)
+}
+func (m *ServerShutdown) XXX_Size() int {
+	return xxx_messageInfo_ServerShutdown.Size(m)
+}
+func (m *ServerShutdown) XXX_DiscardUnknown() {
+	xxx_messageInfo_ServerShutdown.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ServerShutdown proto.InternalMessageInfo
+
+func (m *ServerShutdown) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *ServerShutdown) GetReconnectAfter() int64 {
+	if m != nil {
+		return m.ReconnectAfter
+	}
+	return 0
+}
+
+type ClusterMsg struct {
+	Node                 string         `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Nick                 string         `protobuf:"bytes,2,opt,name=nick,proto3" json:"nick,omitempty"`
+	Msg                  *ChatServerMsg `protobuf:"bytes,3,opt,name=msg,proto3" json:"msg,omitempty"`
+	WantReceipts         bool           `protobuf:"varint,4,opt,name=want_receipts,json=wantReceipts,proto3" json:"want_receipts,omitempty"`
+	Users                []*User        `protobuf:"bytes,5,rep,name=users,proto3" json:"users,omitempty"`
+	Leaving              bool           `protobuf:"varint,6,opt,name=leaving,proto3" json:"leaving,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
+}
+
+func (m *ClusterMsg) Reset()         { *m = ClusterMsg{} }
+func (m *ClusterMsg) String() string { return proto.CompactTextString(m) }
+func (*ClusterMsg) ProtoMessage()    {}
+func (*ClusterMsg) Descriptor() ([]byte, []int) {
+	return fileDescriptor_chat_584bd4a6d2888add, []int{48}
+}
+func (m *ClusterMsg) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ClusterMsg.Unmarshal(m, b)
+}
+func (m *ClusterMsg) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ClusterMsg.Marshal(b, m, deterministic)
+}
+func (dst *ClusterMsg) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ClusterMsg.Merge(dst, src)
+}
+func (m *ClusterMsg) XXX_Size() int {
+	return xxx_messageInfo_ClusterMsg.Size(m)
+}
+func (m *ClusterMsg) XXX_DiscardUnknown() {
+	xxx_messageInfo_ClusterMsg.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ClusterMsg proto.InternalMessageInfo
+
+func (m *ClusterMsg) GetNode() string {
+	if m != nil {
+		return m.Node
+	}
+	return ""
+}
+
+func (m *ClusterMsg) GetNick() string {
+	if m != nil {
+		return m.Nick
+	}
+	return ""
+}
+
+func (m *ClusterMsg) GetMsg() *ChatServerMsg {
+	if m != nil {
+		return m.Msg
+	}
+	return nil
+}
+
+func (m *ClusterMsg) GetWantReceipts() bool {
+	if m != nil {
+		return m.WantReceipts
+	}
+	return false
+}
+
+func (m *ClusterMsg) GetUsers() []*User {
+	if m != nil {
+		return m.Users
+	}
+	return nil
+}
+
+func (m *ClusterMsg) GetLeaving() bool {
+	if m != nil {
+		return m.Leaving
+	}
+	return false
+}
+
+func init() {
+	proto.RegisterType((*LoginRequest)(nil), "proto.LoginRequest")
+	proto.RegisterType((*RegisterRequest)(nil), "proto.RegisterRequest")
+	proto.RegisterType((*AccountRequest)(nil), "proto.AccountRequest")
+	proto.RegisterType((*AccountResponse)(nil), "proto.AccountResponse")
+	proto.RegisterType((*LogoutResponse)(nil), "proto.LogoutResponse")
+	proto.RegisterType((*User)(nil), "proto.User")
+	proto.RegisterType((*Presence)(nil), "proto.Presence")
+	proto.RegisterType((*Credentials)(nil), "proto.Credentials")
+	proto.RegisterType((*ListUsersResponse)(nil), "proto.ListUsersResponse")
+	proto.RegisterType((*ModerationRequest)(nil), "proto.ModerationRequest")
+	proto.RegisterType((*ModerationResponse)(nil), "proto.ModerationResponse")
+	proto.RegisterType((*BanRequest)(nil), "proto.BanRequest")
+	proto.RegisterType((*Ban)(nil), "proto.Ban")
+	proto.RegisterType((*ListBansResponse)(nil), "proto.ListBansResponse")
+	proto.RegisterType((*RoleRequest)(nil), "proto.RoleRequest")
+	proto.RegisterType((*ChatClientMsg)(nil), "proto.ChatClientMsg")
+	proto.RegisterType((*ChatOpen)(nil), "proto.ChatOpen")
+	proto.RegisterType((*Reply)(nil), "proto.Reply")
+	proto.RegisterType((*PresenceRequest)(nil), "proto.PresenceRequest")
+	proto.RegisterType((*TypingRequest)(nil), "proto.TypingRequest")
+	proto.RegisterType((*TypingResponse)(nil), "proto.TypingResponse")
+	proto.RegisterType((*PingRequest)(nil), "proto.PingRequest")
+	proto.RegisterType((*PingResponse)(nil), "proto.PingResponse")
+	proto.RegisterType((*PrivateMsgRequest)(nil), "proto.PrivateMsgRequest")
+	proto.RegisterType((*EncryptedMsg)(nil), "proto.EncryptedMsg")
+	proto.RegisterType((*PublicKeyRequest)(nil), "proto.PublicKeyRequest")
+	proto.RegisterType((*PublicKeyResponse)(nil), "proto.PublicKeyResponse")
+	proto.RegisterType((*PublicMsgRequest)(nil), "proto.PublicMsgRequest")
+	proto.RegisterType((*SendMsgResponse)(nil), "proto.SendMsgResponse")
+	proto.RegisterType((*StatsResponse)(nil), "proto.StatsResponse")
+	proto.RegisterType((*ResumeRequest)(nil), "proto.ResumeRequest")
+	proto.RegisterType((*HistoryRequest)(nil), "proto.HistoryRequest")
+	proto.RegisterType((*HistoryResponse)(nil), "proto.HistoryResponse")
+	proto.RegisterType((*RoomRequest)(nil), "proto.RoomRequest")
+	proto.RegisterType((*RoomResponse)(nil), "proto.RoomResponse")
+	proto.RegisterType((*Room)(nil), "proto.Room")
+	proto.RegisterType((*ListRoomsResponse)(nil), "proto.ListRoomsResponse")
+	proto.RegisterType((*RoomMsgRequest)(nil), "proto.RoomMsgRequest")
+	proto.RegisterType((*AckRequest)(nil), "proto.AckRequest")
+	proto.RegisterType((*AckResponse)(nil), "proto.AckResponse")
+	proto.RegisterType((*ChatServerMsg)(nil), "proto.ChatServerMsg")
+	proto.RegisterType((*PrivateMsg)(nil), "proto.PrivateMsg")
+	proto.RegisterType((*PublicMsg)(nil), "proto.PublicMsg")
+	proto.RegisterType((*UserEvent)(nil), "proto.UserEvent")
+	proto.RegisterType((*Heartbeat)(nil), "proto.Heartbeat")
+	proto.RegisterType((*Receipt)(nil), "proto.Receipt")
+	proto.RegisterType((*Typing)(nil), "proto.Typing")
+	proto.RegisterType((*ServerShutdown)(nil), "proto.ServerShutdown")
+	proto.RegisterType((*ClusterMsg)(nil), "proto.ClusterMsg")
+	proto.RegisterEnum("proto.User_Role", User_Role_name, User_Role_value)
+	proto.RegisterEnum("proto.Presence_Status", Presence_Status_name, Presence_Status_value)
+	proto.RegisterEnum("proto.SendMsgResponse_Status", SendMsgResponse_Status_name, SendMsgResponse_Status_value)
+	proto.RegisterEnum("proto.UserEvent_EventType", UserEvent_EventType_name, UserEvent_EventType_value)
+	proto.RegisterEnum("proto.Receipt_Type", Receipt_Type_name, Receipt_Type_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// UserServiceClient is the client API for UserService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type UserServiceClient interface {
 	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*Credentials, error)
 	Logout(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*LogoutResponse, error)
 	ListUsers(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListUsersResponse, error)
+	Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*AccountResponse, error)
+	ChangePassword(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*AccountResponse, error)
+	DeleteAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*AccountResponse, error)
+	GetPublicKey(ctx context.Context, in *PublicKeyRequest, opts ...grpc.CallOption) (*PublicKeyResponse, error)
+}
+
+type userServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewUserServiceClient(cc *grpc.ClientConn) UserServiceClient {
+	return &userServiceClient{cc}
+}
+
+func (c *userServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*Credentials, error) {
+	out := new(Credentials)
+	err := c.cc.Invoke(ctx, "/proto.UserService/Login", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Logout(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*LogoutResponse, error) {
+	out := new(LogoutResponse)
+	err := c.cc.Invoke(ctx, "/proto.UserService/Logout", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ListUsers(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListUsersResponse, error) {
+	out := new(ListUsersResponse)
+	err := c.cc.Invoke(ctx, "/proto.UserService/ListUsers", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*AccountResponse, error) {
+	out := new(AccountResponse)
+	err := c.cc.Invoke(ctx, "/proto.UserService/Register", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) ChangePassword(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*AccountResponse, error) {
+	out := new(AccountResponse)
+	err := c.cc.Invoke(ctx, "/proto.UserService/ChangePassword", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) DeleteAccount(ctx context.Context, in *AccountRequest, opts ...grpc.CallOption) (*AccountResponse, error) {
+	out := new(AccountResponse)
+	err := c.cc.Invoke(ctx, "/proto.UserService/DeleteAccount", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *userServiceClient) GetPublicKey(ctx context.Context, in *PublicKeyRequest, opts ...grpc.CallOption) (*PublicKeyResponse, error) {
+	out := new(PublicKeyResponse)
+	err := c.cc.Invoke(ctx, "/proto.UserService/GetPublicKey", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserServiceServer is the server API for UserService service.
+type UserServiceServer interface {
+	Login(context.Context, *LoginRequest) (*Credentials, error)
+	Logout(context.Context, *Credentials) (*LogoutResponse, error)
+	ListUsers(context.Context, *Credentials) (*ListUsersResponse, error)
+	Register(context.Context, *RegisterRequest) (*AccountResponse, error)
+	ChangePassword(context.Context, *AccountRequest) (*AccountResponse, error)
+	DeleteAccount(context.Context, *AccountRequest) (*AccountResponse, error)
+	GetPublicKey(context.Context, *PublicKeyRequest) (*PublicKeyResponse, error)
+}
+
+func RegisterUserServiceServer(s *grpc.Server, srv UserServiceServer) {
+	s.RegisterService(&_UserService_serviceDesc, srv)
+}
+
+func _UserService_Login_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Login(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/Login",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Login(ctx, req.(*LoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_Logout_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Logout(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/Logout",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Logout(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ListUsers",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ListUsers(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type userServiceClient struct {
+func _UserService_Register_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/Register",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).ChangePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/ChangePassword",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).ChangePassword(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/DeleteAccount",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).DeleteAccount(ctx, req.(*AccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _UserService_GetPublicKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PublicKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UserServiceServer).GetPublicKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.UserService/GetPublicKey",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UserServiceServer).GetPublicKey(ctx, req.(*PublicKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _UserService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.UserService",
+	HandlerType: (*UserServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Login",
+			Handler:    _UserService_Login_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _UserService_Logout_Handler,
+		},
+		{
+			MethodName: "ListUsers",
+			Handler:    _UserService_ListUsers_Handler,
+		},
+		{
+			MethodName: "Register",
+			Handler:    _UserService_Register_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _UserService_ChangePassword_Handler,
+		},
+		{
+			MethodName: "DeleteAccount",
+			Handler:    _UserService_DeleteAccount_Handler,
+		},
+		{
+			MethodName: "GetPublicKey",
+			Handler:    _UserService_GetPublicKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "chat.proto",
+}
+
+// ModerationServiceClient is the client API for ModerationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ModerationServiceClient interface {
+	Kick(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*ModerationResponse, error)
+	Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*ModerationResponse, error)
+	Unban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*ModerationResponse, error)
+	ListBans(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListBansResponse, error)
+	Mute(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*ModerationResponse, error)
+	Unmute(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*ModerationResponse, error)
+	SetRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*ModerationResponse, error)
+}
+
+type moderationServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewModerationServiceClient(cc *grpc.ClientConn) ModerationServiceClient {
+	return &moderationServiceClient{cc}
+}
+
+func (c *moderationServiceClient) Kick(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*ModerationResponse, error) {
+	out := new(ModerationResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/Kick", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moderationServiceClient) Ban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*ModerationResponse, error) {
+	out := new(ModerationResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/Ban", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moderationServiceClient) Unban(ctx context.Context, in *BanRequest, opts ...grpc.CallOption) (*ModerationResponse, error) {
+	out := new(ModerationResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/Unban", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moderationServiceClient) ListBans(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListBansResponse, error) {
+	out := new(ListBansResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/ListBans", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moderationServiceClient) Mute(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*ModerationResponse, error) {
+	out := new(ModerationResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/Mute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moderationServiceClient) Unmute(ctx context.Context, in *ModerationRequest, opts ...grpc.CallOption) (*ModerationResponse, error) {
+	out := new(ModerationResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/Unmute", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moderationServiceClient) SetRole(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*ModerationResponse, error) {
+	out := new(ModerationResponse)
+	err := c.cc.Invoke(ctx, "/proto.ModerationService/SetRole", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ModerationServiceServer is the server API for ModerationService service.
+type ModerationServiceServer interface {
+	Kick(context.Context, *ModerationRequest) (*ModerationResponse, error)
+	Ban(context.Context, *BanRequest) (*ModerationResponse, error)
+	Unban(context.Context, *BanRequest) (*ModerationResponse, error)
+	ListBans(context.Context, *Credentials) (*ListBansResponse, error)
+	Mute(context.Context, *ModerationRequest) (*ModerationResponse, error)
+	Unmute(context.Context, *ModerationRequest) (*ModerationResponse, error)
+	SetRole(context.Context, *RoleRequest) (*ModerationResponse, error)
+}
+
+func RegisterModerationServiceServer(s *grpc.Server, srv ModerationServiceServer) {
+	s.RegisterService(&_ModerationService_serviceDesc, srv)
+}
+
+func _ModerationService_Kick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).Kick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/Kick",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).Kick(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModerationService_Ban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).Ban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/Ban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).Ban(ctx, req.(*BanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModerationService_Unban_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).Unban(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/Unban",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).Unban(ctx, req.(*BanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModerationService_ListBans_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).ListBans(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/ListBans",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).ListBans(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModerationService_Mute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).Mute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/Mute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).Mute(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModerationService_Unmute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ModerationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).Unmute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/Unmute",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).Unmute(ctx, req.(*ModerationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ModerationService_SetRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ModerationServiceServer).SetRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ModerationService/SetRole",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ModerationServiceServer).SetRole(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _ModerationService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "proto.ModerationService",
+	HandlerType: (*ModerationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Kick",
+			Handler:    _ModerationService_Kick_Handler,
+		},
+		{
+			MethodName: "Ban",
+			Handler:    _ModerationService_Ban_Handler,
+		},
+		{
+			MethodName: "Unban",
+			Handler:    _ModerationService_Unban_Handler,
+		},
+		{
+			MethodName: "ListBans",
+			Handler:    _ModerationService_ListBans_Handler,
+		},
+		{
+			MethodName: "Mute",
+			Handler:    _ModerationService_Mute_Handler,
+		},
+		{
+			MethodName: "Unmute",
+			Handler:    _ModerationService_Unmute_Handler,
+		},
+		{
+			MethodName: "SetRole",
+			Handler:    _ModerationService_SetRole_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "chat.proto",
+}
+
+// ChatServiceClient is the client API for ChatService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type ChatServiceClient interface {
+	SendPrivate(ctx context.Context, in *PrivateMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error)
+	SendPublic(ctx context.Context, in *PublicMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error)
+	ListenForMessages(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (ChatService_ListenForMessagesClient, error)
+	ResumeListening(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (ChatService_ResumeListeningClient, error)
+	GetHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	CreateRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RoomResponse, error)
+	JoinRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RoomResponse, error)
+	LeaveRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RoomResponse, error)
+	ListRooms(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListRoomsResponse, error)
+	SendToRoom(ctx context.Context, in *RoomMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error)
+	AckMessages(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error)
+	MarkRead(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error)
+	GetStats(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*StatsResponse, error)
+	SetPresence(ctx context.Context, in *PresenceRequest, opts ...grpc.CallOption) (*Presence, error)
+	SendTyping(ctx context.Context, in *TypingRequest, opts ...grpc.CallOption) (*TypingResponse, error)
+	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error)
+	Chat(ctx context.Context, opts ...grpc.CallOption) (ChatService_ChatClient, error)
+}
+
+type chatServiceClient struct {
 	cc *grpc.ClientConn
 }
 
-func NewUserServiceClient(cc *grpc.ClientConn) UserServiceClient {
-	return &userServiceClient{cc}
+func NewChatServiceClient(cc *grpc.ClientConn) ChatServiceClient {
+	return &chatServiceClient{cc}
+}
+
+func (c *chatServiceClient) SendPrivate(ctx context.Context, in *PrivateMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error) {
+	out := new(SendMsgResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/SendPrivate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) SendPublic(ctx context.Context, in *PublicMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error) {
+	out := new(SendMsgResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/SendPublic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListenForMessages(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (ChatService_ListenForMessagesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ChatService_serviceDesc.Streams[0], "/proto.ChatService/ListenForMessages", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceListenForMessagesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_ListenForMessagesClient interface {
+	Recv() (*ChatServerMsg, error)
+	grpc.ClientStream
+}
+
+type chatServiceListenForMessagesClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceListenForMessagesClient) Recv() (*ChatServerMsg, error) {
+	m := new(ChatServerMsg)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *chatServiceClient) ResumeListening(ctx context.Context, in *ResumeRequest, opts ...grpc.CallOption) (ChatService_ResumeListeningClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ChatService_serviceDesc.Streams[1], "/proto.ChatService/ResumeListening", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceResumeListeningClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_ResumeListeningClient interface {
+	Recv() (*ChatServerMsg, error)
+	grpc.ClientStream
+}
+
+type chatServiceResumeListeningClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceResumeListeningClient) Recv() (*ChatServerMsg, error) {
+	m := new(ChatServerMsg)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (c *userServiceClient) Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*Credentials, error) {
-	out := new(Credentials)
-	err := grpc.Invoke(ctx, "/proto.UserService/Login", in, out, c.cc, opts...)
+func (c *chatServiceClient) GetHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/GetHistory", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *userServiceClient) Logout(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*LogoutResponse, error) {
-	out := new(LogoutResponse)
-	err := grpc.Invoke(ctx, "/proto.UserService/Logout", in, out, c.cc, opts...)
+func (c *chatServiceClient) CreateRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RoomResponse, error) {
+	out := new(RoomResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/CreateRoom", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *userServiceClient) ListUsers(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListUsersResponse, error) {
-	out := new(ListUsersResponse)
-	err := grpc.Invoke(ctx, "/proto.UserService/ListUsers", in, out, c.cc, opts...)
+func (c *chatServiceClient) JoinRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RoomResponse, error) {
+	out := new(RoomResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/JoinRoom", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-// Server API for UserService service
-
-type UserServiceServer interface {
-	Login(context.Context, *LoginRequest) (*Credentials, error)
-	Logout(context.Context, *Credentials) (*LogoutResponse, error)
-	ListUsers(context.Context, *Credentials) (*ListUsersResponse, error)
-}
-
-func RegisterUserServiceServer(s *grpc.Server, srv UserServiceServer) {
-	s.RegisterService(&_UserService_serviceDesc, srv)
-}
-
-func _UserService_Login_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
-	in := new(LoginRequest)
-	if err := codec.Unmarshal(buf, in); err != nil {
-		return nil, err
-	}
-	out, err := srv.(UserServiceServer).Login(ctx, in)
+func (c *chatServiceClient) LeaveRoom(ctx context.Context, in *RoomRequest, opts ...grpc.CallOption) (*RoomResponse, error) {
+	out := new(RoomResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/LeaveRoom", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func _UserService_Logout_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
-	in := new(Credentials)
-	if err := codec.Unmarshal(buf, in); err != nil {
-		return nil, err
-	}
-	out, err := srv.(UserServiceServer).Logout(ctx, in)
+func (c *chatServiceClient) ListRooms(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*ListRoomsResponse, error) {
+	out := new(ListRoomsResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/ListRooms", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func _UserService_ListUsers_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
-	in := new(Credentials)
-	if err := codec.Unmarshal(buf, in); err != nil {
-		return nil, err
-	}
-	out, err := srv.(UserServiceServer).ListUsers(ctx, in)
+func (c *chatServiceClient) SendToRoom(ctx context.Context, in *RoomMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error) {
+	out := new(SendMsgResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/SendToRoom", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-var _UserService_serviceDesc = grpc.ServiceDesc{
-	ServiceName: "proto.UserService",
-	HandlerType: (*UserServiceServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Login",
-			Handler:    _UserService_Login_Handler,
-		},
-		{
-			MethodName: "Logout",
-			Handler:    _UserService_Logout_Handler,
-		},
-		{
-			MethodName: "ListUsers",
-			Handler:    _UserService_ListUsers_Handler,
-		},
-	},
-	Streams: []grpc.StreamDesc{},
-}
-
-// Client API for ChatService service
-
-type ChatServiceClient interface {
-	SendPrivate(ctx context.Context, in *PrivateMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error)
-	SendPublic(ctx context.Context, in *PublicMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error)
-	ListenForMessages(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (ChatService_ListenForMessagesClient, error)
-}
-
-type chatServiceClient struct {
-	cc *grpc.ClientConn
+func (c *chatServiceClient) AckMessages(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error) {
+	out := new(AckResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/AckMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func NewChatServiceClient(cc *grpc.ClientConn) ChatServiceClient {
-	return &chatServiceClient{cc}
+func (c *chatServiceClient) MarkRead(ctx context.Context, in *AckRequest, opts ...grpc.CallOption) (*AckResponse, error) {
+	out := new(AckResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/MarkRead", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-func (c *chatServiceClient) SendPrivate(ctx context.Context, in *PrivateMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error) {
-	out := new(SendMsgResponse)
-	err := grpc.Invoke(ctx, "/proto.ChatService/SendPrivate", in, out, c.cc, opts...)
+func (c *chatServiceClient) GetStats(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (*StatsResponse, error) {
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/GetStats", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *chatServiceClient) SendPublic(ctx context.Context, in *PublicMsgRequest, opts ...grpc.CallOption) (*SendMsgResponse, error) {
-	out := new(SendMsgResponse)
-	err := grpc.Invoke(ctx, "/proto.ChatService/SendPublic", in, out, c.cc, opts...)
+func (c *chatServiceClient) SetPresence(ctx context.Context, in *PresenceRequest, opts ...grpc.CallOption) (*Presence, error) {
+	out := new(Presence)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/SetPresence", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *chatServiceClient) ListenForMessages(ctx context.Context, in *Credentials, opts ...grpc.CallOption) (ChatService_ListenForMessagesClient, error) {
-	stream, err := grpc.NewClientStream(ctx, &_ChatService_serviceDesc.Streams[0], c.cc, "/proto.ChatService/ListenForMessages", opts...)
+func (c *chatServiceClient) SendTyping(ctx context.Context, in *TypingRequest, opts ...grpc.CallOption) (*TypingResponse, error) {
+	out := new(TypingResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/SendTyping", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &chatServiceListenForMessagesClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
+	return out, nil
+}
+
+func (c *chatServiceClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingResponse, error) {
+	out := new(PingResponse)
+	err := c.cc.Invoke(ctx, "/proto.ChatService/Ping", in, out, opts...)
+	if err != nil {
 		return nil, err
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
+	return out, nil
+}
+
+func (c *chatServiceClient) Chat(ctx context.Context, opts ...grpc.CallOption) (ChatService_ChatClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_ChatService_serviceDesc.Streams[2], "/proto.ChatService/Chat", opts...)
+	if err != nil {
 		return nil, err
 	}
+	x := &chatServiceChatClient{stream}
 	return x, nil
 }
 
-type ChatService_ListenForMessagesClient interface {
+type ChatService_ChatClient interface {
+	Send(*ChatClientMsg) error
 	Recv() (*ChatServerMsg, error)
 	grpc.ClientStream
 }
 
-type chatServiceListenForMessagesClient struct {
+type chatServiceChatClient struct {
 	grpc.ClientStream
 }
 
-func (x *chatServiceListenForMessagesClient) Recv() (*ChatServerMsg, error) {
+func (x *chatServiceChatClient) Send(m *ChatClientMsg) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *chatServiceChatClient) Recv() (*ChatServerMsg, error) {
 	m := new(ChatServerMsg)
 	if err := x.ClientStream.RecvMsg(m); err != nil {
 		return nil, err
@@ -545,40 +4262,65 @@ func (x *chatServiceListenForMessagesClient) Recv() (*ChatServerMsg, error) {
 	return m, nil
 }
 
-// Server API for ChatService service
-
+// ChatServiceServer is the server API for ChatService service.
 type ChatServiceServer interface {
 	SendPrivate(context.Context, *PrivateMsgRequest) (*SendMsgResponse, error)
 	SendPublic(context.Context, *PublicMsgRequest) (*SendMsgResponse, error)
 	ListenForMessages(*Credentials, ChatService_ListenForMessagesServer) error
+	ResumeListening(*ResumeRequest, ChatService_ResumeListeningServer) error
+	GetHistory(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	CreateRoom(context.Context, *RoomRequest) (*RoomResponse, error)
+	JoinRoom(context.Context, *RoomRequest) (*RoomResponse, error)
+	LeaveRoom(context.Context, *RoomRequest) (*RoomResponse, error)
+	ListRooms(context.Context, *Credentials) (*ListRoomsResponse, error)
+	SendToRoom(context.Context, *RoomMsgRequest) (*SendMsgResponse, error)
+	AckMessages(context.Context, *AckRequest) (*AckResponse, error)
+	MarkRead(context.Context, *AckRequest) (*AckResponse, error)
+	GetStats(context.Context, *Credentials) (*StatsResponse, error)
+	SetPresence(context.Context, *PresenceRequest) (*Presence, error)
+	SendTyping(context.Context, *TypingRequest) (*TypingResponse, error)
+	Ping(context.Context, *PingRequest) (*PingResponse, error)
+	Chat(ChatService_ChatServer) error
 }
 
 func RegisterChatServiceServer(s *grpc.Server, srv ChatServiceServer) {
 	s.RegisterService(&_ChatService_serviceDesc, srv)
 }
 
-func _ChatService_SendPrivate_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
+func _ChatService_SendPrivate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PrivateMsgRequest)
-	if err := codec.Unmarshal(buf, in); err != nil {
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	out, err := srv.(ChatServiceServer).SendPrivate(ctx, in)
-	if err != nil {
-		return nil, err
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SendPrivate(ctx, in)
 	}
-	return out, nil
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/SendPrivate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SendPrivate(ctx, req.(*PrivateMsgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _ChatService_SendPublic_Handler(srv interface{}, ctx context.Context, codec grpc.Codec, buf []byte) (interface{}, error) {
+func _ChatService_SendPublic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PublicMsgRequest)
-	if err := codec.Unmarshal(buf, in); err != nil {
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	out, err := srv.(ChatServiceServer).SendPublic(ctx, in)
-	if err != nil {
-		return nil, err
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SendPublic(ctx, in)
 	}
-	return out, nil
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/SendPublic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SendPublic(ctx, req.(*PublicMsgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
 func _ChatService_ListenForMessages_Handler(srv interface{}, stream grpc.ServerStream) error {
@@ -602,6 +4344,269 @@ func (x *chatServiceListenForMessagesServer) Send(m *ChatServerMsg) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _ChatService_ResumeListening_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ResumeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServiceServer).ResumeListening(m, &chatServiceResumeListeningServer{stream})
+}
+
+type ChatService_ResumeListeningServer interface {
+	Send(*ChatServerMsg) error
+	grpc.ServerStream
+}
+
+type chatServiceResumeListeningServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceResumeListeningServer) Send(m *ChatServerMsg) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ChatService_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/GetHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetHistory(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_CreateRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).CreateRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/CreateRoom",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).CreateRoom(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_JoinRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).JoinRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/JoinRoom",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).JoinRoom(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_LeaveRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).LeaveRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/LeaveRoom",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).LeaveRoom(ctx, req.(*RoomRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListRooms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListRooms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/ListRooms",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListRooms(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_SendToRoom_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoomMsgRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SendToRoom(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/SendToRoom",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SendToRoom(ctx, req.(*RoomMsgRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_AckMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).AckMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/AckMessages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).AckMessages(ctx, req.(*AckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_MarkRead_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).MarkRead(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/MarkRead",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).MarkRead(ctx, req.(*AckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_GetStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Credentials)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/GetStats",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetStats(ctx, req.(*Credentials))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_SetPresence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PresenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SetPresence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/SetPresence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SetPresence(ctx, req.(*PresenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_SendTyping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TypingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SendTyping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/SendTyping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SendTyping(ctx, req.(*TypingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).Ping(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.ChatService/Ping",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).Ping(ctx, req.(*PingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_Chat_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ChatServiceServer).Chat(&chatServiceChatServer{stream})
+}
+
+type ChatService_ChatServer interface {
+	Send(*ChatServerMsg) error
+	Recv() (*ChatClientMsg, error)
+	grpc.ServerStream
+}
+
+type chatServiceChatServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceChatServer) Send(m *ChatServerMsg) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *chatServiceChatServer) Recv() (*ChatClientMsg, error) {
+	m := new(ChatClientMsg)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _ChatService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.ChatService",
 	HandlerType: (*ChatServiceServer)(nil),
@@ -614,6 +4619,54 @@ var _ChatService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SendPublic",
 			Handler:    _ChatService_SendPublic_Handler,
 		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _ChatService_GetHistory_Handler,
+		},
+		{
+			MethodName: "CreateRoom",
+			Handler:    _ChatService_CreateRoom_Handler,
+		},
+		{
+			MethodName: "JoinRoom",
+			Handler:    _ChatService_JoinRoom_Handler,
+		},
+		{
+			MethodName: "LeaveRoom",
+			Handler:    _ChatService_LeaveRoom_Handler,
+		},
+		{
+			MethodName: "ListRooms",
+			Handler:    _ChatService_ListRooms_Handler,
+		},
+		{
+			MethodName: "SendToRoom",
+			Handler:    _ChatService_SendToRoom_Handler,
+		},
+		{
+			MethodName: "AckMessages",
+			Handler:    _ChatService_AckMessages_Handler,
+		},
+		{
+			MethodName: "MarkRead",
+			Handler:    _ChatService_MarkRead_Handler,
+		},
+		{
+			MethodName: "GetStats",
+			Handler:    _ChatService_GetStats_Handler,
+		},
+		{
+			MethodName: "SetPresence",
+			Handler:    _ChatService_SetPresence_Handler,
+		},
+		{
+			MethodName: "SendTyping",
+			Handler:    _ChatService_SendTyping_Handler,
+		},
+		{
+			MethodName: "Ping",
+			Handler:    _ChatService_Ping_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -621,5 +4674,183 @@ var _ChatService_serviceDesc = grpc.ServiceDesc{
 			Handler:       _ChatService_ListenForMessages_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ResumeListening",
+			Handler:       _ChatService_ResumeListening_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Chat",
+			Handler:       _ChatService_Chat_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
+	Metadata: "chat.proto",
+}
+
+func init() { proto.RegisterFile("chat.proto", fileDescriptor_chat_584bd4a6d2888add) }
+
+var fileDescriptor_chat_584bd4a6d2888add = []byte{
+	// 2556 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0x5f, 0x73, 0xdb, 0xc6,
+	0x11, 0x27, 0x08, 0x80, 0x02, 0x97, 0x7f, 0x04, 0x9d, 0x65, 0x87, 0x61, 0x9a, 0x56, 0x41, 0x93,
+	0x46, 0x4d, 0x3a, 0x9a, 0x58, 0x71, 0xdc, 0xbf, 0x99, 0x86, 0x92, 0x68, 0x8b, 0x16, 0x25, 0xca,
+	0x27, 0x29, 0x99, 0x4c, 0x9b, 0x61, 0x41, 0xe2, 0x2c, 0xa3, 0x22, 0x01, 0x18, 0x00, 0x6d, 0xb3,
+	0x2f, 0x7d, 0xe8, 0xf4, 0x0b, 0x74, 0xfa, 0xd0, 0xe9, 0x27, 0xe8, 0x5b, 0x1f, 0x3b, 0x7d, 0x68,
+	0xbf, 0x41, 0x67, 0xfa, 0x09, 0xfa, 0x05, 0xfa, 0xda, 0x0f, 0xd0, 0xd9, 0xbb, 0x03, 0x08, 0x50,
+	0xa4, 0x6c, 0xd9, 0x7e, 0x11, 0x71, 0x7b, 0xbb, 0xb7, 0x7b, 0xb7, 0x7b, 0xbb, 0xbf, 0x5b, 0x01,
+	0x0c, 0x1f, 0xdb, 0xf1, 0x56, 0x10, 0xfa, 0xb1, 0x4f, 0x74, 0xfe, 0x63, 0x7d, 0x03, 0xd5, 0xae,
+	0x7f, 0xee, 0x7a, 0x94, 0x3d, 0x99, 0xb0, 0x28, 0x26, 0x04, 0x34, 0xcf, 0x1d, 0x5e, 0x34, 0x94,
+	0x0d, 0x65, 0xb3, 0x4c, 0xf9, 0x37, 0x69, 0x82, 0x11, 0xd8, 0x51, 0xf4, 0xcc, 0x0f, 0x9d, 0x46,
+	0x91, 0xd3, 0xd3, 0x31, 0x79, 0x17, 0x20, 0x98, 0x0c, 0x46, 0xee, 0xb0, 0x7f, 0xc1, 0xa6, 0x0d,
+	0x75, 0x43, 0xd9, 0xac, 0xd2, 0xb2, 0xa0, 0x1c, 0xb0, 0xa9, 0x75, 0x01, 0xab, 0x94, 0x9d, 0xbb,
+	0x51, 0xcc, 0xc2, 0x44, 0xc3, 0x26, 0xe8, 0xc3, 0x90, 0x39, 0x11, 0x57, 0x51, 0xd9, 0x26, 0xc2,
+	0x9e, 0xad, 0xdd, 0x90, 0x39, 0xcc, 0x8b, 0x5d, 0x7b, 0x14, 0x51, 0xc1, 0x90, 0xda, 0x52, 0x5c,
+	0x62, 0x8b, 0x9a, 0xb7, 0xc5, 0x9a, 0x42, 0xbd, 0x35, 0x1c, 0xfa, 0x13, 0x2f, 0xbe, 0xbe, 0xae,
+	0xab, 0xf6, 0xf8, 0x1e, 0x54, 0x3d, 0xf6, 0xac, 0x3f, 0xa7, 0xb7, 0xe2, 0xb1, 0x67, 0xc7, 0x89,
+	0xea, 0x35, 0x58, 0x4d, 0x55, 0x47, 0x81, 0xef, 0x45, 0xcc, 0x32, 0xa1, 0xde, 0xf5, 0xcf, 0xfd,
+	0xc9, 0x8c, 0xf2, 0x1f, 0x05, 0xb4, 0xb3, 0x88, 0x85, 0x0b, 0x0f, 0xf9, 0x7d, 0xa8, 0xc7, 0xee,
+	0x98, 0xf5, 0x47, 0x76, 0x14, 0xf7, 0x23, 0xc6, 0x3c, 0xae, 0x46, 0xa5, 0x55, 0xa4, 0x76, 0xed,
+	0x28, 0x3e, 0x61, 0xcc, 0x23, 0x1f, 0x83, 0x11, 0x84, 0x2c, 0x62, 0xde, 0x90, 0x35, 0x34, 0xbe,
+	0xa7, 0x55, 0xb9, 0xa7, 0x63, 0x49, 0xa6, 0x29, 0x03, 0x79, 0x1f, 0xb4, 0xd0, 0x1f, 0xb1, 0x86,
+	0xbe, 0xa1, 0x6c, 0xd6, 0xb7, 0x4d, 0xc9, 0x88, 0x16, 0x6c, 0x51, 0x7f, 0xc4, 0x28, 0x9f, 0x9d,
+	0xf3, 0x60, 0x69, 0xde, 0x83, 0x1f, 0x81, 0x86, 0xcc, 0xc4, 0x00, 0xed, 0xec, 0xa4, 0x4d, 0xcd,
+	0x02, 0xa9, 0x41, 0xf9, 0xb0, 0xb7, 0xd7, 0xa6, 0xad, 0xd3, 0x1e, 0x35, 0x15, 0x52, 0x06, 0xbd,
+	0xb5, 0x77, 0xd8, 0x39, 0x32, 0x8b, 0xd6, 0x5f, 0x14, 0x30, 0x12, 0x3b, 0xc8, 0x16, 0x94, 0xa2,
+	0xd8, 0x8e, 0x27, 0xe2, 0xf0, 0xeb, 0xdb, 0xb7, 0xe6, 0x0c, 0xdd, 0x3a, 0xe1, 0xb3, 0x54, 0x72,
+	0xe1, 0xa1, 0xc4, 0xec, 0x79, 0x9c, 0x78, 0x1b, 0xbf, 0x91, 0xe6, 0x3a, 0x23, 0xc6, 0x8f, 0xc2,
+	0xa0, 0xfc, 0xdb, 0xda, 0x83, 0x92, 0x90, 0x24, 0x15, 0x58, 0xe9, 0xdd, 0xbb, 0xd7, 0xed, 0x1c,
+	0xb5, 0xcd, 0x02, 0x01, 0x28, 0xf5, 0x8e, 0xf8, 0xb7, 0x82, 0xb6, 0xb6, 0xbe, 0x6a, 0x7d, 0x6d,
+	0x16, 0xf1, 0x6b, 0xe7, 0xec, 0xe4, 0x6b, 0x53, 0x45, 0xab, 0x3b, 0x47, 0x5f, 0x76, 0x4e, 0x3a,
+	0x3b, 0xdd, 0xb6, 0xa9, 0x59, 0x0f, 0xa1, 0x92, 0x89, 0x82, 0x85, 0x1e, 0x59, 0x07, 0x3d, 0xf6,
+	0x2f, 0x98, 0xc7, 0x2d, 0xaa, 0x52, 0x31, 0x20, 0x0d, 0x58, 0x89, 0x58, 0x14, 0xb9, 0xbe, 0x27,
+	0xe3, 0x20, 0x19, 0x5a, 0x77, 0x61, 0xad, 0xeb, 0x46, 0x31, 0x9e, 0x6f, 0x94, 0xf8, 0x9c, 0xbc,
+	0x07, 0xfa, 0x04, 0x09, 0x0d, 0x65, 0x43, 0xdd, 0xac, 0x6c, 0x57, 0x32, 0x4e, 0xa0, 0x62, 0xc6,
+	0xfa, 0xbd, 0x02, 0x6b, 0x87, 0xbe, 0xc3, 0x42, 0x3b, 0x76, 0x7d, 0xef, 0xcd, 0x5c, 0x93, 0x5b,
+	0x50, 0x0a, 0x99, 0x1d, 0xa5, 0x46, 0xca, 0x11, 0x86, 0xb9, 0x33, 0x11, 0x8a, 0x78, 0xfc, 0xa8,
+	0x34, 0x1d, 0x5b, 0xeb, 0x40, 0xb2, 0x66, 0xc8, 0xa0, 0xfd, 0x83, 0x02, 0xb0, 0x63, 0xbf, 0x21,
+	0xb3, 0x08, 0x68, 0xb6, 0xe3, 0x84, 0xd2, 0x28, 0xfe, 0x9d, 0x31, 0x55, 0x5b, 0x6a, 0xaa, 0x3e,
+	0x67, 0xea, 0x9f, 0x14, 0x50, 0x77, 0x6c, 0x6f, 0xa1, 0xdb, 0x12, 0x1d, 0xc5, 0x85, 0x3a, 0xf2,
+	0xc7, 0x51, 0x87, 0xe2, 0x60, 0x2a, 0xf5, 0x16, 0x07, 0x53, 0xe4, 0x63, 0xcf, 0x03, 0x37, 0x9c,
+	0x4a, 0x8d, 0x72, 0x44, 0xbe, 0x0f, 0x2b, 0x83, 0x69, 0x9f, 0x5f, 0xa6, 0xd2, 0x92, 0xcb, 0x54,
+	0x1a, 0x4c, 0xf1, 0xd7, 0xda, 0x06, 0x13, 0xa3, 0x60, 0xc7, 0xf6, 0x66, 0x41, 0xf0, 0x6d, 0xd0,
+	0x06, 0xb6, 0x97, 0xc4, 0x00, 0x48, 0x59, 0x3c, 0x55, 0x4e, 0xb7, 0x9e, 0x40, 0x85, 0xaf, 0xf1,
+	0x46, 0xce, 0x38, 0xb9, 0xf5, 0xea, 0x55, 0xb7, 0xde, 0xfa, 0xaf, 0x06, 0xb5, 0xdd, 0xc7, 0x76,
+	0xbc, 0x3b, 0x72, 0x99, 0x17, 0x1f, 0x46, 0xe7, 0xe4, 0x03, 0xd0, 0xfc, 0x80, 0x79, 0x0d, 0x25,
+	0x97, 0x56, 0x90, 0xa7, 0x17, 0x30, 0x6f, 0xbf, 0x40, 0xf9, 0x34, 0xb9, 0x0d, 0x25, 0x91, 0x1c,
+	0xb8, 0xd2, 0xca, 0xf6, 0x5b, 0xc9, 0xb5, 0xe6, 0xc4, 0xc3, 0xe8, 0x5c, 0xee, 0x62, 0xbf, 0x40,
+	0x25, 0x23, 0xb9, 0x03, 0x2b, 0x41, 0xe8, 0x3e, 0xb5, 0x63, 0x61, 0x54, 0x65, 0xbb, 0x91, 0xc8,
+	0x08, 0x6a, 0x4e, 0x28, 0x61, 0x25, 0x1f, 0xe3, 0x3e, 0xfc, 0xb1, 0x4c, 0x73, 0x37, 0xa5, 0x08,
+	0xf5, 0xfd, 0x71, 0x8e, 0x9f, 0x33, 0x91, 0xcf, 0xa0, 0x32, 0x0c, 0x99, 0x1d, 0xb3, 0x3e, 0x97,
+	0xd1, 0x73, 0x07, 0x87, 0x32, 0x33, 0x01, 0x10, 0x8c, 0x48, 0x24, 0xb7, 0xa1, 0xfc, 0x6b, 0xdf,
+	0xf5, 0x84, 0x50, 0xe9, 0x0a, 0x21, 0x03, 0xd9, 0xb8, 0xc8, 0xa7, 0x00, 0x23, 0x66, 0x3f, 0x95,
+	0x8a, 0x56, 0xae, 0x90, 0x29, 0x73, 0x3e, 0x2e, 0xf4, 0x01, 0xa8, 0xf6, 0xf0, 0xa2, 0x61, 0x70,
+	0xee, 0x35, 0xc9, 0xdd, 0x1a, 0x5e, 0xcc, 0x98, 0x71, 0x9e, 0x7c, 0x02, 0xe5, 0xb1, 0x1d, 0x5e,
+	0xf4, 0x43, 0x66, 0x3b, 0x8d, 0xf2, 0x72, 0x66, 0x03, 0xb9, 0x28, 0xb3, 0x1d, 0x72, 0x27, 0x53,
+	0x0f, 0x80, 0x0b, 0xcc, 0xa7, 0xd9, 0x8c, 0x54, 0x90, 0x49, 0xcd, 0xf1, 0x34, 0x70, 0xbd, 0xf3,
+	0x46, 0x85, 0xcb, 0xac, 0x4b, 0x99, 0x53, 0x4e, 0xcc, 0x38, 0x50, 0x70, 0x91, 0x4d, 0xd0, 0x38,
+	0x77, 0x35, 0xb7, 0xdb, 0xe3, 0x1c, 0x2f, 0xe7, 0xc0, 0x0b, 0xe5, 0x3a, 0x0d, 0x73, 0x43, 0xd9,
+	0xd4, 0x68, 0xd1, 0x75, 0x76, 0x74, 0x50, 0xc7, 0xd1, 0xb9, 0xe5, 0x80, 0x91, 0x04, 0xd2, 0x35,
+	0xa2, 0x9b, 0xdf, 0xda, 0x68, 0x32, 0x66, 0x3c, 0xd4, 0x0c, 0x2a, 0x47, 0x48, 0x1f, 0x4e, 0xc2,
+	0xc8, 0x17, 0x79, 0x44, 0xa3, 0x72, 0x64, 0xfd, 0x59, 0x01, 0x9d, 0xb2, 0x60, 0x34, 0x95, 0x66,
+	0x28, 0x89, 0x19, 0x78, 0x4f, 0x86, 0xbe, 0x23, 0xd6, 0xd1, 0x29, 0xff, 0xc6, 0xf4, 0xce, 0xc2,
+	0xd0, 0x4f, 0x92, 0x91, 0x18, 0x90, 0x8f, 0x40, 0x8b, 0x98, 0x17, 0x37, 0xb4, 0xdc, 0x61, 0x9e,
+	0x30, 0xcf, 0xe1, 0x51, 0x27, 0x2e, 0x34, 0xe5, 0x3c, 0xb9, 0x62, 0xac, 0xbf, 0xa0, 0x18, 0x5b,
+	0xbf, 0x85, 0xd5, 0x39, 0x97, 0x5c, 0xe3, 0x24, 0x66, 0xb5, 0xb4, 0x78, 0xad, 0x5a, 0xaa, 0xce,
+	0x6a, 0xa9, 0xf5, 0x0d, 0xd4, 0x72, 0xfe, 0xbd, 0x86, 0xfa, 0x3a, 0x14, 0x63, 0x5f, 0x26, 0x99,
+	0x62, 0xec, 0xe3, 0xf2, 0x3c, 0xfa, 0xe5, 0xf2, 0xf8, 0x8d, 0x70, 0x27, 0x59, 0x5e, 0x56, 0x8e,
+	0x0e, 0x54, 0x8e, 0x5f, 0x49, 0x9d, 0x09, 0x6a, 0xc4, 0x9e, 0x70, 0x7d, 0x1a, 0xc5, 0x4f, 0x6b,
+	0x03, 0xaa, 0xc7, 0x99, 0xa5, 0x13, 0x0e, 0x65, 0xc6, 0xf1, 0x37, 0x05, 0xd6, 0x2e, 0xa5, 0x93,
+	0xd7, 0xd8, 0xa2, 0xc9, 0x03, 0x57, 0xee, 0x10, 0x3f, 0xc9, 0x77, 0xa1, 0xf6, 0xcc, 0xf6, 0xe2,
+	0x7e, 0xc8, 0x86, 0xcc, 0x0d, 0xe2, 0x88, 0x87, 0x88, 0x41, 0xab, 0x48, 0xa4, 0x92, 0x86, 0x09,
+	0x85, 0x79, 0xc3, 0x70, 0x1a, 0xc4, 0xcc, 0x91, 0x31, 0x71, 0x43, 0x2a, 0x6d, 0x27, 0x74, 0xb4,
+	0x6f, 0xc6, 0x65, 0xfd, 0x06, 0xaa, 0xd9, 0x29, 0xc4, 0x63, 0x11, 0xf3, 0x1c, 0x16, 0x72, 0x3c,
+	0xa6, 0x08, 0x3c, 0x26, 0x28, 0x07, 0x6c, 0x8a, 0x66, 0x84, 0x6c, 0xe8, 0x06, 0x98, 0xb6, 0x39,
+	0x87, 0x40, 0x27, 0xd5, 0x94, 0x88, 0x4c, 0xeb, 0xa0, 0x7b, 0x3e, 0x86, 0xa5, 0x00, 0xe4, 0x62,
+	0x80, 0x7b, 0x1a, 0xf8, 0xcf, 0xb9, 0xdd, 0x55, 0x8a, 0x9f, 0xd6, 0x31, 0x98, 0xc7, 0x09, 0xd2,
+	0x7b, 0x23, 0xd5, 0xc7, 0xba, 0x07, 0x6b, 0x99, 0x15, 0xa5, 0xbb, 0x16, 0x95, 0xe9, 0x3c, 0xec,
+	0x2c, 0xce, 0xc3, 0xce, 0xa3, 0xc4, 0xb2, 0x57, 0xf2, 0xa6, 0xf4, 0x5e, 0x31, 0xf5, 0x9e, 0xf5,
+	0x3f, 0x05, 0x56, 0xe7, 0x6e, 0x31, 0xf9, 0x6c, 0x0e, 0xa1, 0xbe, 0xbb, 0xf8, 0xb6, 0xcf, 0x5f,
+	0xae, 0x19, 0x98, 0x28, 0xe6, 0xc0, 0xc4, 0xb7, 0xa0, 0xec, 0xb0, 0x91, 0xfb, 0x94, 0x85, 0x4c,
+	0xbc, 0x11, 0x6a, 0x74, 0x46, 0x40, 0xdc, 0xe8, 0x84, 0x7e, 0x10, 0x30, 0x87, 0x3b, 0xa0, 0x46,
+	0x93, 0xa1, 0x4c, 0x56, 0x7a, 0x92, 0xac, 0xac, 0x07, 0x59, 0x80, 0x7b, 0x76, 0x74, 0x70, 0xd4,
+	0xfb, 0xea, 0x48, 0xc0, 0xee, 0xbd, 0x76, 0xb7, 0xf3, 0x65, 0x9b, 0xb6, 0xf7, 0x4c, 0x05, 0xf1,
+	0xee, 0xc3, 0xb3, 0xf6, 0x59, 0x7b, 0xcf, 0x2c, 0x22, 0xdf, 0x1e, 0xed, 0x1d, 0x1f, 0xb7, 0xf7,
+	0x4c, 0x15, 0x07, 0xb4, 0xdd, 0x6d, 0x7d, 0xdd, 0xde, 0x33, 0x35, 0xab, 0x07, 0x35, 0x5c, 0x2b,
+	0x8b, 0x47, 0xab, 0x52, 0x6f, 0x7f, 0x1c, 0x9d, 0x47, 0xf2, 0x0a, 0x55, 0x24, 0xed, 0x30, 0x3a,
+	0x8f, 0xc8, 0x3b, 0x50, 0x7e, 0x32, 0x61, 0x13, 0xd6, 0x1f, 0x49, 0xec, 0x5b, 0xa3, 0x06, 0x27,
+	0x74, 0x99, 0x67, 0x3d, 0x84, 0x1a, 0xe5, 0x59, 0xf8, 0xfa, 0x4e, 0x99, 0xa5, 0xed, 0x62, 0x2e,
+	0x6d, 0xff, 0x51, 0x81, 0xfa, 0xbe, 0x1b, 0xc5, 0x7e, 0xf8, 0x0a, 0x31, 0xb8, 0x0e, 0xfa, 0xc8,
+	0x1d, 0xbb, 0x22, 0x61, 0xeb, 0x54, 0x0c, 0xc8, 0xdb, 0x60, 0xd8, 0x8f, 0x62, 0x16, 0xf6, 0xd3,
+	0x83, 0x5d, 0xe1, 0xe3, 0x8e, 0x83, 0xbb, 0x1b, 0xb0, 0x47, 0x7e, 0xc8, 0x70, 0xae, 0xc4, 0xe7,
+	0x0c, 0x41, 0xe8, 0x38, 0x0f, 0x34, 0xa3, 0x68, 0xaa, 0x0f, 0x34, 0x43, 0x35, 0x35, 0xeb, 0x10,
+	0x56, 0x53, 0xab, 0xe4, 0xe1, 0x6d, 0x82, 0x26, 0x0f, 0x4d, 0xcd, 0x54, 0x4d, 0xac, 0x6c, 0x27,
+	0x2c, 0x7c, 0xca, 0x42, 0x0c, 0x1a, 0xce, 0x81, 0x01, 0xe8, 0x3a, 0x98, 0xad, 0x55, 0x4c, 0x50,
+	0xae, 0x13, 0x59, 0x07, 0x50, 0xc9, 0xc0, 0x83, 0xeb, 0xdd, 0x32, 0x9e, 0x6c, 0x8b, 0x99, 0x64,
+	0x5b, 0x87, 0xaa, 0x58, 0x4c, 0xa6, 0xda, 0x3b, 0xf8, 0x48, 0xf3, 0xc7, 0xfc, 0xa2, 0xd9, 0x63,
+	0x96, 0x5e, 0x34, 0x7b, 0xcc, 0x30, 0xf0, 0xc6, 0x6c, 0x3c, 0x60, 0xa1, 0x30, 0xa7, 0x4c, 0x93,
+	0x61, 0xf2, 0x60, 0x41, 0xc9, 0xdc, 0x83, 0x05, 0x55, 0xcc, 0x3f, 0x58, 0xb8, 0x3a, 0x31, 0x63,
+	0xfd, 0x0a, 0xea, 0x79, 0x18, 0xf6, 0x7a, 0xbb, 0xb9, 0x9c, 0x6b, 0xad, 0x03, 0x80, 0x19, 0xe0,
+	0xb9, 0xc6, 0xea, 0x37, 0x40, 0x9f, 0x04, 0x7d, 0x99, 0xc8, 0x35, 0xaa, 0x4d, 0x82, 0x53, 0xdf,
+	0xaa, 0x41, 0x85, 0x2f, 0x26, 0xcf, 0xea, 0x5f, 0x2a, 0xd4, 0x72, 0x2e, 0x23, 0xb7, 0xd3, 0x54,
+	0x84, 0x66, 0x08, 0x25, 0xe6, 0x3c, 0xac, 0x45, 0x40, 0x17, 0x24, 0x03, 0x72, 0x07, 0x2a, 0x12,
+	0xa7, 0xf6, 0x93, 0x44, 0x33, 0xc3, 0x6a, 0xb3, 0x3a, 0x84, 0x70, 0x33, 0x48, 0x47, 0xa8, 0x08,
+	0x9f, 0x7c, 0x7d, 0xf6, 0x14, 0x21, 0x86, 0x9a, 0x53, 0x84, 0x00, 0xbd, 0x8d, 0x74, 0x54, 0x34,
+	0x49, 0x06, 0x08, 0x09, 0x1f, 0x33, 0x3b, 0x8c, 0x07, 0xcc, 0x4e, 0x40, 0x49, 0x22, 0xb1, 0x9f,
+	0xd0, 0x51, 0x22, 0x65, 0x22, 0x1f, 0xc1, 0x8a, 0x2c, 0x51, 0xb2, 0x00, 0xd5, 0x13, 0x17, 0x0a,
+	0x2a, 0x62, 0x6c, 0xc9, 0x40, 0xbe, 0x80, 0xd5, 0x88, 0x1f, 0x43, 0x3f, 0x7a, 0x3c, 0x89, 0x1d,
+	0xff, 0x99, 0xd7, 0x28, 0xe5, 0xe0, 0xb6, 0x38, 0xa4, 0x13, 0x39, 0xb9, 0x5f, 0xa0, 0xf5, 0x28,
+	0x47, 0x21, 0x1f, 0xa6, 0x50, 0x52, 0x40, 0xe1, 0x5a, 0x0e, 0x4a, 0x66, 0x30, 0xe4, 0xfb, 0xa0,
+	0x87, 0x88, 0xcd, 0x24, 0x08, 0xae, 0xa6, 0x46, 0x05, 0xa3, 0xe9, 0x7e, 0x81, 0x8a, 0xc9, 0x34,
+	0x3c, 0xcc, 0x4c, 0x78, 0xcc, 0xf2, 0xc6, 0x5a, 0x36, 0x6f, 0x24, 0xd8, 0xf2, 0xaf, 0x0a, 0xc0,
+	0xec, 0xc4, 0x65, 0x21, 0x57, 0xd2, 0x42, 0xfe, 0x1d, 0xd0, 0x1e, 0x85, 0x32, 0xe0, 0xe6, 0xde,
+	0xdf, 0x7c, 0x62, 0x41, 0xa5, 0x7f, 0x07, 0xca, 0xbc, 0x15, 0x93, 0x02, 0x41, 0x95, 0x1a, 0x48,
+	0x38, 0x41, 0x87, 0xcc, 0x65, 0xeb, 0x7c, 0xc5, 0x2f, 0xbd, 0x54, 0xc5, 0xff, 0x05, 0x94, 0xd3,
+	0xb0, 0x4a, 0xed, 0x53, 0x5e, 0x60, 0x5f, 0x71, 0x89, 0x7d, 0x6a, 0xde, 0x3e, 0xeb, 0xdf, 0x0a,
+	0x94, 0xcf, 0x32, 0xe1, 0xa3, 0x8b, 0x60, 0x13, 0x15, 0xae, 0x39, 0x1f, 0x6c, 0x5b, 0xfc, 0xef,
+	0xe9, 0x34, 0x60, 0x54, 0x30, 0xa2, 0x3d, 0x18, 0x7d, 0x0b, 0xcf, 0x6b, 0x22, 0x9b, 0x57, 0xa8,
+	0x4c, 0x2a, 0xe6, 0xdf, 0xd6, 0x2f, 0xa1, 0x9c, 0x2e, 0x94, 0xaf, 0x5a, 0x65, 0xd0, 0xbb, 0xbd,
+	0xfb, 0x9d, 0x23, 0x51, 0xb1, 0xba, 0xbd, 0xfb, 0xbd, 0xb3, 0x53, 0xd1, 0x97, 0x79, 0xd0, 0xeb,
+	0x1c, 0x99, 0x2a, 0x67, 0x68, 0xb7, 0xbe, 0x6c, 0x9b, 0x1a, 0xa9, 0x82, 0x71, 0x4c, 0xdb, 0x27,
+	0xed, 0xa3, 0xdd, 0xb6, 0xa9, 0x23, 0xcb, 0x41, 0x67, 0xf7, 0xc0, 0x2c, 0x59, 0x15, 0x28, 0xa7,
+	0xb1, 0x8e, 0xd5, 0x62, 0x45, 0x46, 0x32, 0xf9, 0x10, 0xb4, 0x78, 0x1a, 0x30, 0xb9, 0xb9, 0x1b,
+	0xf9, 0x38, 0xdf, 0xe2, 0xbb, 0xe2, 0x0c, 0x97, 0xd0, 0x9d, 0x70, 0xa2, 0x9a, 0x7d, 0x1f, 0xf0,
+	0x3d, 0x69, 0x99, 0x3d, 0xfd, 0x00, 0xb4, 0xcb, 0xdb, 0x99, 0x2b, 0xc2, 0x06, 0x68, 0xb4, 0xdd,
+	0xda, 0x33, 0x8b, 0xd6, 0x17, 0x50, 0x12, 0x21, 0x8f, 0x6b, 0xa5, 0x0e, 0x2d, 0x4b, 0x1f, 0x2e,
+	0x00, 0xd0, 0x97, 0xce, 0xf0, 0x21, 0xd4, 0xf3, 0xb7, 0x2d, 0x03, 0x34, 0x94, 0x1c, 0xd0, 0xf8,
+	0x10, 0x56, 0x43, 0x36, 0xf4, 0x3d, 0x8f, 0x0d, 0xe3, 0x3e, 0xaf, 0x6b, 0x7c, 0x69, 0x95, 0xd6,
+	0x53, 0x72, 0x0b, 0xa9, 0xd6, 0x3f, 0x15, 0x80, 0xdd, 0xd1, 0x24, 0x8a, 0x45, 0x9e, 0xc3, 0xea,
+	0x80, 0xaf, 0xa0, 0xa4, 0x3a, 0xe0, 0x2b, 0x68, 0x51, 0x07, 0xe1, 0x7b, 0xb3, 0x1b, 0xb1, 0xac,
+	0xca, 0xbd, 0x3c, 0x22, 0x4e, 0x1b, 0x60, 0xfa, 0xb2, 0x06, 0x18, 0x56, 0x28, 0x7c, 0x2a, 0x63,
+	0x12, 0x29, 0xf1, 0x15, 0x92, 0xe1, 0xf6, 0x3f, 0x54, 0xa8, 0x20, 0x27, 0x2a, 0x76, 0x87, 0x8c,
+	0x6c, 0x83, 0xce, 0xbb, 0xd5, 0x24, 0xf1, 0x75, 0xb6, 0x77, 0xdd, 0x5c, 0x50, 0x18, 0xac, 0x02,
+	0xa2, 0x3c, 0xd1, 0x87, 0x25, 0x0b, 0xe6, 0x9b, 0x37, 0x67, 0x0b, 0x65, 0x5b, 0xb5, 0x05, 0xf2,
+	0x53, 0x28, 0xa7, 0xdd, 0xbc, 0x85, 0x92, 0x49, 0x13, 0xe3, 0x52, 0xcf, 0xcf, 0x2a, 0x90, 0x9f,
+	0x81, 0x91, 0xb4, 0xbd, 0xc9, 0xad, 0x34, 0x2c, 0x73, 0x7d, 0xf0, 0xe6, 0xad, 0xf4, 0x65, 0x9f,
+	0xef, 0x1b, 0x17, 0x48, 0x0b, 0xea, 0xbb, 0x8f, 0x6d, 0xef, 0x9c, 0x25, 0xed, 0x65, 0x72, 0x73,
+	0x9e, 0xf7, 0x45, 0x4b, 0x7c, 0x01, 0xb5, 0x3d, 0x36, 0x62, 0x31, 0x93, 0x53, 0xd7, 0x5f, 0x61,
+	0x17, 0xaa, 0xf7, 0x59, 0x9c, 0x62, 0x79, 0x92, 0xef, 0xf3, 0xcc, 0xde, 0x0b, 0xcd, 0xc6, 0xe5,
+	0x89, 0x64, 0x91, 0xed, 0xbf, 0xab, 0xd9, 0xd6, 0x66, 0xe2, 0xc5, 0xcf, 0x41, 0x3b, 0xc0, 0x38,
+	0x4b, 0x24, 0x2f, 0x35, 0x3f, 0x9b, 0x6f, 0x2f, 0x98, 0x49, 0x2d, 0xfb, 0x4c, 0xf4, 0xfe, 0xd6,
+	0x32, 0x6d, 0xb4, 0x97, 0x11, 0xfb, 0x21, 0xe8, 0x67, 0xde, 0xe0, 0x15, 0x04, 0x7f, 0x0c, 0x46,
+	0xd2, 0xd1, 0x5b, 0x18, 0x08, 0x6f, 0x65, 0x02, 0x21, 0xdb, 0xf6, 0xb3, 0x0a, 0xb8, 0xd3, 0xc3,
+	0x49, 0xcc, 0x5e, 0x75, 0xa7, 0x3f, 0x87, 0xd2, 0x99, 0x37, 0x7e, 0x8d, 0x05, 0x7e, 0x02, 0x2b,
+	0x27, 0x2c, 0xe6, 0xfd, 0xfb, 0x59, 0x8f, 0x6a, 0xc4, 0x5e, 0x46, 0x76, 0xfb, 0x77, 0x06, 0x54,
+	0x92, 0x4b, 0x8f, 0x5e, 0x6b, 0x41, 0x05, 0x1f, 0x46, 0xb2, 0xd4, 0x92, 0xa5, 0x3d, 0xbc, 0xe6,
+	0x92, 0xa6, 0x09, 0xdf, 0x0f, 0xf0, 0x25, 0x44, 0x5b, 0x70, 0x59, 0xe7, 0xf0, 0x8a, 0x05, 0x5a,
+	0x02, 0xb1, 0x32, 0xef, 0x9e, 0x1f, 0x1e, 0xb2, 0x28, 0xb2, 0xcf, 0xd9, 0x62, 0x9f, 0x2c, 0xcc,
+	0x5a, 0x56, 0xe1, 0x13, 0x85, 0xb4, 0x60, 0x55, 0x3c, 0x60, 0xc4, 0x42, 0x98, 0xb1, 0xd7, 0xd3,
+	0x1b, 0x9a, 0x79, 0xd8, 0x5c, 0xb1, 0xc4, 0xe7, 0x00, 0xf7, 0x59, 0x2c, 0x1f, 0x07, 0xe9, 0xcd,
+	0xca, 0x3f, 0x61, 0x9a, 0xb7, 0xe6, 0xc9, 0x99, 0x40, 0x84, 0xdd, 0x59, 0x0b, 0x72, 0x41, 0xef,
+	0xb0, 0x79, 0x23, 0x47, 0xcb, 0x04, 0xbe, 0xf1, 0x20, 0x69, 0x43, 0x5e, 0x43, 0xec, 0x2e, 0x94,
+	0xbb, 0x69, 0x27, 0xf2, 0x1a, 0x72, 0x32, 0x03, 0x22, 0xf5, 0xc5, 0x19, 0x30, 0xf7, 0x88, 0xe0,
+	0x91, 0xcf, 0x5d, 0x7d, 0xea, 0x73, 0xad, 0x8b, 0xbb, 0xb7, 0x57, 0x38, 0xfa, 0x2e, 0xc7, 0xec,
+	0xa9, 0x8b, 0x2f, 0x77, 0x41, 0x9b, 0x24, 0x4b, 0x4a, 0xe5, 0x3e, 0x05, 0xe3, 0x30, 0xe9, 0x8d,
+	0xbe, 0xb4, 0xd0, 0x5d, 0x30, 0xee, 0xb3, 0x98, 0xbf, 0x93, 0xaf, 0x0c, 0xa6, 0xdc, 0x4b, 0xda,
+	0x2a, 0x90, 0x1f, 0xe1, 0x8d, 0x88, 0xd3, 0x7f, 0x78, 0x2d, 0xe9, 0xbc, 0x36, 0xe7, 0x9b, 0x82,
+	0xfc, 0x68, 0xc5, 0xe9, 0x08, 0xc4, 0xb0, 0xb0, 0xfd, 0xda, 0xbc, 0x39, 0x47, 0x4d, 0xd5, 0xde,
+	0x06, 0x0d, 0x9b, 0x61, 0x64, 0x41, 0x1f, 0xb6, 0x79, 0x23, 0x47, 0xcb, 0x58, 0xaa, 0x61, 0x18,
+	0x93, 0x6c, 0x4c, 0xa7, 0x9d, 0xff, 0x65, 0x91, 0xbe, 0xa9, 0x7c, 0xa2, 0x0c, 0x4a, 0x7c, 0xea,
+	0xd3, 0xff, 0x0f, 0x00, 0xcf, 0xef, 0xa1, 0xc9, 0x3b, 0x1e, 0x00, 0x00,
 }
@@ -0,0 +1,3 @@
+package ratelimit
+
+var NewLimiterWithClock = newLimiter
@@ -0,0 +1,175 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/logging"
+	"github.com/tormoder/chat/storage"
+)
+
+// Only calls to this service are limited.
+const limitedService = "/proto.ChatService/"
+
+type Config struct {
+	UserRate  float64 // Requests per second per logged-in user, 0 for no limit
+	UserBurst int
+	PeerRate  float64 // Requests per second per peer address, 0 for no limit
+	PeerBurst int
+}
+
+type Stats struct {
+	UserRejected uint64
+	PeerRejected uint64
+}
+
+// Interceptor limits the rate of ChatService requests per user and per
+// peer address. Rejected requests fail with ResourceExhausted and a
+// RetryInfo detail telling when to retry. Requests after the first on a
+// client stream are delayed instead, so that the stream survives bursts.
+type Interceptor struct {
+	users    *Limiter // nil if not limited
+	peers    *Limiter // nil if not limited
+	ustorage storage.UserStorage
+	log      *logging.Logger
+
+	userRejected uint64 // Accessed atomically
+	peerRejected uint64 // Accessed atomically
+}
+
+func NewInterceptor(cfg Config, userStorage storage.UserStorage, logger *logging.Logger) *Interceptor {
+	i := &Interceptor{ustorage: userStorage, log: logger}
+	if cfg.UserRate > 0 {
+		i.users = NewLimiter(cfg.UserRate, cfg.UserBurst)
+	}
+	if cfg.PeerRate > 0 {
+		i.peers = NewLimiter(cfg.PeerRate, cfg.PeerBurst)
+	}
+	return i
+}
+
+func (i *Interceptor) Stats() Stats {
+	return Stats{
+		UserRejected: atomic.LoadUint64(&i.userRejected),
+		PeerRejected: atomic.LoadUint64(&i.peerRejected),
+	}
+}
+
+func (i *Interceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if strings.HasPrefix(info.FullMethod, limitedService) {
+		if err := i.check(ctx, req); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (i *Interceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !strings.HasPrefix(info.FullMethod, limitedService) {
+		return handler(srv, ss)
+	}
+	return handler(srv, &limitedStream{ServerStream: ss, i: i, throttle: info.IsClientStream})
+}
+
+// limitedStream checks the first request of a stream when the handler
+// receives it. The requests that follow on a client stream are charged to
+// the credentials of the first.
+type limitedStream struct {
+	grpc.ServerStream
+	i        *Interceptor
+	throttle bool
+	first    interface{} // The first request, once received
+}
+
+func (s *limitedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.first == nil {
+		s.first = m
+		return s.i.check(s.Context(), m)
+	}
+	if s.throttle {
+		return s.i.wait(s.Context(), s.first)
+	}
+	return nil
+}
+
+func (i *Interceptor) check(ctx context.Context, req interface{}) error {
+	if limit, wait := i.allow(ctx, req); limit != "" {
+		return exhausted(limit, wait)
+	}
+	return nil
+}
+
+// wait delays until a request made with the credentials of req is allowed.
+func (i *Interceptor) wait(ctx context.Context, req interface{}) error {
+	for {
+		limit, wait := i.allow(ctx, req)
+		if limit == "" {
+			return nil
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
+
+// allow takes a token for req, or returns the limit that was exceeded and
+// how long until it allows a request. A rejected request takes no tokens.
+func (i *Interceptor) allow(ctx context.Context, req interface{}) (string, time.Duration) {
+	charged := "" // The address charged, if any
+	if i.peers != nil {
+		if host, ok := c.PeerHost(ctx); ok {
+			if ok, wait := i.peers.Allow(host); !ok {
+				atomic.AddUint64(&i.peerRejected, 1)
+				i.log.WithContext(ctx).Debug("rate limited", "limit", "address")
+				return "address", wait
+			}
+			charged = host
+		}
+	}
+	if i.users != nil {
+		// Only charge verified users, so no one can use up the
+		// requests of others. The handler rejects the rest.
+		if user, err := i.ustorage.CheckCredentials(c.RequestCreds(req)); err == nil {
+			if ok, wait := i.users.Allow(user.Nick); !ok {
+				if charged != "" {
+					i.peers.Refund(charged)
+				}
+				atomic.AddUint64(&i.userRejected, 1)
+				i.log.WithContext(ctx).Debug("rate limited", "limit", "user")
+				return "user", wait
+			}
+		}
+	}
+	return "", 0
+}
+
+func exhausted(limit string, wait time.Duration) error {
+	// Round up so that retrying after wait succeeds
+	wait = (wait + time.Millisecond - 1).Truncate(time.Millisecond)
+	st := status.New(
+		codes.ResourceExhausted,
+		fmt.Sprintf("%s rate limit exceeded, retry after %v", limit, wait),
+	)
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{
+		RetryDelay: ptypes.DurationProto(wait),
+	})
+	if err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
@@ -0,0 +1,231 @@
+package ratelimit_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/ratelimit"
+	"github.com/tormoder/chat/storage"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func TestLimiter(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	l := ratelimit.NewLimiterWithClock(2, 3, clock.now)
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d within burst was not allowed", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok {
+		t.Fatal("request after burst was allowed")
+	}
+	if wait != 500*time.Millisecond {
+		t.Errorf("wait: got %v, want %v", wait, 500*time.Millisecond)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Error("request for other key was not allowed")
+	}
+
+	clock.t = clock.t.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Error("request after refill was not allowed")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("second request after refill of one token was allowed")
+	}
+
+	clock.t = clock.t.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("request %d after full refill was not allowed", i+1)
+		}
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Error("refill exceeded burst")
+	}
+}
+
+// newLoggedIn returns a user storage with alice logged in, and alice's
+// credentials.
+func newLoggedIn(t *testing.T) (storage.UserStorage, *pb.Credentials) {
+	users := storage.NewInMemoryUserStorage()
+	creds := &pb.Credentials{Nick: "alice", Token: []byte("secret"), Session: "s1"}
+	err := users.AddUser(storage.User{
+		Online: true,
+		Sessions: []storage.Session{{
+			ID:     creds.Session,
+			Token:  creds.Token,
+			Expiry: time.Now().Add(time.Hour).Unix(),
+		}},
+		User: pb.User{Nick: "alice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return users, creds
+}
+
+// caller returns a function calling a unary method through i from host.
+func caller(i *ratelimit.Interceptor) func(host, method string, req interface{}) error {
+	return func(host, method string, req interface{}) error {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{
+			Addr: &net.TCPAddr{IP: net.ParseIP(host), Port: 40000},
+		})
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := i.Unary(ctx, req, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	users, creds := newLoggedIn(t)
+	i := ratelimit.NewInterceptor(
+		ratelimit.Config{UserRate: 0.001, UserBurst: 2, PeerRate: 0.001, PeerBurst: 4},
+		users,
+		logging.Discard(),
+	)
+	call := caller(i)
+	send := "/proto.ChatService/SendPublic"
+	req := &pb.PublicMsgRequest{Creds: creds, Msg: "hi"}
+
+	for n := 0; n < 2; n++ {
+		if err := call("10.0.0.1", send, req); err != nil {
+			t.Fatalf("request %d: %v", n+1, err)
+		}
+	}
+	err := call("10.0.0.2", send, req)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("user over limit: got %v, want ResourceExhausted", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, detail := range status.Convert(err).Details() {
+		if r, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = r
+		}
+	}
+	if retry == nil || retry.RetryDelay.GetSeconds() <= 0 {
+		t.Errorf("missing or invalid retry info: %v", retry)
+	}
+
+	// Requests with bad credentials only count against the address.
+	bad := &pb.PublicMsgRequest{Creds: &pb.Credentials{Nick: "alice"}}
+	if err := call("10.0.0.1", send, bad); err != nil {
+		t.Fatalf("request with bad credentials: %v", err)
+	}
+	if err := call("10.0.0.1", send, bad); err != nil {
+		t.Fatalf("request with bad credentials: %v", err)
+	}
+	if err := call("10.0.0.1", send, bad); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("address over limit: got %v, want ResourceExhausted", err)
+	}
+
+	// Other services are not limited.
+	if err := call("10.0.0.1", "/proto.UserService/Login", &pb.LoginRequest{Nick: "bob"}); err != nil {
+		t.Fatalf("user service request: %v", err)
+	}
+
+	stats := i.Stats()
+	if stats.UserRejected != 1 || stats.PeerRejected != 1 {
+		t.Errorf("stats: got %+v, want one rejection of each", stats)
+	}
+}
+
+func TestUserRejectedFreesAddress(t *testing.T) {
+	users, creds := newLoggedIn(t)
+	i := ratelimit.NewInterceptor(
+		ratelimit.Config{UserRate: 0.001, UserBurst: 1, PeerRate: 0.001, PeerBurst: 3},
+		users,
+		logging.Discard(),
+	)
+	call := caller(i)
+	send := "/proto.ChatService/SendPublic"
+	req := &pb.PublicMsgRequest{Creds: creds, Msg: "hi"}
+
+	if err := call("10.0.0.1", send, req); err != nil {
+		t.Fatal(err)
+	}
+	for n := 0; n < 5; n++ {
+		if err := call("10.0.0.1", send, req); status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("user over limit: got %v, want ResourceExhausted", err)
+		}
+	}
+	// The requests rejected for the user took nothing from the address
+	other := &pb.PublicMsgRequest{Creds: &pb.Credentials{Nick: "bob"}}
+	for n := 0; n < 2; n++ {
+		if err := call("10.0.0.1", send, other); err != nil {
+			t.Fatalf("other user's request %d: %v", n+1, err)
+		}
+	}
+	if err := call("10.0.0.1", send, other); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("address over limit: got %v, want ResourceExhausted", err)
+	}
+	if stats := i.Stats(); stats.UserRejected != 5 || stats.PeerRejected != 1 {
+		t.Errorf("stats: got %+v, want 5 user and 1 address rejections", stats)
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeServerStream) RecvMsg(interface{}) error {
+	return nil
+}
+
+func TestStreamThrottled(t *testing.T) {
+	i := ratelimit.NewInterceptor(
+		ratelimit.Config{PeerRate: 20, PeerBurst: 1},
+		storage.NewInMemoryUserStorage(),
+		logging.Discard(),
+	)
+	ctx, cancel := context.WithCancel(peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000},
+	}))
+	ss := &fakeServerStream{ctx: ctx}
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.ChatService/Chat", IsClientStream: true}
+
+	err := i.Stream(nil, ss, info, func(_ interface{}, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg(&pb.ChatClientMsg{}); err != nil {
+			t.Fatalf("first request: %v", err)
+		}
+		start := time.Now()
+		if err := stream.RecvMsg(&pb.ChatClientMsg{}); err != nil {
+			t.Fatalf("request over limit: got %v, want it delayed", err)
+		}
+		if waited := time.Since(start); waited < 25*time.Millisecond {
+			t.Errorf("request over limit delayed %v, want about 50ms", waited)
+		}
+		cancel()
+		return stream.RecvMsg(&pb.ChatClientMsg{})
+	})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("request after cancel: got %v, want Canceled", err)
+	}
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// appendLog is a file of newline separated JSON records that is only ever
+// appended to. A torn trailing record left by a crash is truncated away when
+// the log is opened.
+type appendLog struct {
+	path string
+	f    *os.File
+}
+
+func openAppendLog(path string, replay func(line []byte) error) (*appendLog, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	end, err := replayLines(f, replay)
+	if err == nil {
+		err = f.Truncate(end)
+	}
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &appendLog{path: path, f: f}, nil
+}
+
+// replayLines calls replay for every complete line in r and returns the
+// offset just past the last complete line.
+func replayLines(r io.Reader, replay func(line []byte) error) (int64, error) {
+	var end int64
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err == io.EOF {
+			return end, nil
+		}
+		if err != nil {
+			return end, err
+		}
+		end += int64(len(line))
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := replay(line); err != nil {
+			return end, err
+		}
+	}
+}
+
+func (l *appendLog) append(record interface{}) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = l.f.Write(append(b, '\n'))
+	return err
+}
+
+// compact replaces the log with the given snapshot records.
+func (l *appendLog) compact(records []interface{}) error {
+	tmpPath := l.path + ".tmp"
+	tmp, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	for _, record := range records {
+		b, err := json.Marshal(record)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(b)
+		w.WriteByte('\n')
+	}
+	if err = w.Flush(); err == nil {
+		err = tmp.Sync()
+	}
+	tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	l.f.Close()
+	if err = os.Rename(tmpPath, l.path); err != nil {
+		return err
+	}
+	l.f, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0600)
+	return err
+}
+
+func (l *appendLog) sync() error {
+	return l.f.Sync()
+}
+
+func (l *appendLog) close() error {
+	err := l.f.Sync()
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
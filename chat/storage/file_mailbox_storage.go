@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const mailboxLogFile = "mailboxes.log"
+
+// mailboxRecord is either a delivered message, an acknowledgement of all
+// messages for To with an id up to ID if Ack is set, or the removal of the
+// mailbox of To if Purge is set.
+type mailboxRecord struct {
+	Ack       bool             `json:"ack,omitempty"`
+	Purge     bool             `json:"purge,omitempty"`
+	To        string           `json:"to"`
+	ID        uint64           `json:"id"`
+	From      string           `json:"from,omitempty"`
+	Msg       string           `json:"msg,omitempty"`
+	Encrypted *pb.EncryptedMsg `json:"encrypted,omitempty"`
+	TimeSent  int64            `json:"time_sent,omitempty"`
+}
+
+func newMailboxRecord(msg *pb.PrivateMsg) mailboxRecord {
+	return mailboxRecord{
+		To:        msg.To,
+		ID:        msg.Id,
+		From:      msg.GetFrom().GetNick(),
+		Msg:       msg.Msg,
+		Encrypted: msg.Encrypted,
+		TimeSent:  msg.TimeSent,
+	}
+}
+
+// FileMailboxStorage keeps mailboxes in memory and records deliveries and
+// acknowledgements in an append-only log under its data directory. The log
+// is replayed and compacted when the storage is opened.
+type FileMailboxStorage struct {
+	*InMemoryMailboxStorage
+	log *appendLog
+	mu  sync.Mutex // Serializes log writes with the in-memory updates
+}
+
+func NewFileMailboxStorage(dir string) (*FileMailboxStorage, error) {
+	mem := NewInMemoryMailboxStorage()
+	log, err := openAppendLog(
+		filepath.Join(dir, mailboxLogFile),
+		func(line []byte) error {
+			var r mailboxRecord
+			if err := json.Unmarshal(line, &r); err != nil {
+				return fmt.Errorf("corrupt mailbox log record: %v", err)
+			}
+			if r.Purge {
+				delete(mem.boxes, r.To)
+				return nil
+			}
+			if r.Ack {
+				if box := mem.box(r.To); r.ID > box.nextID {
+					box.nextID = r.ID
+				}
+				mem.ack(r.To, r.ID)
+				return nil
+			}
+			mem.store(&pb.PrivateMsg{
+				To:        r.To,
+				From:      &pb.User{Nick: r.From},
+				Msg:       r.Msg,
+				Encrypted: r.Encrypted,
+				TimeSent:  r.TimeSent,
+				Id:        r.ID,
+			})
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The snapshot keeps an acknowledgement for each mailbox so that ids
+	// are never reused after a restart.
+	var snapshot []interface{}
+	for nick, box := range mem.boxes {
+		if acked := box.nextID - uint64(len(box.msgs)); acked > 0 {
+			snapshot = append(snapshot, mailboxRecord{Ack: true, To: nick, ID: acked})
+		}
+		for _, m := range box.msgs {
+			snapshot = append(snapshot, newMailboxRecord(m))
+		}
+	}
+	if err = log.compact(snapshot); err != nil {
+		log.close()
+		return nil, err
+	}
+
+	return &FileMailboxStorage{
+		InMemoryMailboxStorage: mem,
+		log:                    log,
+	}, nil
+}
+
+func (ms *FileMailboxStorage) Deliver(msg *pb.PrivateMsg) (*pb.PrivateMsg, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	mem := ms.InMemoryMailboxStorage
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	m, err := mem.prepare(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err = ms.log.append(newMailboxRecord(m)); err != nil {
+		return nil, err
+	}
+	mem.store(m)
+	return copyPrivateMsg(m), nil
+}
+
+func (ms *FileMailboxStorage) Ack(nick string, upTo uint64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	mem := ms.InMemoryMailboxStorage
+	mem.mu.Lock()
+	defer mem.mu.Unlock()
+	box, found := mem.boxes[nick]
+	if !found || len(box.msgs) == 0 || box.msgs[0].Id > upTo {
+		return nil
+	}
+	if upTo > box.nextID {
+		upTo = box.nextID
+	}
+	err := ms.log.append(mailboxRecord{Ack: true, To: nick, ID: upTo})
+	if err != nil {
+		return err
+	}
+	mem.ack(nick, upTo)
+	return nil
+}
+
+func (ms *FileMailboxStorage) Purge(nick string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.log.append(mailboxRecord{Purge: true, To: nick}); err != nil {
+		return err
+	}
+	return ms.InMemoryMailboxStorage.Purge(nick)
+}
+
+func (ms *FileMailboxStorage) Close() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.log.close()
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const msgLogFile = "messages.log"
+
+// msgRecord is a message or, if Purge is set, the removal of the private
+// messages to or from From. ID is the id of the message, or for a purge the
+// last id given out, which is all a purge without From records. Records
+// written before ids were logged have none.
+type msgRecord struct {
+	ID        uint64           `json:"id,omitempty"`
+	Purge     bool             `json:"purge,omitempty"`
+	Private   bool             `json:"private,omitempty"`
+	From      string           `json:"from"`
+	To        string           `json:"to,omitempty"`
+	Msg       string           `json:"msg"`
+	Encrypted *pb.EncryptedMsg `json:"encrypted,omitempty"`
+	TimeSent  int64            `json:"time_sent"`
+}
+
+// FileMessageStorage keeps the message history in memory and appends every
+// message to a log under its data directory. The log is replayed and
+// compacted to the kept history when the storage is opened.
+type FileMessageStorage struct {
+	*InMemoryMessageStorage
+	log *appendLog
+	mu  sync.Mutex // Serializes log writes with the in-memory updates
+}
+
+func NewFileMessageStorage(dir string) (*FileMessageStorage, error) {
+	mem := NewInMemoryMessageStorage()
+	log, err := openAppendLog(
+		filepath.Join(dir, msgLogFile),
+		func(line []byte) error {
+			var r msgRecord
+			if err := json.Unmarshal(line, &r); err != nil {
+				return fmt.Errorf("corrupt message log record: %v", err)
+			}
+			if r.Purge {
+				if r.From != "" {
+					mem.purge(r.From)
+				}
+				if r.ID > mem.lastID {
+					mem.lastID = r.ID
+				}
+				return nil
+			}
+			if r.ID <= mem.lastID {
+				r.ID = mem.lastID + 1
+			}
+			mem.insert(r.ID, r.msg())
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := make([]interface{}, 0, len(mem.msgs)+1)
+	for _, msg := range mem.msgs {
+		snapshot = append(snapshot, newMsgRecord(msg.ID, msg.Msg))
+	}
+	if n := len(mem.msgs); mem.lastID != 0 && (n == 0 || mem.msgs[n-1].ID < mem.lastID) {
+		// The newest messages were purged, keep their ids from being
+		// given out again
+		snapshot = append(snapshot, msgRecord{ID: mem.lastID, Purge: true})
+	}
+	if err = log.compact(snapshot); err != nil {
+		log.close()
+		return nil, err
+	}
+	return &FileMessageStorage{
+		InMemoryMessageStorage: mem,
+		log:                    log,
+	}, nil
+}
+
+func newMsgRecord(id uint64, msg *pb.ChatServerMsg) msgRecord {
+	if pmsg := msg.GetPrivateMsg(); pmsg != nil {
+		return msgRecord{
+			ID:        id,
+			Private:   true,
+			From:      pmsg.GetFrom().GetNick(),
+			To:        pmsg.To,
+			Msg:       pmsg.Msg,
+			Encrypted: pmsg.Encrypted,
+			TimeSent:  pmsg.TimeSent,
+		}
+	}
+	pmsg := msg.GetPublicMsg()
+	return msgRecord{
+		ID:       id,
+		From:     pmsg.GetFrom().GetNick(),
+		Msg:      pmsg.Msg,
+		TimeSent: pmsg.TimeSent,
+	}
+}
+
+func (r *msgRecord) msg() *pb.ChatServerMsg {
+	from := &pb.User{Nick: r.From}
+	if r.Private {
+		return &pb.ChatServerMsg{
+			Msg: &pb.ChatServerMsg_PrivateMsg{
+				PrivateMsg: &pb.PrivateMsg{
+					To:        r.To,
+					From:      from,
+					Msg:       r.Msg,
+					Encrypted: r.Encrypted,
+					TimeSent:  r.TimeSent,
+				},
+			},
+		}
+	}
+	return &pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_PublicMsg{
+			PublicMsg: &pb.PublicMsg{
+				From:     from,
+				Msg:      r.Msg,
+				TimeSent: r.TimeSent,
+			},
+		},
+	}
+}
+
+func (ms *FileMessageStorage) AddPublicMsg(msg *pb.PublicMsg) error {
+	return ms.add(&pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_PublicMsg{PublicMsg: msg},
+	})
+}
+
+func (ms *FileMessageStorage) AddPrivateMsg(msg *pb.PrivateMsg) error {
+	return ms.add(&pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_PrivateMsg{PrivateMsg: msg},
+	})
+}
+
+func (ms *FileMessageStorage) add(msg *pb.ChatServerMsg) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	id := ms.lastMsgID() + 1
+	if err := ms.log.append(newMsgRecord(id, msg)); err != nil {
+		return err
+	}
+	ms.addAs(id, msg)
+	return nil
+}
+
+func (ms *FileMessageStorage) PurgeNick(nick string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if err := ms.log.append(msgRecord{ID: ms.lastMsgID(), Purge: true, From: nick}); err != nil {
+		return err
+	}
+	return ms.InMemoryMessageStorage.PurgeNick(nick)
+}
+
+func (ms *FileMessageStorage) Close() error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return ms.log.close()
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const (
+	userLogFile = "users.log"
+	banLogFile  = "bans.log"
+)
+
+type userRecord struct {
+	Deleted      bool         `json:"deleted,omitempty"`
+	Nick         string       `json:"nick"`
+	TimeLastSeen int64        `json:"time_last_seen,omitempty"`
+	Role         pb.User_Role `json:"role,omitempty"`
+	Muted        bool         `json:"muted,omitempty"`
+	MuteExpiry   int64        `json:"mute_expiry,omitempty"`
+	PassHash     string       `json:"pass_hash,omitempty"`
+	PublicKey    []byte       `json:"public_key,omitempty"`
+}
+
+func newUserRecord(user User) userRecord {
+	return userRecord{
+		Nick:         user.Nick,
+		TimeLastSeen: user.TimeLastSeen,
+		Role:         user.Role,
+		Muted:        user.Muted,
+		MuteExpiry:   user.MuteExpiry,
+		PassHash:     user.PassHash,
+		PublicKey:    user.PublicKey,
+	}
+}
+
+func (r userRecord) user() User {
+	return User{
+		Muted:      r.Muted,
+		MuteExpiry: r.MuteExpiry,
+		PassHash:   r.PassHash,
+		User: pb.User{
+			Nick:         r.Nick,
+			TimeLastSeen: r.TimeLastSeen,
+			Role:         r.Role,
+			PublicKey:    r.PublicKey,
+		},
+	}
+}
+
+type banRecord struct {
+	Deleted bool         `json:"deleted,omitempty"`
+	Nick    string       `json:"nick,omitempty"`
+	Addr    string       `json:"addr,omitempty"`
+	Reason  string       `json:"reason,omitempty"`
+	By      string       `json:"by,omitempty"`
+	ByRole  pb.User_Role `json:"by_role,omitempty"`
+	Expiry  int64        `json:"expiry,omitempty"`
+}
+
+func newBanRecord(ban *pb.Ban) banRecord {
+	return banRecord{
+		Nick:   ban.Nick,
+		Addr:   ban.Addr,
+		Reason: ban.Reason,
+		By:     ban.By,
+		ByRole: ban.ByRole,
+		Expiry: ban.Expiry,
+	}
+}
+
+func (r banRecord) ban() *pb.Ban {
+	return &pb.Ban{
+		Nick:   r.Nick,
+		Addr:   r.Addr,
+		Reason: r.Reason,
+		By:     r.By,
+		ByRole: r.ByRole,
+		Expiry: r.Expiry,
+	}
+}
+
+// FileUserStorage keeps users in memory and records every change in an
+// append-only log under its data directory. The log is replayed and
+// compacted when the storage is opened. Online state and session tokens
+// are not persisted; all users are offline after a restart. Bans are kept
+// in a log of their own, expired bans are dropped when it is compacted.
+type FileUserStorage struct {
+	*InMemoryUserStorage
+	log    *appendLog
+	banLog *appendLog
+	mu     sync.Mutex // Serializes log writes with the in-memory updates
+}
+
+func NewFileUserStorage(dir string) (*FileUserStorage, error) {
+	mem := NewInMemoryUserStorage()
+	log, err := openAppendLog(
+		filepath.Join(dir, userLogFile),
+		func(line []byte) error {
+			var r userRecord
+			if err := json.Unmarshal(line, &r); err != nil {
+				return fmt.Errorf("corrupt user log record: %v", err)
+			}
+			if r.Deleted {
+				delete(mem.users, r.Nick)
+			} else {
+				mem.users[r.Nick] = r.user()
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot []interface{}
+	for _, user := range mem.users {
+		snapshot = append(snapshot, newUserRecord(user))
+	}
+	if err = log.compact(snapshot); err != nil {
+		log.close()
+		return nil, err
+	}
+
+	banLog, err := openAppendLog(
+		filepath.Join(dir, banLogFile),
+		func(line []byte) error {
+			var r banRecord
+			if err := json.Unmarshal(line, &r); err != nil {
+				return fmt.Errorf("corrupt ban log record: %v", err)
+			}
+			if r.Deleted {
+				delete(mem.bans, banKey(r.Nick, r.Addr))
+			} else {
+				mem.bans[banKey(r.Nick, r.Addr)] = r.ban()
+			}
+			return nil
+		},
+	)
+	if err != nil {
+		log.close()
+		return nil, err
+	}
+	snapshot = nil
+	for key, ban := range mem.bans {
+		if BanExpired(ban) {
+			delete(mem.bans, key)
+			continue
+		}
+		snapshot = append(snapshot, newBanRecord(ban))
+	}
+	if err = banLog.compact(snapshot); err != nil {
+		log.close()
+		banLog.close()
+		return nil, err
+	}
+
+	return &FileUserStorage{
+		InMemoryUserStorage: mem,
+		log:                 log,
+		banLog:              banLog,
+	}, nil
+}
+
+func (us *FileUserStorage) AddUser(user User) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if _, found := us.InMemoryUserStorage.GetUser(user.Nick); found {
+		return fmt.Errorf("user %q already exists", user.Nick)
+	}
+	if err := us.log.append(newUserRecord(user)); err != nil {
+		return err
+	}
+	return us.InMemoryUserStorage.AddUser(user)
+}
+
+func (us *FileUserStorage) UpdateUser(nick string, update func(user *User) error) (User, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return us.update(nick, update, nil, us.logUser)
+}
+
+// AddSession records the changes of update, sessions are not persisted.
+func (us *FileUserStorage) AddSession(nick string, sess Session, update func(user *User) error) (User, error) {
+	if update == nil {
+		return us.InMemoryUserStorage.AddSession(nick, sess, nil)
+	}
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	return us.update(nick, update, &sess, us.logUser)
+}
+
+func (us *FileUserStorage) logUser(user User) error {
+	return us.log.append(newUserRecord(user))
+}
+
+// RemoveSession records the time last seen when the user goes offline.
+func (us *FileUserStorage) RemoveSession(nick, id string) (User, int, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	user, removed, err := us.InMemoryUserStorage.RemoveSession(nick, id)
+	if err != nil || removed == 0 || user.Online {
+		return user, removed, err
+	}
+	return user, removed, us.log.append(newUserRecord(user))
+}
+
+func (us *FileUserStorage) DeleteUser(nick string) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	err := us.log.append(userRecord{Deleted: true, Nick: nick})
+	if err != nil {
+		return err
+	}
+	return us.InMemoryUserStorage.DeleteUser(nick)
+}
+
+func (us *FileUserStorage) AddBan(ban *pb.Ban) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	if err := us.banLog.append(newBanRecord(ban)); err != nil {
+		return err
+	}
+	return us.InMemoryUserStorage.AddBan(ban)
+}
+
+func (us *FileUserStorage) RemoveBan(nick, addr string) (bool, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	err := us.banLog.append(banRecord{Deleted: true, Nick: nick, Addr: addr})
+	if err != nil {
+		return false, err
+	}
+	return us.InMemoryUserStorage.RemoveBan(nick, addr)
+}
+
+func (us *FileUserStorage) Close() error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	err := us.log.close()
+	if berr := us.banLog.close(); err == nil {
+		err = berr
+	}
+	return err
+}
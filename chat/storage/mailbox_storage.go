@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"errors"
+	"sync"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+const MaxMailboxSize = 4096
+
+var ErrMailboxFull = errors.New("recipient mailbox full")
+
+// MailboxStorage holds private messages for each recipient until they are
+// acknowledged. Messages are numbered per recipient in delivery order.
+type MailboxStorage interface {
+	// Deliver stores msg in the mailbox of msg.To and returns a copy with
+	// the assigned mailbox id set.
+	Deliver(msg *pb.PrivateMsg) (*pb.PrivateMsg, error)
+	// Pending returns the unacknowledged messages for nick in id order.
+	Pending(nick string) ([]*pb.PrivateMsg, error)
+	// Ack removes all messages for nick with an id up to and including upTo.
+	Ack(nick string, upTo uint64) error
+	// Purge removes the mailbox of nick, ids start over for the next user
+	// of the nick.
+	Purge(nick string) error
+	// Close flushes the storage, it must not be used afterwards.
+	Close() error
+}
+
+type mailbox struct {
+	msgs   []*pb.PrivateMsg
+	nextID uint64
+}
+
+type InMemoryMailboxStorage struct {
+	boxes map[string]*mailbox
+	mu    sync.Mutex
+}
+
+func NewInMemoryMailboxStorage() *InMemoryMailboxStorage {
+	return &InMemoryMailboxStorage{
+		boxes: make(map[string]*mailbox),
+	}
+}
+
+func (ms *InMemoryMailboxStorage) Deliver(msg *pb.PrivateMsg) (*pb.PrivateMsg, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	m, err := ms.prepare(msg)
+	if err != nil {
+		return nil, err
+	}
+	ms.store(m)
+	return copyPrivateMsg(m), nil
+}
+
+// prepare returns a copy of msg with the next mailbox id of its recipient
+// set, without storing it. Must be called with mu held.
+func (ms *InMemoryMailboxStorage) prepare(msg *pb.PrivateMsg) (*pb.PrivateMsg, error) {
+	box := ms.box(msg.To)
+	if len(box.msgs) >= MaxMailboxSize {
+		return nil, ErrMailboxFull
+	}
+	m := copyPrivateMsg(msg)
+	m.Id = box.nextID + 1
+	return m, nil
+}
+
+// store appends a prepared message. Must be called with mu held.
+func (ms *InMemoryMailboxStorage) store(m *pb.PrivateMsg) {
+	box := ms.box(m.To)
+	if m.Id > box.nextID {
+		box.nextID = m.Id
+	}
+	box.msgs = append(box.msgs, m)
+}
+
+func (ms *InMemoryMailboxStorage) Pending(nick string) ([]*pb.PrivateMsg, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	box, found := ms.boxes[nick]
+	if !found {
+		return nil, nil
+	}
+	msgs := make([]*pb.PrivateMsg, len(box.msgs))
+	for i, m := range box.msgs {
+		msgs[i] = copyPrivateMsg(m)
+	}
+	return msgs, nil
+}
+
+func (ms *InMemoryMailboxStorage) Ack(nick string, upTo uint64) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.ack(nick, upTo)
+	return nil
+}
+
+func (ms *InMemoryMailboxStorage) ack(nick string, upTo uint64) {
+	box, found := ms.boxes[nick]
+	if !found {
+		return
+	}
+	i := 0
+	for i < len(box.msgs) && box.msgs[i].Id <= upTo {
+		i++
+	}
+	box.msgs = append([]*pb.PrivateMsg(nil), box.msgs[i:]...)
+}
+
+func (ms *InMemoryMailboxStorage) Purge(nick string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.boxes, nick)
+	return nil
+}
+
+func (ms *InMemoryMailboxStorage) Close() error {
+	return nil
+}
+
+func (ms *InMemoryMailboxStorage) box(nick string) *mailbox {
+	box, found := ms.boxes[nick]
+	if !found {
+		box = new(mailbox)
+		ms.boxes[nick] = box
+	}
+	return box
+}
+
+func copyPrivateMsg(msg *pb.PrivateMsg) *pb.PrivateMsg {
+	m := &pb.PrivateMsg{
+		To:        msg.To,
+		Msg:       msg.Msg,
+		Encrypted: msg.Encrypted,
+		TimeSent:  msg.TimeSent,
+		Id:        msg.Id,
+	}
+	if msg.From != nil {
+		m.From = &pb.User{
+			Nick:         msg.From.Nick,
+			TimeLastSeen: msg.From.TimeLastSeen,
+		}
+	}
+	return m
+}
@@ -0,0 +1,169 @@
+package storage_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+func TestInMemoryMailboxStorage(t *testing.T) {
+	testMailboxStorage(t, storage.NewInMemoryMailboxStorage())
+}
+
+func TestFileMailboxStorage(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ms, err := storage.NewFileMailboxStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMailboxStorage(t, ms)
+	if err = ms.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen twice: once to replay the log, once to replay the compacted log.
+	for i := 0; i < 2; i++ {
+		ms, err = storage.NewFileMailboxStorage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkPending(t, ms, "reopen", "bob", "4:d")
+		checkPending(t, ms, "reopen", "alice", "")
+		checkPending(t, ms, "reopen", "carol", "")
+		if err = ms.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Ids must not be reused after everything has been acknowledged.
+	ms, err = storage.NewFileMailboxStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ms.Ack("bob", 4); err != nil {
+		t.Fatal(err)
+	}
+	ms.Close()
+	ms, err = storage.NewFileMailboxStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	deliver(t, ms, "bob", "e")
+	checkPending(t, ms, "after full ack and reopen", "bob", "5:e")
+}
+
+func TestFileMailboxStorageEncrypted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ms, err := storage.NewFileMailboxStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := &pb.EncryptedMsg{
+		SenderKey:    bytes.Repeat([]byte{1}, 32),
+		RecipientKey: bytes.Repeat([]byte{2}, 32),
+		Nonce:        bytes.Repeat([]byte{3}, 24),
+		Box:          []byte("\x00sealed\xff"),
+	}
+	_, err = ms.Deliver(&pb.PrivateMsg{To: "bob", From: &pb.User{Nick: "alice"}, Encrypted: enc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms.Close()
+
+	ms, err = storage.NewFileMailboxStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	msgs, err := ms.Pending("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || !proto.Equal(msgs[0].Encrypted, enc) {
+		t.Errorf("got pending %v after reopen, want the encrypted message", msgs)
+	}
+}
+
+// testMailboxStorage is the conformance suite every MailboxStorage must pass.
+// It leaves message 4 pending for bob and nothing for alice and carol.
+func testMailboxStorage(t *testing.T, ms storage.MailboxStorage) {
+	checkPending(t, ms, "empty", "bob", "")
+
+	deliver(t, ms, "bob", "a")
+	deliver(t, ms, "bob", "b")
+	deliver(t, ms, "alice", "x")
+	deliver(t, ms, "bob", "c")
+	checkPending(t, ms, "delivered", "bob", "1:a 2:b 3:c")
+	checkPending(t, ms, "delivered", "alice", "1:x")
+
+	if err := ms.Ack("bob", 2); err != nil {
+		t.Fatal(err)
+	}
+	checkPending(t, ms, "acked up to 2", "bob", "3:c")
+	checkPending(t, ms, "acked other user", "alice", "1:x")
+
+	deliver(t, ms, "bob", "d")
+	if err := ms.Ack("bob", 3); err != nil {
+		t.Fatal(err)
+	}
+	checkPending(t, ms, "acked up to 3", "bob", "4:d")
+
+	if err := ms.Ack("alice", 1); err != nil {
+		t.Fatal(err)
+	}
+	checkPending(t, ms, "acked all", "alice", "")
+	if err := ms.Ack("nobody", 1); err != nil {
+		t.Errorf("ack on empty mailbox: %v", err)
+	}
+
+	deliver(t, ms, "carol", "y")
+	if err := ms.Purge("carol"); err != nil {
+		t.Fatal(err)
+	}
+	checkPending(t, ms, "purged", "carol", "")
+	deliver(t, ms, "carol", "z")
+	checkPending(t, ms, "delivered after purge", "carol", "1:z")
+	if err := ms.Purge("carol"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func deliver(t *testing.T, ms storage.MailboxStorage, to, msg string) {
+	m, err := ms.Deliver(&pb.PrivateMsg{
+		To:   to,
+		From: &pb.User{Nick: "sender"},
+		Msg:  msg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Id == 0 {
+		t.Errorf("delivered message %q got no id", msg)
+	}
+}
+
+func checkPending(t *testing.T, ms storage.MailboxStorage, desc, nick, want string) {
+	msgs, err := ms.Pending(nick)
+	if err != nil {
+		t.Fatalf("%s: %v", desc, err)
+	}
+	var got string
+	for i, m := range msgs {
+		if i > 0 {
+			got += " "
+		}
+		got += fmt.Sprintf("%d:%s", m.Id, m.Msg)
+	}
+	if got != want {
+		t.Errorf("%s: got pending %q for %s, want %q", desc, got, nick, want)
+	}
+}
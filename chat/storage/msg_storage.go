@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"sort"
+	"sync"
+
+	pb "github.com/tormoder/chat/proto"
+)
+
+// HistorySize is the number of newest messages kept in the history.
+const HistorySize = 10000
+
+type MessageStorage interface {
+	AddPublicMsg(msg *pb.PublicMsg) error
+	AddPrivateMsg(msg *pb.PrivateMsg) error
+	// GetHistory returns at most limit messages visible to nick with ids
+	// strictly between after and before, oldest first. A zero after or
+	// before leaves that end unbounded. The newest of those messages are
+	// returned, or the oldest if only after is given, so that the history
+	// can be paged through in both directions.
+	GetHistory(nick string, after, before uint64, limit int) ([]HistoryMsg, error)
+	// PurgeNick removes the private messages to or from nick, so that the
+	// next user of the nick cannot read them.
+	PurgeNick(nick string) error
+	// Close flushes the storage, it must not be used afterwards.
+	Close() error
+}
+
+// HistoryMsg is a stored message. Every message stored gets a higher id
+// than the ones before it.
+type HistoryMsg struct {
+	ID  uint64
+	Msg *pb.ChatServerMsg
+}
+
+type InMemoryMessageStorage struct {
+	msgs   []HistoryMsg // At most HistorySize, oldest first
+	lastID uint64
+	mu     sync.RWMutex
+}
+
+func NewInMemoryMessageStorage() *InMemoryMessageStorage {
+	return &InMemoryMessageStorage{}
+}
+
+func (ms *InMemoryMessageStorage) AddPublicMsg(msg *pb.PublicMsg) error {
+	ms.add(&pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_PublicMsg{PublicMsg: msg},
+	})
+	return nil
+}
+
+func (ms *InMemoryMessageStorage) AddPrivateMsg(msg *pb.PrivateMsg) error {
+	ms.add(&pb.ChatServerMsg{
+		Msg: &pb.ChatServerMsg_PrivateMsg{PrivateMsg: msg},
+	})
+	return nil
+}
+
+func (ms *InMemoryMessageStorage) add(msg *pb.ChatServerMsg) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.insert(ms.lastID+1, msg)
+}
+
+// lastMsgID returns the id of the last message added.
+func (ms *InMemoryMessageStorage) lastMsgID() uint64 {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	return ms.lastID
+}
+
+// addAs adds msg with the given id, which must be higher than the id of
+// any message added before.
+func (ms *InMemoryMessageStorage) addAs(id uint64, msg *pb.ChatServerMsg) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.insert(id, msg)
+}
+
+// insert must be called with mu held.
+func (ms *InMemoryMessageStorage) insert(id uint64, msg *pb.ChatServerMsg) {
+	if len(ms.msgs) == HistorySize {
+		// The array is reallocated with only the kept messages once full
+		ms.msgs = ms.msgs[1:]
+	}
+	ms.lastID = id
+	ms.msgs = append(ms.msgs, HistoryMsg{ID: id, Msg: msg})
+}
+
+func (ms *InMemoryMessageStorage) GetHistory(nick string, after, before uint64, limit int) ([]HistoryMsg, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	var msgs []HistoryMsg
+	if after != 0 && before == 0 {
+		i := sort.Search(len(ms.msgs), func(i int) bool { return ms.msgs[i].ID > after })
+		for ; i < len(ms.msgs) && len(msgs) < limit; i++ {
+			if msgVisibleTo(ms.msgs[i].Msg, nick) {
+				msgs = append(msgs, ms.msgs[i])
+			}
+		}
+		return msgs, nil
+	}
+	for i := len(ms.msgs) - 1; i >= 0 && len(msgs) < limit; i-- {
+		msg := ms.msgs[i]
+		if before != 0 && msg.ID >= before {
+			continue
+		}
+		if msg.ID <= after {
+			break
+		}
+		if !msgVisibleTo(msg.Msg, nick) {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+func (ms *InMemoryMessageStorage) PurgeNick(nick string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.purge(nick)
+	return nil
+}
+
+// purge removes the private messages to or from nick. Must be called with
+// mu held.
+func (ms *InMemoryMessageStorage) purge(nick string) {
+	msgs := ms.msgs[:0]
+	for _, msg := range ms.msgs {
+		if msg.Msg.GetPrivateMsg() == nil || !msgVisibleTo(msg.Msg, nick) {
+			msgs = append(msgs, msg)
+		}
+	}
+	for i := len(msgs); i < len(ms.msgs); i++ {
+		ms.msgs[i] = HistoryMsg{}
+	}
+	ms.msgs = msgs
+}
+
+func (ms *InMemoryMessageStorage) Close() error {
+	return nil
+}
+
+func msgVisibleTo(msg *pb.ChatServerMsg, nick string) bool {
+	pmsg := msg.GetPrivateMsg()
+	if pmsg == nil {
+		return true
+	}
+	return pmsg.To == nick || pmsg.GetFrom().GetNick() == nick
+}
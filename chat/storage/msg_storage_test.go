@@ -0,0 +1,205 @@
+package storage_test
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+func TestInMemoryMessageStorage(t *testing.T) {
+	testMessageStorage(t, storage.NewInMemoryMessageStorage())
+}
+
+func TestFileMessageStorage(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ms, err := storage.NewFileMessageStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testMessageStorage(t, ms)
+	if err = ms.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err = storage.NewFileMessageStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	checkHistory(t, ms, "after reopen", "bob", 2, 0, 100, "4 6 7")
+	addPublic(t, ms, "alice", "8", 8)
+	if msgs, _ := ms.GetHistory("bob", 7, 0, 100); len(msgs) != 1 || msgs[0].ID != 8 {
+		t.Errorf("got %v after reopen, want a message with id 8", msgs)
+	}
+}
+
+func TestFileHistoryCompacted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ms, err := storage.NewFileMessageStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < storage.HistorySize+5; i++ {
+		addPublic(t, ms, "alice", strconv.Itoa(i+1), 1)
+	}
+	addPrivate(t, ms, "alice", "bob", "private", 1)
+	if err = ms.PurgeNick("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err = ms.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ms, err = storage.NewFileMessageStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ms.Close()
+	b, err := ioutil.ReadFile(filepath.Join(dir, "messages.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The kept messages, all but the purged one, and the last id given out
+	if lines := bytes.Count(b, []byte("\n")); lines != storage.HistorySize {
+		t.Errorf("got %d records after compaction, want %d", lines, storage.HistorySize)
+	}
+	msgs, err := ms.GetHistory("alice", 0, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != storage.HistorySize+5 {
+		t.Errorf("got %v, want the newest message to keep its id", msgs)
+	}
+	// Not reusing the id of the purged message
+	addPublic(t, ms, "alice", "next", 1)
+	msgs, err = ms.GetHistory("alice", storage.HistorySize+5, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 1 || msgs[0].ID != storage.HistorySize+7 {
+		t.Errorf("got %v, want a message with id %d", msgs, storage.HistorySize+7)
+	}
+}
+
+// testMessageStorage is the conformance suite every MessageStorage must
+// pass. Messages are added to an empty storage, so their ids are 1 to 5,
+// and identified by their text in the expected results.
+func testMessageStorage(t *testing.T, ms storage.MessageStorage) {
+	checkHistory(t, ms, "empty", "alice", 0, 0, 100, "")
+
+	addPublic(t, ms, "alice", "1", 1)
+	addPrivate(t, ms, "alice", "bob", "2", 2)
+	addPrivate(t, ms, "bob", "carol", "3", 3)
+	addPublic(t, ms, "carol", "4", 4)
+	addPrivate(t, ms, "carol", "bob", "5", 5)
+
+	checkHistory(t, ms, "all for alice", "alice", 0, 0, 100, "1 2 4")
+	checkHistory(t, ms, "all for bob", "bob", 0, 0, 100, "1 2 3 4 5")
+	checkHistory(t, ms, "all for carol", "carol", 0, 0, 100, "1 3 4 5")
+	checkHistory(t, ms, "all for outsider", "dave", 0, 0, 100, "1 4")
+	checkHistory(t, ms, "limit keeps newest", "bob", 0, 0, 2, "4 5")
+	checkHistory(t, ms, "after", "bob", 2, 0, 100, "3 4 5")
+	checkHistory(t, ms, "before", "bob", 0, 4, 100, "1 2 3")
+	checkHistory(t, ms, "after and before", "bob", 1, 5, 100, "2 3 4")
+	checkHistory(t, ms, "paging back", "bob", 0, 3, 1, "2")
+
+	// Sent in the same second
+	addPublic(t, ms, "alice", "6", 6)
+	addPublic(t, ms, "alice", "7", 6)
+	checkHistory(t, ms, "paging back within a second", "bob", 0, 7, 1, "6")
+	checkHistory(t, ms, "paging forward within a second", "bob", 6, 0, 100, "7")
+	checkHistory(t, ms, "limit after keeps oldest", "bob", 1, 0, 2, "2 3")
+
+	// Paging forward from the first message with pages smaller than the
+	// history
+	var paged []uint64
+	for after := uint64(1); ; {
+		msgs, err := ms.GetHistory("alice", after, 0, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		for _, msg := range msgs {
+			paged = append(paged, msg.ID)
+		}
+		after = msgs[len(msgs)-1].ID
+	}
+	if fmt.Sprint(paged) != "[2 4 6 7]" {
+		t.Errorf("paged forward through ids %v, want [2 4 6 7]", paged)
+	}
+
+	if err := ms.PurgeNick("carol"); err != nil {
+		t.Fatal(err)
+	}
+	checkHistory(t, ms, "purged", "bob", 0, 0, 100, "1 2 4 6 7")
+	checkHistory(t, ms, "purged nick", "carol", 0, 0, 100, "1 4 6 7")
+}
+
+func TestHistorySize(t *testing.T) {
+	ms := storage.NewInMemoryMessageStorage()
+	for i := 0; i <= storage.HistorySize; i++ {
+		addPublic(t, ms, "alice", strconv.Itoa(i+1), 1)
+	}
+	msgs, err := ms.GetHistory("alice", 0, 0, storage.HistorySize+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != storage.HistorySize || msgs[0].ID != 2 {
+		t.Errorf("got %d messages from id %d, want %d from 2", len(msgs), msgs[0].ID, storage.HistorySize)
+	}
+}
+
+func addPublic(t *testing.T, ms storage.MessageStorage, from, msg string, ts int64) {
+	err := ms.AddPublicMsg(&pb.PublicMsg{
+		From:     &pb.User{Nick: from},
+		Msg:      msg,
+		TimeSent: ts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addPrivate(t *testing.T, ms storage.MessageStorage, from, to, msg string, ts int64) {
+	err := ms.AddPrivateMsg(&pb.PrivateMsg{
+		From:     &pb.User{Nick: from},
+		To:       to,
+		Msg:      msg,
+		TimeSent: ts,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func checkHistory(t *testing.T, ms storage.MessageStorage, desc, nick string, after, before uint64, limit int, want string) {
+	msgs, err := ms.GetHistory(nick, after, before, limit)
+	if err != nil {
+		t.Fatalf("%s: %v", desc, err)
+	}
+	var got string
+	for i, msg := range msgs {
+		if i > 0 {
+			got += " "
+		}
+		if pmsg := msg.Msg.GetPublicMsg(); pmsg != nil {
+			got += pmsg.Msg
+		} else {
+			got += msg.Msg.GetPrivateMsg().Msg
+		}
+	}
+	if got != want {
+		t.Errorf("%s: got messages %q, want %q", desc, got, want)
+	}
+}
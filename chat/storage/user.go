@@ -1,13 +1,83 @@
 package storage
 
-import pb "github.com/tormoder/chat/proto"
+import (
+	"crypto/subtle"
+	"time"
+
+	pb "github.com/tormoder/chat/proto"
+)
 
 type User struct {
-	Online     bool
-	MsgChannel chan *pb.ChatServerMsg
+	Online     bool      // Whether the user has any sessions
+	Sessions   []Session // Must not be modified, see UserStorage.AddSession
+	Muted      bool
+	MuteExpiry int64  // Unix time, 0 if muted until unmuted
+	PassHash   string // Empty for guests, see common.HashPassword
 	pb.User
 }
 
+// Session is a login of a user, a user may be logged in from several
+// clients at once.
+type Session struct {
+	ID      string
+	Token   []byte
+	Created int64  // Unix time
+	Expiry  int64  // Unix time
+	Addr    string // Host logged in from, checked against address bans
+}
+
+func (u User) IsMuted() bool {
+	return u.Muted && (u.MuteExpiry == 0 || time.Now().Unix() < u.MuteExpiry)
+}
+
+// Registered reports whether the nick is protected by a password.
+func (u User) Registered() bool {
+	return u.PassHash != ""
+}
+
+// ValidSession reports whether the user has an unexpired session id with
+// the given token.
+func (u User) ValidSession(id string, token []byte) bool {
+	sess, found := u.Session(id)
+	return found && sess.ValidToken(token)
+}
+
+// Session returns the session id of the user.
+func (u User) Session(id string) (Session, bool) {
+	for _, sess := range u.Sessions {
+		if sess.ID == id {
+			return sess, true
+		}
+	}
+	return Session{}, false
+}
+
+func (s Session) Expired() bool {
+	return time.Now().Unix() >= s.Expiry
+}
+
+func (s Session) ValidToken(token []byte) bool {
+	if len(s.Token) == 0 || len(token) != len(s.Token) {
+		return false
+	}
+	if s.Expired() {
+		return false
+	}
+	return subtle.ConstantTimeCompare(s.Token, token) == 1
+}
+
+// BanExpired reports whether a ban is no longer in effect.
+func BanExpired(ban *pb.Ban) bool {
+	return ban.Expiry != 0 && time.Now().Unix() >= ban.Expiry
+}
+
+func banKey(nick, addr string) string {
+	if addr != "" {
+		return "addr:" + addr
+	}
+	return "nick:" + nick
+}
+
 type ByNick []*pb.User
 
 func (s ByNick) Len() int           { return len(s) }
@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/tormoder/chat/common"
 	pb "github.com/tormoder/chat/proto"
@@ -11,22 +13,55 @@ import (
 type UserStorage interface {
 	AddUser(user User) error
 	GetUser(nick string) (User, bool)
-	UpdateUser(user User) error
+	// UpdateUser calls update with the user nick and stores the changes
+	// it makes, under the storage's lock so that concurrent updates are not
+	// lost. Changes to Online and Sessions are ignored, they are only
+	// changed by AddSession and RemoveSession. It returns the updated user,
+	// or the error of update. ErrUserNotFound is returned for unknown nicks.
+	UpdateUser(nick string, update func(user *User) error) (User, error)
+	// AddSession adds a session to the user nick, marking it online, and
+	// returns the updated user. Expired sessions are dropped. If update is
+	// not nil, the user is first updated like by UpdateUser and the session
+	// is only added if update succeeds.
+	AddSession(nick string, sess Session, update func(user *User) error) (User, error)
+	// RemoveSession removes the session id of nick, or all its sessions
+	// if id is empty, and returns the updated user. The user is marked
+	// offline with time last seen set to now when the last session is
+	// removed. It returns the number of sessions removed.
+	RemoveSession(nick, id string) (User, int, error)
 	DeleteUser(nick string) error
 	GetAllUsers() []User
 	GetAllOnlineUsers() []User
 	GetAllOnlineUsersDTO() []*pb.User
 	CheckCredentials(*pb.Credentials) (User, error)
+	// AddBan adds a ban on a nick or an address, replacing any ban on
+	// the same nick or address.
+	AddBan(ban *pb.Ban) error
+	// RemoveBan removes the ban on nick or addr and reports whether
+	// there was one.
+	RemoveBan(nick, addr string) (bool, error)
+	// GetBans returns the bans in effect.
+	GetBans() []*pb.Ban
+	// Banned returns the ban in effect on nick or addr, if any, for a
+	// user with role. Either may be empty. Address bans do not apply to
+	// users with at least the role of the moderator who made them.
+	Banned(nick, addr string, role pb.User_Role) (*pb.Ban, bool)
+	// Close flushes the storage, it must not be used afterwards.
+	Close() error
 }
 
+var ErrUserNotFound = errors.New("user not found")
+
 type InMemoryUserStorage struct {
 	users map[string]User
+	bans  map[string]*pb.Ban // Keyed by banKey
 	mu    sync.RWMutex
 }
 
 func NewInMemoryUserStorage() *InMemoryUserStorage {
 	return &InMemoryUserStorage{
 		users: make(map[string]User),
+		bans:  make(map[string]*pb.Ban),
 	}
 }
 
@@ -48,11 +83,74 @@ func (us *InMemoryUserStorage) GetUser(nick string) (User, bool) {
 	return u, found
 }
 
-func (us *InMemoryUserStorage) UpdateUser(user User) error {
+func (us *InMemoryUserStorage) UpdateUser(nick string, update func(user *User) error) (User, error) {
+	return us.update(nick, update, nil, nil)
+}
+
+func (us *InMemoryUserStorage) AddSession(nick string, sess Session, update func(user *User) error) (User, error) {
+	return us.update(nick, update, &sess, nil)
+}
+
+// update applies update, if not nil, to the user nick and adds sess, if not
+// nil. The result is stored if persist, if not nil, succeeds with it.
+func (us *InMemoryUserStorage) update(nick string, update func(user *User) error, sess *Session, persist func(user User) error) (User, error) {
 	us.mu.Lock()
 	defer us.mu.Unlock()
-	us.users[user.Nick] = user
-	return nil
+	old, found := us.users[nick]
+	if !found {
+		return old, ErrUserNotFound
+	}
+	u := old
+	if update != nil {
+		if err := update(&u); err != nil {
+			return old, err
+		}
+		u.Nick, u.Online, u.Sessions = old.Nick, old.Online, old.Sessions
+	}
+	if sess != nil {
+		// Copied, users handed out share the old slice
+		sessions := make([]Session, 0, len(u.Sessions)+1)
+		for _, s := range u.Sessions {
+			if s.ID != sess.ID && !s.Expired() {
+				sessions = append(sessions, s)
+			}
+		}
+		u.Sessions = append(sessions, *sess)
+		u.Online = true
+	}
+	if persist != nil {
+		if err := persist(u); err != nil {
+			return old, err
+		}
+	}
+	us.users[nick] = u
+	return u, nil
+}
+
+func (us *InMemoryUserStorage) RemoveSession(nick, id string) (User, int, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	u, found := us.users[nick]
+	if !found {
+		return u, 0, nil
+	}
+	var sessions []Session
+	for _, s := range u.Sessions {
+		if id != "" && s.ID != id {
+			sessions = append(sessions, s)
+		}
+	}
+	removed := len(u.Sessions) - len(sessions)
+	if removed == 0 {
+		return u, 0, nil
+	}
+	u.Sessions = sessions
+	if len(sessions) == 0 {
+		u.Online = false
+		u.TimeLastSeen = time.Now().Unix()
+	}
+	us.users[nick] = u
+	return u, removed, nil
 }
 
 func (us *InMemoryUserStorage) DeleteUser(nick string) error {
@@ -98,6 +196,9 @@ func (us *InMemoryUserStorage) GetAllOnlineUsersDTO() []*pb.User {
 }
 
 func (us *InMemoryUserStorage) CheckCredentials(creds *pb.Credentials) (User, error) {
+	if creds == nil {
+		return User{}, common.AuthenticationError("missing credentials")
+	}
 	user, found := us.GetUser(creds.Nick)
 	if !found {
 		return User{}, common.AuthenticationError("user not found")
@@ -105,8 +206,81 @@ func (us *InMemoryUserStorage) CheckCredentials(creds *pb.Credentials) (User, er
 	if !user.Online {
 		return User{}, common.AuthenticationError("user not logged-in")
 	}
+	sess, found := user.Session(creds.Session)
+	if !found || !sess.ValidToken(creds.Token) {
+		return User{}, common.AuthenticationError("invalid or expired token")
+	}
+	if ban, banned := us.Banned(user.Nick, sess.Addr, user.Role); banned {
+		return User{}, BanError(ban)
+	}
+	return user, nil
+}
+
+func (us *InMemoryUserStorage) AddBan(ban *pb.Ban) error {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	us.bans[banKey(ban.Nick, ban.Addr)] = ban
+	return nil
+}
+
+func (us *InMemoryUserStorage) RemoveBan(nick, addr string) (bool, error) {
+	us.mu.Lock()
+	defer us.mu.Unlock()
+	key := banKey(nick, addr)
+	_, found := us.bans[key]
+	delete(us.bans, key)
+	return found, nil
+}
+
+func (us *InMemoryUserStorage) GetBans() []*pb.Ban {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+	var bans []*pb.Ban
+	for _, ban := range us.bans {
+		if !BanExpired(ban) {
+			bans = append(bans, ban)
+		}
+	}
+	return bans
+}
 
-	// TODO: Authentication
+func (us *InMemoryUserStorage) Banned(nick, addr string, role pb.User_Role) (*pb.Ban, bool) {
+	us.mu.RLock()
+	defer us.mu.RUnlock()
+	if nick != "" {
+		if ban, found := us.bans[banKey(nick, "")]; found && !BanExpired(ban) {
+			return ban, true
+		}
+	}
+	if addr != "" {
+		if ban, found := us.bans[banKey("", addr)]; found && !BanExpired(ban) && role < banRole(ban) {
+			return ban, true
+		}
+	}
+	return nil, false
+}
 
-	return user, nil
+// banRole returns the role of the moderator who made ban. Bans recorded
+// without it were made by moderators at least.
+func banRole(ban *pb.Ban) pb.User_Role {
+	if ban.ByRole < pb.User_MODERATOR {
+		return pb.User_MODERATOR
+	}
+	return ban.ByRole
+}
+
+// BanError is the authentication error for a banned user.
+func BanError(ban *pb.Ban) error {
+	msg := "banned"
+	if ban.Expiry != 0 {
+		msg += " until " + time.Unix(ban.Expiry, 0).UTC().Format(time.RFC3339)
+	}
+	if ban.Reason != "" {
+		msg += ": " + ban.Reason
+	}
+	return common.AuthenticationError(msg)
+}
+
+func (us *InMemoryUserStorage) Close() error {
+	return nil
 }
@@ -0,0 +1,418 @@
+package storage_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tormoder/chat/common"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+func TestInMemoryUserStorage(t *testing.T) {
+	testUserStorage(t, storage.NewInMemoryUserStorage())
+}
+
+func TestFileUserStorage(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	us, err := storage.NewFileUserStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer us.Close()
+	testUserStorage(t, us)
+}
+
+func TestFileUserStorageSurvivesRestart(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	us, err := storage.NewFileUserStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nick := range []string{"alice", "bob", "carol"} {
+		mustAdd(t, us, newUser(nick, true))
+	}
+	_, err = us.UpdateUser("bob", func(bob *storage.User) error {
+		bob.TimeLastSeen = 42
+		bob.Role = pb.User_MODERATOR
+		bob.Muted = true
+		bob.PassHash = "hash"
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = us.AddBan(&pb.Ban{Nick: "carol", Reason: "spam", ByRole: pb.User_ADMIN}); err != nil {
+		t.Fatal(err)
+	}
+	if err = us.AddBan(&pb.Ban{Addr: "10.0.0.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = us.RemoveBan("", "10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err = us.DeleteUser("carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err = us.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen twice: once to replay the log, once to replay the compacted log.
+	for i := 0; i < 2; i++ {
+		us, err = storage.NewFileUserStorage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := len(us.GetAllUsers()); n != 2 {
+			t.Errorf("reopen %d: got %d users, want 2", i, n)
+		}
+		if _, found := us.GetUser("carol"); found {
+			t.Errorf("reopen %d: deleted user was restored", i)
+		}
+		bob, found := us.GetUser("bob")
+		if !found {
+			t.Fatalf("reopen %d: user not restored", i)
+		}
+		if bob.TimeLastSeen != 42 {
+			t.Errorf("reopen %d: got time last seen %d, want 42", i, bob.TimeLastSeen)
+		}
+		if bob.Role != pb.User_MODERATOR || !bob.IsMuted() {
+			t.Errorf("reopen %d: role and mute not restored: %+v", i, bob)
+		}
+		if bob.PassHash != "hash" {
+			t.Errorf("reopen %d: got password hash %q, want %q", i, bob.PassHash, "hash")
+		}
+		if ban, banned := us.Banned("carol", "", pb.User_USER); !banned || ban.Reason != "spam" || ban.ByRole != pb.User_ADMIN {
+			t.Errorf("reopen %d: ban not restored", i)
+		}
+		if _, banned := us.Banned("", "10.0.0.1", pb.User_USER); banned {
+			t.Errorf("reopen %d: removed ban was restored", i)
+		}
+		if bob.Online {
+			t.Errorf("reopen %d: restored user is online", i)
+		}
+		if len(bob.Sessions) != 0 {
+			t.Errorf("reopen %d: restored user has sessions", i)
+		}
+		if err = us.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// testUserStorage is the conformance suite every UserStorage must pass.
+func testUserStorage(t *testing.T, us storage.UserStorage) {
+	if n := len(us.GetAllUsers()); n != 0 {
+		t.Fatalf("new storage has %d users, want 0", n)
+	}
+
+	alice := newUser("alice", true)
+	mustAdd(t, us, alice)
+	mustAdd(t, us, newUser("bob", false))
+	mustAdd(t, us, newUser("carol", true))
+	if err := us.AddUser(newUser("alice", false)); err == nil {
+		t.Error("adding an existing user: got nil error")
+	}
+
+	u, found := us.GetUser("alice")
+	if !found {
+		t.Fatal("added user not found")
+	}
+	if u.Nick != "alice" || !u.Online {
+		t.Errorf("got user %+v, want online alice", u.User)
+	}
+	if _, found = us.GetUser("mallory"); found {
+		t.Error("found user that was never added")
+	}
+
+	if n := len(us.GetAllUsers()); n != 3 {
+		t.Errorf("got %d users, want 3", n)
+	}
+	if n := len(us.GetAllOnlineUsers()); n != 2 {
+		t.Errorf("got %d online users, want 2", n)
+	}
+	if n := len(us.GetAllOnlineUsersDTO()); n != 2 {
+		t.Errorf("got %d online user DTOs, want 2", n)
+	}
+
+	_, err := us.UpdateUser("alice", func(u *storage.User) error {
+		u.TimeLastSeen = 1234
+		u.Online = false
+		u.Sessions = nil
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ = us.GetUser("alice")
+	if u.TimeLastSeen != 1234 {
+		t.Errorf("update not applied: got %+v", u.User)
+	}
+	if !u.Online || len(u.Sessions) != 1 {
+		t.Errorf("update changed sessions: got online %t with %d sessions", u.Online, len(u.Sessions))
+	}
+	errUpdate := errors.New("update failed")
+	_, err = us.UpdateUser("alice", func(u *storage.User) error {
+		u.TimeLastSeen = 5678
+		return errUpdate
+	})
+	if err != errUpdate {
+		t.Errorf("failed update: got error %v, want %v", err, errUpdate)
+	}
+	_, err = us.AddSession("alice", newSession("s2"), func(u *storage.User) error {
+		return errUpdate
+	})
+	if err != errUpdate {
+		t.Errorf("failed update of new session: got error %v, want %v", err, errUpdate)
+	}
+	if u, _ = us.GetUser("alice"); u.TimeLastSeen != 1234 || len(u.Sessions) != 1 {
+		t.Errorf("failed update applied: got %+v with %d sessions", u.User, len(u.Sessions))
+	}
+	_, err = us.UpdateUser("mallory", func(u *storage.User) error { return nil })
+	if err != storage.ErrUserNotFound {
+		t.Errorf("update of unknown user: got error %v, want %v", err, storage.ErrUserNotFound)
+	}
+
+	u, removed, err := us.RemoveSession("alice", "")
+	if err != nil || removed != 1 {
+		t.Fatalf("remove all sessions: got %v, %v", removed, err)
+	}
+	if u.Online || u.TimeLastSeen == 1234 {
+		t.Errorf("user not logged out: got online %t, time last seen %d", u.Online, u.TimeLastSeen)
+	}
+	if _, removed, _ = us.RemoveSession("alice", ""); removed != 0 {
+		t.Error("removed sessions twice")
+	}
+	if n := len(us.GetAllOnlineUsers()); n != 1 {
+		t.Errorf("got %d online users after logout, want 1", n)
+	}
+
+	if err := us.DeleteUser("bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found = us.GetUser("bob"); found {
+		t.Error("deleted user still found")
+	}
+	if n := len(us.GetAllUsers()); n != 2 {
+		t.Errorf("got %d users after delete, want 2", n)
+	}
+
+	testSessions(t, us)
+	testCheckCredentials(t, us)
+	testBans(t, us)
+	testConcurrentUpdates(t, us)
+}
+
+func testConcurrentUpdates(t *testing.T, us storage.UserStorage) {
+	mustAdd(t, us, newUser("frank", false))
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := us.UpdateUser("frank", func(u *storage.User) error {
+				u.TimeLastSeen++
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if u, _ := us.GetUser("frank"); u.TimeLastSeen != n {
+		t.Errorf("got %d updates, want %d", u.TimeLastSeen, n)
+	}
+}
+
+func testSessions(t *testing.T, us storage.UserStorage) {
+	if _, err := us.AddSession("mallory", newSession("s1"), nil); err == nil {
+		t.Error("adding a session for an unknown user: got nil error")
+	}
+	mustAdd(t, us, newUser("erin", false))
+	expired := newSession("s0")
+	expired.Expiry = time.Now().Add(-time.Minute).Unix()
+	for _, sess := range []storage.Session{expired, newSession("s1"), newSession("s2")} {
+		if _, err := us.AddSession("erin", sess, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u, err := us.AddSession("erin", newSession("s2"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Online || len(u.Sessions) != 2 {
+		t.Errorf("got online %t with %d sessions, want online with 2", u.Online, len(u.Sessions))
+	}
+
+	u, removed, err := us.RemoveSession("erin", "s1")
+	if err != nil || removed != 1 {
+		t.Fatalf("remove session: got %v, %v", removed, err)
+	}
+	if !u.Online {
+		t.Error("user offline with a session left")
+	}
+	if _, removed, _ = us.RemoveSession("erin", "s1"); removed != 0 {
+		t.Error("removed session twice")
+	}
+	u, _, _ = us.RemoveSession("erin", "s2")
+	if u.Online {
+		t.Error("user online without sessions")
+	}
+	if err = us.DeleteUser("erin"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testCheckCredentials(t *testing.T, us storage.UserStorage) {
+	carol, _ := us.GetUser("carol")
+	sess := carol.Sessions[0]
+	tests := []struct {
+		desc  string
+		creds *pb.Credentials
+		ok    bool
+	}{
+		{"nil credentials", nil, false},
+		{"unknown user", &pb.Credentials{Nick: "mallory", Token: sess.Token, Session: sess.ID}, false},
+		{"offline user", &pb.Credentials{Nick: "alice"}, false},
+		{"missing token", &pb.Credentials{Nick: "carol", Session: sess.ID}, false},
+		{"wrong token", &pb.Credentials{Nick: "carol", Token: make([]byte, common.TokenSize), Session: sess.ID}, false},
+		{"wrong session", &pb.Credentials{Nick: "carol", Token: sess.Token, Session: "other"}, false},
+		{"valid token", &pb.Credentials{Nick: "carol", Token: sess.Token, Session: sess.ID}, true},
+	}
+	for _, test := range tests {
+		_, err := us.CheckCredentials(test.creds)
+		if test.ok && err != nil {
+			t.Errorf("%s: got error %v, want nil", test.desc, err)
+		}
+		if !test.ok {
+			if _, isAuthErr := err.(common.AuthenticationError); !isAuthErr {
+				t.Errorf("%s: got error %v, want authentication error", test.desc, err)
+			}
+		}
+	}
+
+	sess.Expiry = time.Now().Add(-time.Minute).Unix()
+	if _, err := us.AddSession("carol", sess, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := us.CheckCredentials(&pb.Credentials{Nick: "carol", Token: sess.Token, Session: sess.ID})
+	if err == nil {
+		t.Error("expired token: got nil error")
+	}
+}
+
+func testBans(t *testing.T, us storage.UserStorage) {
+	dave := newUser("dave", true)
+	mustAdd(t, us, dave)
+	creds := &pb.Credentials{Nick: "dave", Token: dave.Sessions[0].Token, Session: dave.Sessions[0].ID}
+
+	bans := []*pb.Ban{
+		{Nick: "dave", Reason: "spam", By: "alice"},
+		{Addr: "10.0.0.1", By: "alice", Expiry: time.Now().Add(time.Hour).Unix()},
+		{Addr: "10.0.0.2", By: "alice", Expiry: time.Now().Add(-time.Hour).Unix()},
+	}
+	for _, ban := range bans {
+		if err := us.AddBan(ban); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(us.GetBans()); n != 2 {
+		t.Errorf("got %d bans in effect, want 2", n)
+	}
+
+	if _, err := us.CheckCredentials(creds); err == nil {
+		t.Error("banned user: got nil error")
+	}
+	if ban, banned := us.Banned("dave", "", pb.User_USER); !banned || ban.Reason != "spam" {
+		t.Errorf("nick ban: got %v, %v", ban, banned)
+	}
+	if _, banned := us.Banned("erin", "10.0.0.1", pb.User_USER); !banned {
+		t.Error("address ban not in effect")
+	}
+	if _, banned := us.Banned("erin", "10.0.0.2", pb.User_USER); banned {
+		t.Error("expired address ban in effect")
+	}
+	if _, banned := us.Banned("10.0.0.1", "", pb.User_USER); banned {
+		t.Error("address ban applied to nick")
+	}
+	if _, banned := us.Banned("erin", "10.0.0.1", pb.User_MODERATOR); banned {
+		t.Error("address ban without a role applied to a moderator")
+	}
+	if err := us.AddBan(&pb.Ban{Addr: "10.0.0.3", By: "alice", ByRole: pb.User_ADMIN}); err != nil {
+		t.Fatal(err)
+	}
+	if _, banned := us.Banned("erin", "10.0.0.3", pb.User_MODERATOR); !banned {
+		t.Error("admin's address ban not applied to a moderator")
+	}
+	if _, banned := us.Banned("erin", "10.0.0.3", pb.User_ADMIN); banned {
+		t.Error("admin's address ban applied to an admin")
+	}
+
+	removed, err := us.RemoveBan("dave", "")
+	if err != nil || !removed {
+		t.Errorf("remove ban: got %v, %v", removed, err)
+	}
+	if removed, _ = us.RemoveBan("dave", ""); removed {
+		t.Error("removed ban twice")
+	}
+	if _, err := us.CheckCredentials(creds); err != nil {
+		t.Errorf("unbanned user: got error %v", err)
+	}
+
+	sess := newSession("s2")
+	sess.Addr = "10.0.0.1"
+	if _, err = us.AddSession("dave", sess, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = us.CheckCredentials(&pb.Credentials{Nick: "dave", Token: sess.Token, Session: sess.ID})
+	if err == nil {
+		t.Error("session from banned address: got nil error")
+	}
+	if _, err = us.CheckCredentials(creds); err != nil {
+		t.Errorf("session from other address: got error %v", err)
+	}
+}
+
+func newUser(nick string, online bool) storage.User {
+	user := storage.User{
+		Online: online,
+		User:   pb.User{Nick: nick},
+	}
+	if online {
+		user.Sessions = []storage.Session{newSession("s1")}
+	}
+	return user
+}
+
+func newSession(id string) storage.Session {
+	token, _ := common.NewToken()
+	return storage.Session{
+		ID:     id,
+		Token:  token,
+		Expiry: time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func mustAdd(t *testing.T, us storage.UserStorage, user storage.User) {
+	if err := us.AddUser(user); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "chat-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
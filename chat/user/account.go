@@ -0,0 +1,157 @@
+package user
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	c "github.com/tormoder/chat/common"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+var (
+	errPasswordRequired = status.Error(codes.Unauthenticated, "password required")
+	errBadPassword      = status.Error(codes.Unauthenticated, "invalid nick or password")
+	errNotRegistered    = status.Error(codes.FailedPrecondition, "nick not registered")
+	errGuestsDisabled   = status.Error(codes.PermissionDenied, "nick not registered and guests are not allowed")
+	errNickRegistered   = status.Error(codes.AlreadyExists, "nick already registered")
+	errNickInUse        = status.Error(codes.AlreadyExists, "nick in use by another guest")
+	errEmptyNick        = status.Error(codes.InvalidArgument, "empty nick")
+	errPrivilegedGuest  = status.Error(codes.PermissionDenied, "nick has a role but is not registered, ask an admin")
+)
+
+func (s *Service) Register(ctx context.Context, rreq *pb.RegisterRequest) (*pb.AccountResponse, error) {
+	s.log.WithContext(ctx).Debug("register request", "target", rreq.Nick)
+	if rreq.Nick == "" {
+		return nil, errEmptyNick
+	}
+	if certNick, ok := c.PeerCertNick(ctx); ok && certNick != rreq.Nick {
+		return nil, c.AuthenticationError("nick does not match client certificate")
+	}
+	if ban, banned := s.banned(ctx, rreq.Nick); banned {
+		return nil, storage.BanError(ban)
+	}
+
+	user, found := s.storage.GetUser(rreq.Nick)
+	switch {
+	case user.Registered():
+		return nil, errNickRegistered
+	case user.Role > pb.User_USER:
+		return nil, errPrivilegedGuest
+	case user.Online:
+		// Only the guest using the nick may claim it
+		if rreq.GetCreds().GetNick() != rreq.Nick {
+			return nil, errNickInUse
+		}
+		if _, err := s.storage.CheckCredentials(rreq.Creds); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := c.HashPassword(rreq.Password)
+	if err == c.ErrPasswordTooShort {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, c.InternalServerError("password hashing failed")
+	}
+
+	if found {
+		// Checked again, the user may have changed while hashing
+		var registerErr error
+		_, err = s.storage.UpdateUser(rreq.Nick, func(user *storage.User) error {
+			switch {
+			case user.Registered():
+				registerErr = errNickRegistered
+			case user.Role > pb.User_USER:
+				registerErr = errPrivilegedGuest
+			case user.Online && rreq.GetCreds().GetNick() != rreq.Nick:
+				registerErr = errNickInUse
+			}
+			user.PassHash = hash
+			return registerErr
+		})
+		if registerErr != nil {
+			return nil, registerErr
+		}
+	} else {
+		err = s.storage.AddUser(storage.User{
+			PassHash: hash,
+			User: pb.User{
+				Nick:         rreq.Nick,
+				TimeLastSeen: time.Now().Unix(),
+			},
+		})
+	}
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	s.log.WithContext(ctx).Info("nick registered", "target", rreq.Nick)
+
+	return &pb.AccountResponse{}, nil
+}
+
+func (s *Service) ChangePassword(ctx context.Context, areq *pb.AccountRequest) (*pb.AccountResponse, error) {
+	s.log.WithContext(ctx).Debug("change password request")
+	user, err := s.storage.CheckCredentials(areq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	if !user.Registered() {
+		return nil, errNotRegistered
+	}
+	if err = s.authenticate(user, true, areq.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := c.HashPassword(areq.NewPassword)
+	if err == c.ErrPasswordTooShort {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, c.InternalServerError("password hashing failed")
+	}
+	_, err = s.storage.UpdateUser(user.Nick, func(user *storage.User) error {
+		user.PassHash = hash
+		return nil
+	})
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	s.log.WithContext(ctx).Info("password changed")
+
+	return &pb.AccountResponse{}, nil
+}
+
+func (s *Service) DeleteAccount(ctx context.Context, areq *pb.AccountRequest) (*pb.AccountResponse, error) {
+	s.log.WithContext(ctx).Debug("delete account request")
+	user, err := s.storage.CheckCredentials(areq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+	// Guests only need their credentials
+	if user.Registered() {
+		if err = s.authenticate(user, true, areq.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	user, _, err = s.chat.EndSession(user.Nick, "")
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	if err = s.storage.DeleteUser(user.Nick); err != nil {
+		return nil, c.InternalServerError("storage error")
+	}
+	if err = s.chat.PurgeNick(user.Nick); err != nil {
+		s.log.WithContext(ctx).Error("purging messages failed", "err", err)
+	}
+
+	s.broadcastEvent(pb.UserEvent_LOGOUT, user)
+	s.log.WithContext(ctx).Info("account deleted")
+
+	return &pb.AccountResponse{}, nil
+}
@@ -0,0 +1,180 @@
+package user_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/tormoder/chat/chat"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+func newUserService(guests bool) (*user.Service, storage.UserStorage) {
+	us := storage.NewInMemoryUserStorage()
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, chat.DefaultSessionTimeout, logging.Discard())
+	return user.NewService(cs, us, guests, logging.Discard()), us
+}
+
+func wantCode(t *testing.T, desc string, err error, code codes.Code) {
+	if status.Code(err) != code {
+		t.Errorf("%s: got %v, want %v", desc, err, code)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	users, us := newUserService(true)
+	ctx := context.Background()
+
+	_, err := users.Login(ctx, &pb.LoginRequest{})
+	wantCode(t, "login with empty nick", err, codes.InvalidArgument)
+	alice, err := users.Login(ctx, &pb.LoginRequest{Nick: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = users.Register(ctx, &pb.RegisterRequest{Nick: "alice", Password: "secret"})
+	wantCode(t, "register guest nick in use", err, codes.AlreadyExists)
+	_, err = users.Register(ctx, &pb.RegisterRequest{Creds: alice, Nick: "alice", Password: "short"})
+	wantCode(t, "register with short password", err, codes.InvalidArgument)
+	_, err = users.Register(ctx, &pb.RegisterRequest{Creds: alice, Nick: "alice", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := us.GetUser("alice"); !u.Registered() || !u.Online {
+		t.Errorf("got registered %t, online %t, want both", u.Registered(), u.Online)
+	}
+	_, err = users.Register(ctx, &pb.RegisterRequest{Nick: "alice", Password: "password2"})
+	wantCode(t, "register registered nick", err, codes.AlreadyExists)
+
+	if _, err = users.Logout(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	_, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice"})
+	wantCode(t, "login without password", err, codes.Unauthenticated)
+	_, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice", Password: "password2"})
+	wantCode(t, "login with wrong password", err, codes.Unauthenticated)
+	alice, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key1, key2 := make([]byte, 32), make([]byte, 32)
+	key2[0] = 1
+	if _, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice", Password: "password1", PublicKey: key1}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice", Password: "password1", PublicKey: key2})
+	wantCode(t, "login with another key", err, codes.AlreadyExists)
+	if _, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice", Password: "password1", PublicKey: key1}); err != nil {
+		t.Errorf("login with the same key: %v", err)
+	}
+
+	_, err = users.ChangePassword(ctx, &pb.AccountRequest{Creds: alice, Password: "password2", NewPassword: "password3"})
+	wantCode(t, "change password with wrong password", err, codes.Unauthenticated)
+	_, err = users.ChangePassword(ctx, &pb.AccountRequest{Creds: alice, Password: "password1", NewPassword: "password3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = users.DeleteAccount(ctx, &pb.AccountRequest{Creds: alice, Password: "password1"})
+	wantCode(t, "delete account with old password", err, codes.Unauthenticated)
+	_, err = users.DeleteAccount(ctx, &pb.AccountRequest{Creds: alice, Password: "password3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := us.GetUser("alice"); found {
+		t.Error("deleted user still stored")
+	}
+	if _, err = users.Login(ctx, &pb.LoginRequest{Nick: "alice"}); err != nil {
+		t.Errorf("guest login with deleted nick: %v", err)
+	}
+}
+
+func TestGuestsDisabled(t *testing.T) {
+	users, _ := newUserService(false)
+	ctx := context.Background()
+
+	_, err := users.Login(ctx, &pb.LoginRequest{Nick: "bob"})
+	wantCode(t, "guest login", err, codes.PermissionDenied)
+	_, err = users.Login(ctx, &pb.LoginRequest{Nick: "bob", Password: "password1"})
+	wantCode(t, "login with password before registering", err, codes.FailedPrecondition)
+
+	_, err = users.Register(ctx, &pb.RegisterRequest{Nick: "bob", Password: "password1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = users.Login(ctx, &pb.LoginRequest{Nick: "bob", Password: "password1"}); err != nil {
+		t.Errorf("login after registering: %v", err)
+	}
+}
+
+func TestNickReuse(t *testing.T) {
+	us := storage.NewInMemoryUserStorage()
+	cs := chat.NewService(us, storage.NewInMemoryMessageStorage(), storage.NewInMemoryMailboxStorage(), nil, chat.DefaultSessionTimeout, logging.Discard())
+	users := user.NewService(cs, us, true, logging.Discard())
+	ctx := context.Background()
+	bob, err := users.Login(ctx, &pb.LoginRequest{Nick: "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// privateMsgs sends bob's message to nick, ends the use of the nick
+	// with end and returns the private messages the next user of nick sees.
+	privateMsgs := func(nick string, end func(creds *pb.Credentials) error) int {
+		creds, err := users.Login(ctx, &pb.LoginRequest{Nick: nick})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = cs.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: bob, To: nick, Msg: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = end(creds); err != nil {
+			t.Fatal(err)
+		}
+		if creds, err = users.Login(ctx, &pb.LoginRequest{Nick: nick}); err != nil {
+			t.Fatal(err)
+		}
+		hist, err := cs.GetHistory(ctx, &pb.HistoryRequest{Creds: creds})
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, msg := range hist.Msgs {
+			if msg.GetPrivateMsg() != nil {
+				n++
+			}
+		}
+		return n
+	}
+
+	n := privateMsgs("alice", func(creds *pb.Credentials) error {
+		if _, err := users.Logout(ctx, creds); err != nil {
+			return err
+		}
+		_, err := cs.SendPrivate(ctx, &pb.PrivateMsgRequest{Creds: bob, To: "alice", Msg: "later"})
+		if err == nil {
+			t.Error("message to logged out guest queued")
+		}
+		return nil
+	})
+	if n != 0 {
+		t.Errorf("next guest sees %d private messages of the last", n)
+	}
+
+	n = privateMsgs("carol", func(creds *pb.Credentials) error {
+		_, err := users.Register(ctx, &pb.RegisterRequest{Creds: creds, Nick: "carol", Password: "password1"})
+		if err != nil {
+			return err
+		}
+		_, err = users.DeleteAccount(ctx, &pb.AccountRequest{Creds: creds, Password: "password1"})
+		return err
+	})
+	if n != 0 {
+		t.Errorf("guest sees %d private messages of deleted account", n)
+	}
+}
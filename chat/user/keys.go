@@ -0,0 +1,69 @@
+package user
+
+import (
+	"bytes"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+)
+
+// Size of a Curve25519 public key.
+const publicKeySize = 32
+
+var (
+	errBadPublicKey = status.Error(codes.InvalidArgument, "public key must be 32 bytes")
+	errNoPublicKey  = status.Error(codes.FailedPrecondition, "user has not published a key")
+	errOtherKey     = status.Error(codes.AlreadyExists, "nick logged in elsewhere with another key, use the same key on every client")
+	errUserNotFound = status.Error(codes.NotFound, "user not found")
+)
+
+func checkPublicKey(key []byte) error {
+	if len(key) != 0 && len(key) != publicKeySize {
+		return errBadPublicKey
+	}
+	return nil
+}
+
+// keyConflict reports whether logging in user with key would replace the
+// key its live sessions publish, which their clients alone can decrypt with.
+func keyConflict(user storage.User, key []byte) bool {
+	if len(key) == 0 || len(user.PublicKey) == 0 || bytes.Equal(key, user.PublicKey) {
+		return false
+	}
+	for _, sess := range user.Sessions {
+		if !sess.Expired() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) GetPublicKey(ctx context.Context, keyReq *pb.PublicKeyRequest) (*pb.PublicKeyResponse, error) {
+	s.log.WithContext(ctx).Debug("public key request", "target", keyReq.Nick)
+	_, err := s.storage.CheckCredentials(keyReq.GetCreds())
+	if err != nil {
+		return nil, err
+	}
+
+	// Like private messages, prefer the server the user is online on
+	var key []byte
+	user, found := s.storage.GetUser(keyReq.Nick)
+	if remote, online := s.chat.RemoteUser(keyReq.Nick); !user.Online && online {
+		key = remote.PublicKey
+	} else if found {
+		key = user.PublicKey
+	} else {
+		return nil, errUserNotFound
+	}
+	if len(key) == 0 {
+		return nil, errNoPublicKey
+	}
+	return &pb.PublicKeyResponse{
+		Nick:      keyReq.Nick,
+		PublicKey: key,
+	}, nil
+}
@@ -2,113 +2,219 @@ package user
 
 import (
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/tormoder/chat/chat"
 	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/logging"
 	pb "github.com/tormoder/chat/proto"
 	"github.com/tormoder/chat/storage"
 
 	"golang.org/x/net/context"
 )
 
+const tokenLifetime = 24 * time.Hour
+
 type Service struct {
 	storage storage.UserStorage
 	chat    *chat.Service
+	log     *logging.Logger
+	guests  bool // Whether unregistered nicks may log in
+
+	logins uint64 // Accessed atomically
 }
 
-func NewService(chatService *chat.Service, userStorage storage.UserStorage) *Service {
+func NewService(chatService *chat.Service, userStorage storage.UserStorage, guests bool, logger *logging.Logger) *Service {
 	return &Service{
 		storage: userStorage,
 		chat:    chatService,
+		log:     logger,
+		guests:  guests,
 	}
 }
 
 func (s *Service) Login(ctx context.Context, lreq *pb.LoginRequest) (*pb.Credentials, error) {
-	c.Debugln("login request from", lreq.Nick)
+	s.log.WithContext(ctx).Debug("login request")
+	if lreq.Nick == "" {
+		return nil, errEmptyNick
+	}
+	certNick, certAuth := c.PeerCertNick(ctx)
+	if certAuth && certNick != lreq.Nick {
+		return nil, c.AuthenticationError("nick does not match client certificate")
+	}
+	if ban, banned := s.banned(ctx, lreq.Nick); banned {
+		return nil, storage.BanError(ban)
+	}
+
+	if err := checkPublicKey(lreq.PublicKey); err != nil {
+		return nil, err
+	}
+
 	user, found := s.storage.GetUser(lreq.Nick)
-	if found {
-		if user.Online {
-			return nil, c.AuthenticationError("user already online")
-		}
-		user.Online = true
-		user.TimeLastSeen = time.Now().Unix()
-		err := s.storage.UpdateUser(user)
-		if err != nil {
-			return nil, err
+	err := s.authenticate(user, found, lreq.Password)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := newSession()
+	if err != nil {
+		return nil, c.InternalServerError("token generation failed")
+	}
+	sess.Addr, _ = c.PeerHost(ctx)
+	if !found {
+		// Fails if another login added the user first, which is checked
+		// for below
+		s.storage.AddUser(storage.User{
+			User: pb.User{Nick: lreq.Nick},
+		})
+	}
+	// The user may have changed since authenticated, so it is checked
+	// again atomically with adding the session
+	var loginErr error
+	user, err = s.storage.AddSession(lreq.Nick, sess, func(u *storage.User) error {
+		switch {
+		case u.PassHash != user.PassHash:
+			loginErr = errBadPassword
+		case u.Online && !u.Registered() && !certAuth:
+			// Nothing proves that a second login to a guest nick is by
+			// the same person
+			loginErr = c.AuthenticationError("user already online, register the nick to log in from several clients")
+		case keyConflict(*u, lreq.PublicKey):
+			loginErr = errOtherKey
 		}
-	} else {
-		user = storage.User{
-			Online:     true,
-			MsgChannel: make(chan *pb.ChatServerMsg, 2048),
-			User: pb.User{
-				Nick:         lreq.Nick,
-				TimeLastSeen: time.Now().Unix(),
-			},
+		if loginErr != nil {
+			return loginErr
 		}
-		err := s.storage.AddUser(user)
-		if err != nil {
-			return nil, err
+		u.TimeLastSeen = time.Now().Unix()
+		if len(lreq.PublicKey) > 0 || !u.Registered() {
+			// A guest nick may be someone else's next time
+			u.PublicKey = lreq.PublicKey
 		}
+		return nil
+	})
+	if loginErr != nil {
+		return nil, loginErr
+	}
+	if err != nil {
+		return nil, c.InternalServerError("storage error")
 	}
 
-	s.chat.BroadcastAllConnectedClients(
-		&pb.ChatServerMsg{
-			Msg: &pb.ChatServerMsg_UserEvent{
-				UserEvent: &pb.UserEvent{
-					Event: pb.UserEvent_LOGIN,
-					User:  &user.User,
-					Time:  time.Now().Unix(),
-				},
-			},
-		},
-	)
+	if len(user.Sessions) == 1 {
+		s.broadcastEvent(pb.UserEvent_LOGIN, user)
+	}
 
-	c.Debugln("user", user.User.Nick, "logged-in")
+	atomic.AddUint64(&s.logins, 1)
+	s.log.WithContext(ctx).Info("user logged in", "session", sess.ID, "sessions", len(user.Sessions))
 
 	return &pb.Credentials{
-		Nick: user.User.Nick,
+		Nick:    user.User.Nick,
+		Token:   sess.Token,
+		Session: sess.ID,
+	}, nil
+}
+
+func newSession() (storage.Session, error) {
+	id, err := c.NewSessionID()
+	if err != nil {
+		return storage.Session{}, err
+	}
+	token, err := c.NewToken()
+	if err != nil {
+		return storage.Session{}, err
+	}
+	now := time.Now()
+	return storage.Session{
+		ID:      id,
+		Token:   token,
+		Created: now.Unix(),
+		Expiry:  now.Add(tokenLifetime).Unix(),
 	}, nil
 }
 
 func (s *Service) Logout(ctx context.Context, creds *pb.Credentials) (*pb.LogoutResponse, error) {
-	c.Debugln("logout request from", creds.Nick)
+	s.log.WithContext(ctx).Debug("logout request")
 	user, err := s.storage.CheckCredentials(creds)
 	if err != nil {
 		return nil, err
 	}
 
-	user.Online = false
-	user.TimeLastSeen = time.Now().Unix()
-	err = s.storage.UpdateUser(user)
+	user, loggedOut, err := s.chat.EndSession(user.Nick, creds.Session)
 	if err != nil {
 		return nil, c.InternalServerError("storage error")
 	}
+	if loggedOut {
+		s.broadcastEvent(pb.UserEvent_LOGOUT, user)
+	}
+
+	s.log.WithContext(ctx).Info("user logged out")
+
+	return &pb.LogoutResponse{}, nil
+}
 
+func (s *Service) broadcastEvent(event pb.UserEvent_EventType, user storage.User) {
 	s.chat.BroadcastAllConnectedClients(
 		&pb.ChatServerMsg{
 			Msg: &pb.ChatServerMsg_UserEvent{
 				UserEvent: &pb.UserEvent{
-					Event: pb.UserEvent_LOGOUT,
+					Event: event,
 					User:  &user.User,
 					Time:  time.Now().Unix(),
 				},
 			},
 		},
 	)
+}
+
+// authenticate checks password if the nick is registered, and otherwise
+// that guests are allowed.
+func (s *Service) authenticate(user storage.User, found bool, password string) error {
+	if !found || !user.Registered() {
+		if password != "" {
+			return errNotRegistered
+		}
+		// Roles are only given to registered nicks, but storage may predate that
+		if user.Role > pb.User_USER {
+			return errPrivilegedGuest
+		}
+		if !s.guests {
+			return errGuestsDisabled
+		}
+		return nil
+	}
+	if password == "" {
+		return errPasswordRequired
+	}
+	ok, err := c.CheckPassword(user.PassHash, password)
+	if err != nil {
+		return c.InternalServerError("password check failed")
+	}
+	if !ok {
+		return errBadPassword
+	}
+	return nil
+}
 
-	c.Debugln("user", user.User.Nick, "logged-out")
+// banned returns the ban in effect on nick connecting from the peer in
+// ctx, taking the role of an existing user into account.
+func (s *Service) banned(ctx context.Context, nick string) (*pb.Ban, bool) {
+	user, _ := s.storage.GetUser(nick)
+	addr, _ := c.PeerHost(ctx)
+	return s.storage.Banned(nick, addr, user.Role)
+}
 
-	return &pb.LogoutResponse{}, nil
+// LoginCount returns the number of successful logins.
+func (s *Service) LoginCount() uint64 {
+	return atomic.LoadUint64(&s.logins)
 }
 
 func (s *Service) ListUsers(ctx context.Context, creds *pb.Credentials) (*pb.ListUsersResponse, error) {
-	c.Debugln("list user request from", creds.Nick)
+	s.log.WithContext(ctx).Debug("list user request")
 	_, err := s.storage.CheckCredentials(creds)
 	if err != nil {
 		return nil, err
 	}
-	users := s.storage.GetAllOnlineUsersDTO()
+	users := s.chat.VisibleUsers(creds.Nick, s.storage.GetAllOnlineUsersDTO())
+	users = s.chat.WithRemoteUsers(users)
 	sort.Sort(storage.ByNick(users))
 	return &pb.ListUsersResponse{
 		Users: users,
@@ -0,0 +1,232 @@
+package user_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/tormoder/chat/chat"
+	c "github.com/tormoder/chat/common"
+	"github.com/tormoder/chat/logging"
+	pb "github.com/tormoder/chat/proto"
+	"github.com/tormoder/chat/storage"
+	"github.com/tormoder/chat/user"
+)
+
+func TestLoginTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	defer pki.cleanup()
+
+	addr, stop := startServer(t, pki.serverCreds(t, false))
+	defer stop()
+
+	userService, done := dial(t, addr, pki.clientCreds(t, ""))
+	defer done()
+
+	creds, err := userService.Login(context.Background(), &pb.LoginRequest{Nick: "alice"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if len(creds.Token) != c.TokenSize {
+		t.Errorf("got token of length %d, want %d", len(creds.Token), c.TokenSize)
+	}
+	if _, err = userService.ListUsers(context.Background(), creds); err != nil {
+		t.Errorf("list users with valid token: %v", err)
+	}
+
+	badCreds := &pb.Credentials{Nick: creds.Nick, Token: make([]byte, c.TokenSize)}
+	if _, err = userService.ListUsers(context.Background(), badCreds); err == nil {
+		t.Error("list users with invalid token: got nil error")
+	}
+}
+
+func TestLoginMutualTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	defer pki.cleanup()
+
+	addr, stop := startServer(t, pki.serverCreds(t, true))
+	defer stop()
+
+	userService, done := dial(t, addr, pki.clientCreds(t, "alice"))
+	defer done()
+
+	_, err := userService.Login(context.Background(), &pb.LoginRequest{Nick: "bob"})
+	if err == nil {
+		t.Error("login with nick not matching certificate: got nil error")
+	}
+	_, err = userService.Login(context.Background(), &pb.LoginRequest{Nick: "alice"})
+	if err != nil {
+		t.Errorf("login with nick matching certificate: %v", err)
+	}
+}
+
+func TestMutualTLSRequiresClientCert(t *testing.T) {
+	pki := newTestPKI(t)
+	defer pki.cleanup()
+
+	addr, stop := startServer(t, pki.serverCreds(t, true))
+	defer stop()
+
+	userService, done := dial(t, addr, pki.clientCreds(t, ""))
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := userService.Login(ctx, &pb.LoginRequest{Nick: "alice"})
+	if err == nil {
+		t.Error("login without client certificate: got nil error")
+	}
+}
+
+func startServer(t *testing.T, tlsCreds credentials.TransportCredentials) (string, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer(grpc.Creds(tlsCreds))
+	userStorage := storage.NewInMemoryUserStorage()
+	chatService := chat.NewService(
+		userStorage,
+		storage.NewInMemoryMessageStorage(),
+		storage.NewInMemoryMailboxStorage(),
+		nil,
+		chat.DefaultSessionTimeout,
+		logging.Discard(),
+	)
+	pb.RegisterUserServiceServer(grpcServer, user.NewService(chatService, userStorage, true, logging.Discard()))
+	pb.RegisterChatServiceServer(grpcServer, chatService)
+	go grpcServer.Serve(listener)
+	return listener.Addr().String(), grpcServer.Stop
+}
+
+func dial(t *testing.T, addr string, tlsCreds credentials.TransportCredentials) (pb.UserServiceClient, func()) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(tlsCreds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb.NewUserServiceClient(conn), func() { conn.Close() }
+}
+
+// testPKI is a CA with a server certificate for 127.0.0.1, written as PEM
+// files to a temporary directory.
+type testPKI struct {
+	dir    string
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+	serial int64
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	dir, err := ioutil.TempDir("", "chat-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pki := &testPKI{dir: dir}
+	pki.caKey, pki.caCert = pki.issue(t, "ca", &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "chat test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	pki.issue(t, "server", &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "chatserver"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	})
+	return pki
+}
+
+func (pki *testPKI) cleanup() {
+	os.RemoveAll(pki.dir)
+}
+
+func (pki *testPKI) serverCreds(t *testing.T, mutual bool) credentials.TransportCredentials {
+	var caFile string
+	if mutual {
+		caFile = pki.path("ca.pem")
+	}
+	cfg, err := c.ServerTLSConfig(pki.path("server.pem"), pki.path("server.key"), caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credentials.NewTLS(cfg)
+}
+
+// clientCreds returns client credentials trusting the test CA, with a
+// client certificate for nick unless nick is empty.
+func (pki *testPKI) clientCreds(t *testing.T, nick string) credentials.TransportCredentials {
+	var certFile, keyFile string
+	if nick != "" {
+		pki.issue(t, nick, &x509.Certificate{
+			Subject:     pkix.Name{CommonName: nick},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			KeyUsage:    x509.KeyUsageDigitalSignature,
+		})
+		certFile, keyFile = pki.path(nick+".pem"), pki.path(nick+".key")
+	}
+	cfg, err := c.ClientTLSConfig(certFile, keyFile, pki.path("ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return credentials.NewTLS(cfg)
+}
+
+// issue creates a key and a certificate from template, signed by the CA or
+// self-signed if there is no CA yet, and writes both to name.pem/name.key.
+func (pki *testPKI) issue(t *testing.T, name string, template *x509.Certificate) (*ecdsa.PrivateKey, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pki.serial++
+	template.SerialNumber = big.NewInt(pki.serial)
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if pki.caCert != nil {
+		parent, signer = pki.caCert, pki.caKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pki.writePEM(t, name+".pem", "CERTIFICATE", der)
+	pki.writePEM(t, name+".key", "EC PRIVATE KEY", keyDER)
+	return key, cert
+}
+
+func (pki *testPKI) writePEM(t *testing.T, name, typ string, der []byte) {
+	b := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := ioutil.WriteFile(pki.path(name), b, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (pki *testPKI) path(name string) string {
+	return filepath.Join(pki.dir, name)
+}